@@ -0,0 +1,49 @@
+// cmd/replicator/diff.go
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+// cmdDiff prints the semantic changes from one config to another: units
+// added or removed, changed settings, destination ranges that moved.
+// Both files are fully loaded, so the comparison is of what would run.
+// Exit 3 means the configs differ.
+func cmdDiff(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("diff", stderr)
+	format := formatFlag(fs)
+	refs, ok := parseWithPaths(fs, args, 2)
+	if !ok || !checkFormat(*format, stderr) {
+		return exitUsage
+	}
+
+	var cfgs [2]*config.Config
+	for i, ref := range refs {
+		cfg, err := loadConfig(ref)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: config load failed: %v\n", ref.path, err)
+			return exitInvalid
+		}
+		cfgs[i] = cfg
+	}
+
+	changes := config.Diff(cfgs[0], cfgs[1])
+	if *format == "json" {
+		if changes == nil {
+			changes = []config.Change{}
+		}
+		writeJSON(stdout, changes)
+	} else {
+		for _, c := range changes {
+			fmt.Fprintln(stdout, c)
+		}
+	}
+
+	if len(changes) > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
@@ -0,0 +1,120 @@
+// cmd/replicator/duplicate.go
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+// cmdDuplicate emits clones of one unit, ready to append under
+// replicator.units. The config file itself is never modified.
+// Each clone target gets its own target id, so clones do not write over
+// the original's data. Exit 0 means the clones validate together with
+// the config; exit 3 means they were emitted but still conflict (e.g.
+// no free target id on an endpoint).
+func cmdDuplicate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("duplicate", stderr)
+	unit := fs.String("unit", "", "id of the unit to clone (required)")
+	count := fs.Int("count", 1, "number of clones")
+	format := fs.String("format", "yaml", "output format: yaml or json")
+	ref, ok := parseWithPath(fs, args)
+	if !ok {
+		return exitUsage
+	}
+	if *unit == "" || *count < 1 {
+		fmt.Fprintln(stderr, "duplicate: --unit is required and --count must be >= 1")
+		return exitUsage
+	}
+	if *format != "yaml" && *format != "json" {
+		fmt.Fprintf(stderr, "unsupported --format %q (yaml, json)\n", *format)
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(ref)
+	if err != nil {
+		fmt.Fprintf(stderr, "config load failed: %v\n", err)
+		return exitInvalid
+	}
+
+	// Each clone is appended before the next is resolved, so clones
+	// never collide with each other either.
+	var clones []config.UnitConfig
+	for i := 0; i < *count; i++ {
+		dup, err := config.DuplicateUnit(cfg, *unit)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitInvalid
+		}
+		if err := retarget(cfg, &dup); err != nil {
+			fmt.Fprintf(stderr, "duplicate: %v\n", err)
+		}
+		cfg.Replicator.Units = append(cfg.Replicator.Units, dup)
+		clones = append(clones, dup)
+	}
+
+	if *format == "json" {
+		writeJSON(stdout, clones)
+	} else {
+		enc := yaml.NewEncoder(stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(clones); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitInvalid
+		}
+		_ = enc.Close()
+	}
+
+	// Say so instead of failing: the output is still the right starting
+	// point.
+	if err := config.Validate(cfg); err != nil {
+		fmt.Fprintf(stderr, "duplicate: clones need editing before use: %v\n", err)
+		return exitFindings
+	}
+	return exitOK
+}
+
+// retarget moves every target of a clone to the lowest target id from 1
+// that no unit uses on that endpoint, for data or status. DuplicateUnit
+// copies targets as-is, and the target id is the Raw Ingest unit id
+// the data is written to, so an unchanged copy overlaps the original.
+// A target left as-is when its endpoint has no free id is reported.
+func retarget(cfg *config.Config, dup *config.UnitConfig) error {
+	used := make(map[string]map[uint32]bool)
+	mark := func(ep string, id uint32) {
+		if used[ep] == nil {
+			used[ep] = make(map[uint32]bool)
+		}
+		used[ep][id] = true
+	}
+	for _, u := range cfg.Replicator.Units {
+		for _, t := range u.Targets {
+			mark(t.Endpoint, t.ID)
+			if t.StatusUnitID != nil {
+				mark(t.Endpoint, uint32(*t.StatusUnitID))
+			}
+		}
+	}
+	for _, t := range dup.Targets {
+		if t.StatusUnitID != nil {
+			mark(t.Endpoint, uint32(*t.StatusUnitID))
+		}
+	}
+
+	for i := range dup.Targets {
+		t := &dup.Targets[i]
+		id := uint32(1)
+		for used[t.Endpoint][id] {
+			id++
+		}
+		if id > 255 {
+			return fmt.Errorf("no free target id on %s for unit %s", t.Endpoint, dup.ID)
+		}
+		t.ID = id
+		mark(t.Endpoint, id)
+	}
+	return nil
+}
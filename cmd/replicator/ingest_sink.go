@@ -0,0 +1,82 @@
+// cmd/replicator/ingest_sink.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/tamzrod/modbus-replicator/internal/ingestserver"
+	"github.com/tamzrod/modbus-replicator/internal/logging"
+)
+
+// cmdIngestSink runs the reference Raw Ingest v1 receiver until
+// interrupted. Without a config every unit id gets a full memory.
+func cmdIngestSink(args []string, _, stderr io.Writer) int {
+	fs := newFlagSet("ingest-sink", stderr)
+	listen := fs.String("listen", "", "Raw Ingest host:port (default: config listen, then "+ingestserver.DefaultListen+")")
+	modbusListen := fs.String("modbus-listen", "", "also serve the memories over Modbus TCP on host:port")
+	auto := fs.Bool("auto-create", false, "allocate a full memory for unknown unit ids (always on without a config)")
+	level := fs.String("log-level", "info", "debug logs every accepted packet")
+
+	pos, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsage
+	}
+	if len(pos) > 1 {
+		fmt.Fprintln(stderr, "ingest-sink: at most one config path")
+		return exitUsage
+	}
+	lv, err := logging.ParseLevel(*level)
+	if err != nil {
+		fmt.Fprintf(stderr, "ingest-sink: --log-level: %v\n", err)
+		return exitUsage
+	}
+
+	cfg := ingestserver.Config{AutoCreate: true}
+	if len(pos) == 1 {
+		if cfg, err = ingestserver.LoadConfig(pos[0]); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitInvalid
+		}
+	}
+	if *auto {
+		cfg.AutoCreate = true
+	}
+	if *modbusListen != "" {
+		cfg.ModbusListen = *modbusListen
+	}
+
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: lv}))
+	srv, err := ingestserver.New(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(stderr, "ingest-sink: %v\n", err)
+		return exitInvalid
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if cfg.ModbusListen != "" {
+		go func() {
+			if err := srv.ServeModbus(ctx, ""); err != nil {
+				logger.Error("modbus server stopped", "err", err)
+				cancel()
+			}
+		}()
+	}
+
+	if err := srv.Serve(ctx, *listen); err != nil {
+		fmt.Fprintf(stderr, "ingest-sink: %v\n", err)
+		return exitInvalid
+	}
+	st := srv.Stats()
+	logger.Info("ingest sink stopped", "accepted", st.Accepted, "rejected", st.Rejected)
+	return exitOK
+}
@@ -2,210 +2,92 @@
 package main
 
 import (
-	"context"
-	"errors"
-	"log"
+	"fmt"
+	"io"
 	"os"
-	"time"
+	"strings"
+)
 
-	"github.com/tamzrod/modbus-replicator/internal/config"
-	"github.com/tamzrod/modbus-replicator/internal/poller"
-	"github.com/tamzrod/modbus-replicator/internal/status"
-	"github.com/tamzrod/modbus-replicator/internal/writer"
+// Exit codes are part of the CLI contract (CI pipelines branch on them).
+const (
+	exitOK       = 0
+	exitInvalid  = 1 // config rejected, lint findings, or runtime failure
+	exitUsage    = 2 // bad command line
+	exitFindings = 3 // validate --strict / lint: config valid but has findings
 )
 
-func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("usage: replicator <config.yaml>")
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "run the replicator (default)", cmdRun},
+		{"validate", "load and validate a config; report lint findings", cmdValidate},
+		{"lint", "like validate --strict", cmdLint},
+		{"duplicate", "emit a clone of one unit with free identities", cmdDuplicate},
+		{"plan", "print the resolved write plan (alias: print-plan)", cmdPlan},
+		{"resolve", "dump the config after includes, interpolation and expansion", cmdResolve},
+		{"migrate", "rewrite a config from an older layout version (-w: in place)", cmdMigrate},
+		{"diff", "show semantic changes between two configs", cmdDiff},
+		{"scan", "discover unit ids and readable ranges on a Modbus TCP endpoint", cmdScan},
+		{"simulate", "serve a simulated Modbus TCP device (memory seed, scripts, faults)", cmdSimulate},
+		{"ingest-sink", "reference Raw Ingest v1 receiver (MMA stand-in), optionally served over Modbus TCP", cmdIngestSink},
+		{"query", "export a unit's values from the local historian as CSV", cmdQuery},
+		{"schema", "print the JSON Schema of the config format", cmdSchema},
+		{"print-plan", "", cmdPlan},
 	}
+}
 
-	cfgPath := os.Args[1]
+func main() {
+	os.Exit(cli(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	// --------------------
-	// Load + validate config
-	// --------------------
-	cfg, err := config.Load(cfgPath)
-	if err != nil {
-		log.Fatalf("config load failed: %v", err)
+// cli dispatches to a subcommand and returns the process exit code.
+//
+// A bare path (`replicator /config/replicator.yaml`) is kept as `run`
+// so existing deployments and the Dockerfile CMD keep working.
+func cli(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
 	}
 
-	if err := config.Validate(cfg); err != nil {
-		log.Fatalf("config validation failed: %v", err)
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		usage(stdout)
+		return exitOK
 	}
 
-	ctx := context.Background()
-
-	// --------------------
-	// Build per-unit pipelines
-	// --------------------
-	for _, unit := range cfg.Replicator.Units {
-
-		// ---- poller ----
-		p, closePoller, err := poller.Build(unit)
-		if err != nil {
-			log.Fatalf("poller build failed (unit=%s): %v", unit.ID, err)
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
 		}
-		defer closePoller()
-
-		// ---- writer plan ----
-		plan, err := writer.BuildPlan(unit)
-		if err != nil {
-			log.Fatalf("writer plan failed (unit=%s): %v", unit.ID, err)
-		}
-
-		// ---- writer clients ----
-		clients, closeWriters, err := writer.BuildEndpointClients(unit)
-		if err != nil {
-			log.Fatalf("writer clients failed (unit=%s): %v", unit.ID, err)
-		}
-		defer closeWriters()
-
-		dataWriter := writer.New(plan, clients)
-		statusWriters := writer.NewDeviceStatusWriters(plan, clients)
-
-		out := make(chan poller.PollResult)
-
-		// ---- orchestrator ----
-		go func(unitID string, p *poller.Poller) {
-			snap := status.Snapshot{
-				Health:         status.HealthUnknown,
-				LastErrorCode:  0,
-				SecondsInError: 0,
-			}
-
-			secTicker := time.NewTicker(time.Second)
-			defer secTicker.Stop()
-
-			// initial full assert
-			for _, sw := range statusWriters {
-				_ = sw.WriteStatus(snap)
-			}
-
-			for {
-				select {
-				case <-ctx.Done():
-					return
-
-				case res := <-out:
-					if err := dataWriter.Write(res); err != nil {
-						log.Printf("writer error (unit=%s): %v", unitID, err)
-					}
-
-					if len(statusWriters) == 0 {
-						continue
-					}
-
-					changed := false
-
-					// ----------------------------
-					// Health logic (unchanged semantics)
-					// ----------------------------
-					if res.Err == nil {
-						if snap.Health != status.HealthOK {
-							snap.Health = status.HealthOK
-							changed = true
-						}
-						if snap.LastErrorCode != 0 {
-							snap.LastErrorCode = 0
-							changed = true
-						}
-						if snap.SecondsInError != 0 {
-							snap.SecondsInError = 0
-							changed = true
-						}
-					} else {
-						if snap.Health != status.HealthError {
-							snap.Health = status.HealthError
-							changed = true
-						}
-
-						code := errorCode(res.Err)
-						if snap.LastErrorCode != code {
-							snap.LastErrorCode = code
-							changed = true
-						}
-					}
-
-					// ----------------------------
-					// Transport counters injection (passive)
-					// ----------------------------
-					c := p.Counters()
-
-					if snap.RequestsTotal != c.RequestsTotal {
-						snap.RequestsTotal = c.RequestsTotal
-						changed = true
-					}
-					if snap.ResponsesValidTotal != c.ResponsesValidTotal {
-						snap.ResponsesValidTotal = c.ResponsesValidTotal
-						changed = true
-					}
-					if snap.TimeoutsTotal != c.TimeoutsTotal {
-						snap.TimeoutsTotal = c.TimeoutsTotal
-						changed = true
-					}
-					if snap.TransportErrorsTotal != c.TransportErrorsTotal {
-						snap.TransportErrorsTotal = c.TransportErrorsTotal
-						changed = true
-					}
-					if snap.ConsecutiveFailCurr != c.ConsecutiveFailCurr {
-						snap.ConsecutiveFailCurr = c.ConsecutiveFailCurr
-						changed = true
-					}
-					if snap.ConsecutiveFailMax != c.ConsecutiveFailMax {
-						snap.ConsecutiveFailMax = c.ConsecutiveFailMax
-						changed = true
-					}
-
-					if changed {
-						for _, sw := range statusWriters {
-							_ = sw.WriteStatus(snap)
-						}
-					}
-
-				case <-secTicker.C:
-					if len(statusWriters) == 0 {
-						continue
-					}
-					if snap.Health != status.HealthOK && snap.SecondsInError < 65535 {
-						snap.SecondsInError++
-						for _, sw := range statusWriters {
-							_ = sw.WriteStatus(snap)
-						}
-					}
-				}
-			}
-		}(unit.ID, p)
-
-		go p.Run(ctx, out)
 	}
 
-	// daemon block
-	for {
-		time.Sleep(time.Hour)
+	if !strings.HasPrefix(args[0], "-") && len(args) == 1 {
+		return cmdRun(args, stdout, stderr)
 	}
-}
 
-func errorCode(err error) uint16 {
-	if err == nil {
-		return 0
-	}
-
-	type coderA interface{ Code() uint16 }
-	type coderB interface{ ErrorCode() uint16 }
-	type coderC interface{ ModbusCode() uint16 }
+	fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
 
-	var a coderA
-	if errors.As(err, &a) {
-		return a.Code()
-	}
-	var b coderB
-	if errors.As(err, &b) {
-		return b.ErrorCode()
-	}
-	var c coderC
-	if errors.As(err, &c) {
-		return c.ModbusCode()
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: replicator <command> [flags] <config.yaml>")
+	fmt.Fprintln(w, "       replicator <config.yaml>            (same as run)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		if c.summary != "" {
+			fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+		}
 	}
-
-	return 1
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "exit codes: 0 ok, 1 invalid config or failure, 2 usage, 3 findings")
 }
\ No newline at end of file
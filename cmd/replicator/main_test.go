@@ -0,0 +1,385 @@
+// cmd/replicator/main_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/historian"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/scan"
+)
+
+const validYAML = `
+replicator:
+  units:
+    - id: "dev"
+      source:
+        endpoint: "127.0.0.1:502"
+        unit_id: 1
+        timeout_ms: 500
+        device_name: "DEV"
+        status_slot: 2
+      reads:
+        - fc: 3
+          address: 10
+          quantity: 5
+      targets:
+        - id: 7
+          endpoint: "127.0.0.1:1502"
+          status_unit_id: 9
+          memories:
+            - memory_id: 0
+              offsets: { 3: 100 }
+      poll:
+        interval_ms: 1000
+`
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "replicator.yaml")
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func runCLI(args ...string) (int, string, string) {
+	var out, errOut bytes.Buffer
+	code := cli(args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestCLI_ExitCodes(t *testing.T) {
+	valid := writeConfig(t, validYAML)
+	invalid := writeConfig(t, strings.Replace(validYAML, "status_unit_id: 9", "", 1))
+	findings := writeConfig(t, strings.NewReplacer("interval_ms: 1000", "interval_ms: 50", "timeout_ms: 500", "timeout_ms: 20").Replace(validYAML))
+
+	cases := []struct {
+		args []string
+		want int
+	}{
+		{nil, exitUsage},
+		{[]string{"nope", "a", "b"}, exitUsage},
+		{[]string{"validate"}, exitUsage},
+		{[]string{"validate", "--format", "xml", valid}, exitUsage},
+		{[]string{"validate", valid}, exitOK},
+		{[]string{"validate", invalid}, exitInvalid},
+		{[]string{"validate", findings}, exitOK},
+		{[]string{"validate", "--strict", findings}, exitFindings},
+		{[]string{"lint", findings}, exitFindings},
+		{[]string{"lint", valid}, exitOK},
+		{[]string{"plan", invalid}, exitInvalid},
+		{[]string{"duplicate", valid}, exitUsage},
+		{[]string{"duplicate", "--unit", "missing", valid}, exitInvalid},
+		{[]string{"validate", "--config-format", "yaml", valid}, exitOK},
+		{[]string{"validate", "--config-format", "json", valid}, exitInvalid},
+		{[]string{"validate", "--config-format", "ini", valid}, exitUsage},
+		{[]string{"validate", "../../internal/config/example.toml"}, exitOK},
+		{[]string{"diff", valid}, exitUsage},
+		{[]string{"diff", valid, valid}, exitOK},
+		{[]string{"diff", valid, "../../internal/config/example.yaml"}, exitFindings},
+		{[]string{"migrate", "../../internal/config/example.json"}, exitUsage},
+		{[]string{"migrate", "--config-format", "yaml", "../../internal/config/example.json"}, exitUsage},
+		{[]string{"scan"}, exitUsage},
+		{[]string{"scan", "--unit-ids", "1-300", "127.0.0.1:1"}, exitUsage},
+		{[]string{"scan", "--range", "10-5", "127.0.0.1:1"}, exitUsage},
+		{[]string{"scan", "--unit-ids", "1", "127.0.0.1:1"}, exitInvalid}, // nothing listening
+		{[]string{"simulate", "a.yaml", "b.yaml"}, exitUsage},
+		{[]string{"simulate", "--unit-ids", "300"}, exitUsage},
+		{[]string{"simulate", "missing.yaml"}, exitInvalid},
+		{[]string{"simulate", "--seed", "missing.csv"}, exitInvalid},
+		{[]string{"simulate", "--listen", "256.0.0.1:1"}, exitInvalid},
+		{[]string{"ingest-sink", "a.yaml", "b.yaml"}, exitUsage},
+		{[]string{"ingest-sink", "missing.yaml"}, exitInvalid},
+		{[]string{"ingest-sink", "--listen", "256.0.0.1:1"}, exitInvalid},
+		{[]string{"run", "--replay-speed", "0", valid}, exitUsage},
+		{[]string{"query", "--unit", "dev", "--address", "10"}, exitUsage},
+		{[]string{"query", "--dir", ".", "--address", "10"}, exitUsage},
+		{[]string{"query", "--dir", ".", "--unit", "dev", "--address", "10-5"}, exitUsage},
+		{[]string{"query", "--dir", ".", "--unit", "dev", "--address", "10", "--from", "yesterday"}, exitUsage},
+		{[]string{"query", "--dir", t.TempDir(), "--unit", "dev", "--address", "10"}, exitInvalid},
+		{[]string{"query", "--unit", "dev", "--address", "10", valid}, exitInvalid}, // no historian.dir
+		{[]string{"schema"}, exitOK},
+		{[]string{"schema", valid}, exitUsage},
+	}
+	for _, c := range cases {
+		if got, _, errOut := runCLI(c.args...); got != c.want {
+			t.Errorf("%v: exit %d, want %d (stderr %q)", c.args, got, c.want, errOut)
+		}
+	}
+}
+
+func TestCLI_ValidateJSONListsEveryError(t *testing.T) {
+	body := strings.NewReplacer("fc: 3", "fc: 9", "interval_ms: 1000", "interval_ms: 100").Replace(validYAML)
+	code, out, _ := runCLI("validate", "--format", "json", writeConfig(t, body))
+	if code != exitInvalid {
+		t.Fatalf("exit %d", code)
+	}
+
+	var res validateResult
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Valid || len(res.Errors) != 2 ||
+		res.Errors[0].Path != "replicator.units[0].poll.interval_ms" ||
+		res.Errors[1].Path != "replicator.units[0].reads[0].fc" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestCLI_ValidateJSON(t *testing.T) {
+	code, out, _ := runCLI("validate", "--format", "json", writeConfig(t, validYAML))
+	if code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+
+	var res validateResult
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatalf("not json: %v\n%s", err, out)
+	}
+	if !res.Valid || res.Units != 1 || len(res.Findings) != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestCLI_PlanResolvesAddresses(t *testing.T) {
+	// Flags after the path are accepted too.
+	code, out, errOut := runCLI("print-plan", writeConfig(t, validYAML), "--format", "json")
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+
+	var plans []unitPlan
+	if err := json.Unmarshal([]byte(out), &plans); err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 || len(plans[0].Targets) != 1 || len(plans[0].Status) != 1 {
+		t.Fatalf("unexpected plan: %+v", plans)
+	}
+
+	r := plans[0].Targets[0].Ranges[0]
+	if r.Area != 3 || r.Start != 110 || r.End != 114 {
+		t.Fatalf("range: %+v", r)
+	}
+	s := plans[0].Status[0]
+	if s.UnitID != 9 || s.Start != 60 || s.End != 89 {
+		t.Fatalf("status: %+v", s)
+	}
+}
+
+func TestCLI_PlanShowsDeliveryBlock(t *testing.T) {
+	body := strings.Replace(validYAML, "status_slot: 2", "status_slot: 2\n        delivery_slot: 3", 1)
+	code, out, errOut := runCLI("plan", writeConfig(t, body))
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	want := "  status 127.0.0.1:1502 unit_id=9 slot=2 hr 60-89\n" +
+		"  delivery 127.0.0.1:1502 unit_id=9 slot=3 hr 90-119\n"
+	if !strings.Contains(out, want) {
+		t.Fatalf("plan:\n%s", out)
+	}
+}
+
+func TestCLI_DuplicateEmitsYAML(t *testing.T) {
+	code, out, errOut := runCLI("duplicate", "--unit", "dev", "--count", "2", writeConfig(t, validYAML))
+
+	// Clones move to free target ids, so they validate as-is.
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	var clones []config.UnitConfig
+	if err := yaml.Unmarshal([]byte(out), &clones); err != nil {
+		t.Fatal(err)
+	}
+	if len(clones) != 2 || clones[0].ID != "dev_1" || clones[0].Source.UnitID != 2 {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	// 7 is the original's target, 9 its status memory.
+	if clones[0].Targets[0].ID != 1 || clones[1].Targets[0].ID != 2 {
+		t.Fatalf("clone target ids %d, %d; want 1, 2", clones[0].Targets[0].ID, clones[1].Targets[0].ID)
+	}
+}
+
+func TestCLI_ResolveRedactsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "name"), []byte("TOPSECRET\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "replicator.yaml")
+	body := strings.Replace(validYAML, `device_name: "DEV"`, `device_name: "${file:name}"`, 1)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut := runCLI("resolve", p)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if strings.Contains(out, "TOPSECRET") || !strings.Contains(out, "<redacted>") {
+		t.Fatalf("secret not redacted:\n%s", out)
+	}
+
+	_, out, _ = runCLI("resolve", "--show-secrets", p)
+	if !strings.Contains(out, "TOPSECRET") {
+		t.Fatalf("--show-secrets:\n%s", out)
+	}
+}
+
+func TestCLI_MigrateInPlace(t *testing.T) {
+	b, err := os.ReadFile("../../internal/config/testdata/migrate/v1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfig(t, string(b))
+
+	// status_slot without status_unit_id is rejected until migrated
+	if code, _, _ := runCLI("validate", path); code != exitInvalid {
+		t.Fatalf("v1: exit %d, want %d", code, exitInvalid)
+	}
+	code, out, errOut := runCLI("migrate", "-w", path)
+	if code != exitOK || out != "" || !strings.Contains(errOut, "version 1 -> 2") {
+		t.Fatalf("exit %d\nstdout %q\nstderr %s", code, out, errOut)
+	}
+
+	code, out, _ = runCLI("lint", path)
+	if code != exitOK {
+		t.Fatalf("migrated file has findings: %s", out)
+	}
+	code, out, _ = runCLI("diff", path, "../../internal/config/example.yaml")
+	if code != exitFindings || !strings.Contains(out, "127.0.0.1:1502 status_unit_id=3: hr 0-29 -> (none)") {
+		t.Fatalf("diff against example.yaml: exit %d\n%s", code, out)
+	}
+}
+
+func TestCLI_RunFailsWhenStartupBuildFails(t *testing.T) {
+	path := writeConfig(t, validYAML)
+
+	// no recording of unit "dev" in an empty replay dir
+	code, _, errOut := runCLI("run", "--replay", t.TempDir(), path)
+	if code != exitInvalid || !strings.Contains(errOut, "startup failed") {
+		t.Fatalf("exit %d, want %d\n%s", code, exitInvalid, errOut)
+	}
+}
+
+func TestCLI_MigrateCurrentLeavesFileAlone(t *testing.T) {
+	body := strings.ReplaceAll(validYAML, "\n", "\r\n")
+	path := writeConfig(t, body)
+
+	code, out, errOut := runCLI("migrate", "-w", path)
+	if code != exitOK || out != "" || !strings.Contains(errOut, "already at version") {
+		t.Fatalf("exit %d\nstdout %q\nstderr %s", code, out, errOut)
+	}
+	if b, _ := os.ReadFile(path); string(b) != body {
+		t.Fatalf("current file rewritten:\n%q", b)
+	}
+}
+
+func TestCLI_MigrateFailureKeepsFile(t *testing.T) {
+	b, err := os.ReadFile("../../internal/config/testdata/migrate/v1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// device-2 writes into device-1's data once both are target id 1
+	body := strings.Replace(string(b), "- id: 2\n", "- id: 1\n", 1)
+	path := writeConfig(t, body)
+
+	code, out, errOut := runCLI("migrate", "-w", path)
+	if code != exitFindings || out != "" || !strings.Contains(errOut, "left unchanged") {
+		t.Fatalf("exit %d\nstdout %q\nstderr %s", code, out, errOut)
+	}
+	if got, _ := os.ReadFile(path); string(got) != body {
+		t.Fatal("failed migration rewrote the file")
+	}
+	if ents, _ := os.ReadDir(filepath.Dir(path)); len(ents) != 1 {
+		t.Fatalf("files left next to the config: %v", ents)
+	}
+
+	// Without -w the migrated config is shown for editing.
+	code, out, _ = runCLI("migrate", path)
+	if code != exitFindings || !strings.Contains(out, "status_unit_id") {
+		t.Fatalf("exit %d\n%s", code, out)
+	}
+}
+
+func TestCLI_QueryExportsHistory(t *testing.T) {
+	dir := t.TempDir()
+	w := historian.NewWriter(historian.Options{Dir: dir}, "dev")
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, v := range []uint16{5, 5, 6} {
+		res := poller.PollResult{
+			At:     at.Add(time.Duration(i) * time.Second),
+			Blocks: []poller.BlockResult{{FC: 3, Address: 10, Quantity: 1, Registers: []uint16{v}}},
+		}
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	cfg := writeConfig(t, strings.Replace(validYAML, "replicator:\n", "replicator:\n  historian: { dir: "+strconv.Quote(dir)+" }\n", 1))
+	code, out, errOut := runCLI("query", "--unit", "dev", "--address", "10", "--from", "2026-03-01", "--to", "2026-03-02", cfg)
+	want := "time,address,value\n2026-03-01T10:00:00.000Z,10,5\n2026-03-01T10:00:02.000Z,10,6\n"
+	if code != exitOK || out != want {
+		t.Fatalf("exit %d\n%s\nstderr: %s", code, out, errOut)
+	}
+}
+
+// silentUnit times out on every request, like an absent unit id.
+type silentUnit struct{}
+
+var errSilent = errors.New("i/o timeout")
+
+func (silentUnit) ReadCoils(uint16, uint16) ([]bool, error)              { return nil, errSilent }
+func (silentUnit) ReadDiscreteInputs(uint16, uint16) ([]bool, error)     { return nil, errSilent }
+func (silentUnit) ReadHoldingRegisters(uint16, uint16) ([]uint16, error) { return nil, errSilent }
+func (silentUnit) ReadInputRegisters(uint16, uint16) ([]uint16, error)   { return nil, errSilent }
+func (silentUnit) Close() error                                          { return nil }
+
+func TestScanIDs_StopsWhenInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dials := 0
+	dial := func(uint8) (scan.Reader, error) {
+		dials++
+		if dials == 3 {
+			cancel() // SIGINT while the third id is probed
+		}
+		return silentUnit{}, nil
+	}
+	ids, _ := parseIntList("1-247", 0, 255)
+
+	found, scanned, _, err := scanIDs(ctx, dial, ids, scan.Options{FCs: []uint8{3}}, io.Discard)
+	if err != nil || len(found) != 0 {
+		t.Fatalf("found %v, err %v", found, err)
+	}
+	if dials != 3 || scanned != 2 {
+		t.Fatalf("dials %d, scanned %d; want 3 and 2", dials, scanned)
+	}
+}
+
+func TestParseIntList(t *testing.T) {
+	got, err := parseIntList("1, 5,10-12", 0, 255)
+	if err != nil || fmt.Sprint(got) != "[1 5 10 11 12]" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "0-256", "9-3", "x"} {
+		if _, err := parseIntList(bad, 0, 255); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// cmd/replicator/migrate.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+// cmdMigrate rewrites a config written for an older layout version to
+// the current one. The result goes to stdout, or back into the file with
+// -w; notes on what changed go to stderr. A file already at the current
+// version is never rewritten. Exit 3 means the migrated config still does
+// not load and needs editing; -w then leaves the file as it was.
+func cmdMigrate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("migrate", stderr)
+	write := fs.Bool("w", false, "rewrite the file in place")
+	ref, ok := parseWithPath(fs, args)
+	if !ok {
+		return exitUsage
+	}
+	// JSON and TOML support arrived with layout 2: nothing to migrate.
+	// Migrate writes YAML, so the file must be YAML by name as well.
+	if (ref.format != config.FormatAuto && ref.format != config.FormatYAML) ||
+		config.DetectFormat(ref.path) != config.FormatYAML {
+		fmt.Fprintln(stderr, "migrate: only YAML configs can predate the current layout")
+		return exitUsage
+	}
+
+	b, err := os.ReadFile(ref.path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitInvalid
+	}
+	res, err := config.Migrate(b)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", ref.path, err)
+		return exitInvalid
+	}
+
+	if res.From == res.To {
+		fmt.Fprintf(stderr, "%s: already at version %d\n", ref.path, res.To)
+		if !*write {
+			_, _ = stdout.Write(b)
+		}
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "%s: version %d -> %d\n", ref.path, res.From, res.To)
+	for _, n := range res.Notes {
+		fmt.Fprintf(stderr, "  %s\n", n)
+	}
+
+	// Check the result as if it were in place, so includes still resolve.
+	if _, err := config.LoadBytes(ref.path, res.Out, config.FormatYAML); err != nil {
+		if !*write {
+			_, _ = stdout.Write(res.Out)
+		}
+		fmt.Fprintf(stderr, "migrated config needs editing: %v\n", err)
+		if *write {
+			fmt.Fprintf(stderr, "%s left unchanged; run without -w to see the migrated config\n", ref.path)
+		}
+		return exitFindings
+	}
+	if !*write {
+		_, _ = stdout.Write(res.Out)
+		return exitOK
+	}
+
+	if err := replaceFile(ref.path, res.Out); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// replaceFile writes b to a temporary file next to path and renames it
+// over path, so path holds either the old or the new content.
+func replaceFile(path string, b []byte) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".migrate-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
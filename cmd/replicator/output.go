@@ -0,0 +1,29 @@
+// cmd/replicator/output.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// formatFlag registers the shared --format flag (text | json).
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "text", "output format: text or json")
+}
+
+func checkFormat(f string, stderr io.Writer) bool {
+	switch f {
+	case "text", "json":
+		return true
+	}
+	fmt.Fprintf(stderr, "unsupported --format %q (text, json)\n", f)
+	return false
+}
+
+func writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,161 @@
+// cmd/replicator/plan.go
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+	"github.com/tamzrod/modbus-replicator/internal/writer"
+)
+
+// Plan views: the writer.Plan of each unit, expanded against the unit's
+// reads into concrete destination address ranges.
+
+type unitPlan struct {
+	Unit    string       `json:"unit"`
+	Source  string       `json:"source"`
+	Targets []targetPlan `json:"targets"`
+	Status  []statusPlan `json:"status"`
+	// Delivery blocks live in the status memory; device_name is unset.
+	Delivery []statusPlan `json:"delivery"`
+}
+
+type targetPlan struct {
+	TargetID uint32      `json:"target_id"`
+	Endpoint string      `json:"endpoint"`
+	Ranges   []rangePlan `json:"ranges"`
+}
+
+type rangePlan struct {
+	Memory int    `json:"memory"` // index into target.memories
+	Area   uint8  `json:"area"`   // Raw Ingest area == source FC
+	Start  uint16 `json:"start"`
+	End    uint16 `json:"end"` // inclusive
+}
+
+type statusPlan struct {
+	Endpoint   string `json:"endpoint"`
+	UnitID     uint8  `json:"unit_id"`
+	Slot       uint16 `json:"slot"`
+	Start      uint16 `json:"start"`
+	End        uint16 `json:"end"` // inclusive
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+func cmdPlan(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("plan", stderr)
+	format := formatFlag(fs)
+	only := fs.String("unit", "", "print only this unit")
+	ref, ok := parseWithPath(fs, args)
+	if !ok || !checkFormat(*format, stderr) {
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(ref)
+	if err != nil {
+		fmt.Fprintf(stderr, "config load failed: %v\n", err)
+		return exitInvalid
+	}
+
+	plans := []unitPlan{}
+	for _, u := range cfg.Replicator.Units {
+		if *only != "" && u.ID != *only {
+			continue
+		}
+		p, err := resolvePlan(u)
+		if err != nil {
+			fmt.Fprintf(stderr, "unit %s: %v\n", u.ID, err)
+			return exitInvalid
+		}
+		plans = append(plans, p)
+	}
+	if *only != "" && len(plans) == 0 {
+		fmt.Fprintf(stderr, "unit %q not found\n", *only)
+		return exitInvalid
+	}
+
+	if *format == "json" {
+		writeJSON(stdout, plans)
+		return exitOK
+	}
+	for _, p := range plans {
+		printPlan(stdout, p)
+	}
+	return exitOK
+}
+
+func resolvePlan(u config.UnitConfig) (unitPlan, error) {
+	wp, err := writer.BuildPlan(u)
+	if err != nil {
+		return unitPlan{}, err
+	}
+
+	out := unitPlan{
+		Unit:     wp.UnitID,
+		Source:   u.Source.Endpoint,
+		Targets:  []targetPlan{},
+		Status:   []statusPlan{},
+		Delivery: []statusPlan{},
+	}
+
+	for _, t := range wp.Targets {
+		tp := targetPlan{TargetID: t.TargetID, Endpoint: t.Endpoint, Ranges: []rangePlan{}}
+		for mi, m := range t.Memories {
+			for _, r := range u.Reads {
+				start := m.Offsets[int(r.FC)] + r.Address
+				tp.Ranges = append(tp.Ranges, rangePlan{
+					Memory: mi,
+					Area:   r.FC,
+					Start:  start,
+					End:    start + r.Quantity - 1,
+				})
+			}
+		}
+		out.Targets = append(out.Targets, tp)
+	}
+
+	for _, s := range wp.Status {
+		start := s.BaseSlot * status.SlotsPerDevice
+		out.Status = append(out.Status, statusPlan{
+			Endpoint:   s.Endpoint,
+			UnitID:     s.UnitID,
+			Slot:       s.BaseSlot,
+			Start:      start,
+			End:        start + status.SlotsPerDevice - 1,
+			DeviceName: s.DeviceName,
+		})
+	}
+
+	for _, d := range wp.Delivery {
+		start := d.BaseSlot * status.SlotsPerDevice
+		out.Delivery = append(out.Delivery, statusPlan{
+			Endpoint: d.Endpoint,
+			UnitID:   d.UnitID,
+			Slot:     d.BaseSlot,
+			Start:    start,
+			End:      start + status.SlotsPerDevice - 1,
+		})
+	}
+
+	return out, nil
+}
+
+func printPlan(w io.Writer, p unitPlan) {
+	fmt.Fprintf(w, "unit %s (source %s)\n", p.Unit, p.Source)
+	for _, t := range p.Targets {
+		fmt.Fprintf(w, "  target %d %s\n", t.TargetID, t.Endpoint)
+		for _, r := range t.Ranges {
+			fmt.Fprintf(w, "    memory[%d] area=%d %d-%d\n", r.Memory, r.Area, r.Start, r.End)
+		}
+	}
+	// Both lists hold one entry per target, in target order.
+	for i, s := range p.Status {
+		fmt.Fprintf(w, "  status %s unit_id=%d slot=%d hr %d-%d\n", s.Endpoint, s.UnitID, s.Slot, s.Start, s.End)
+		if i < len(p.Delivery) {
+			d := p.Delivery[i]
+			fmt.Fprintf(w, "  delivery %s unit_id=%d slot=%d hr %d-%d\n", d.Endpoint, d.UnitID, d.Slot, d.Start, d.End)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// cmd/replicator/query.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/historian"
+)
+
+// cmdQuery exports a unit's values from the local historian as CSV.
+// The directory comes from --dir or from historian.dir of the config.
+func cmdQuery(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("query", stderr)
+	dir := fs.String("dir", "", "historian directory (default: historian.dir of the config)")
+	unit := fs.String("unit", "", "unit id (required)")
+	fc := fs.Uint("fc", 3, "function code 1-4")
+	addrs := fs.String("address", "", "address or inclusive range, e.g. 100 or 100-109 (required)")
+	from := fs.String("from", "", "start, RFC 3339 or 2006-01-02[T15:04] UTC (default: 24h before --to)")
+	to := fs.String("to", "", "end, exclusive (default: now)")
+
+	pos, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsage
+	}
+	if len(pos) > 1 || (len(pos) == 0 && *dir == "") {
+		fmt.Fprintln(stderr, "query: one config path, or --dir, required")
+		return exitUsage
+	}
+	if *unit == "" || *addrs == "" {
+		fmt.Fprintln(stderr, "query: --unit and --address are required")
+		return exitUsage
+	}
+	if *fc < 1 || *fc > 4 {
+		fmt.Fprintln(stderr, "query: --fc must be 1-4")
+		return exitUsage
+	}
+	first, last, err := parseAddresses(*addrs)
+	if err != nil {
+		fmt.Fprintf(stderr, "query: --address: %v\n", err)
+		return exitUsage
+	}
+
+	q := historian.Query{Unit: *unit, FC: uint8(*fc), First: first, Last: last, To: time.Now()}
+	if *to != "" {
+		if q.To, err = parseTime(*to); err != nil {
+			fmt.Fprintf(stderr, "query: --to: %v\n", err)
+			return exitUsage
+		}
+	}
+	q.From = q.To.Add(-24 * time.Hour)
+	if *from != "" {
+		if q.From, err = parseTime(*from); err != nil {
+			fmt.Fprintf(stderr, "query: --from: %v\n", err)
+			return exitUsage
+		}
+	}
+	if !q.From.Before(q.To) {
+		fmt.Fprintln(stderr, "query: --from must be before --to")
+		return exitUsage
+	}
+
+	if *dir == "" {
+		cfg, err := loadConfig(configRef{path: pos[0]})
+		if err != nil {
+			fmt.Fprintf(stderr, "config load failed: %v\n", err)
+			return exitInvalid
+		}
+		if *dir = cfg.Replicator.Historian.Dir; *dir == "" {
+			fmt.Fprintf(stderr, "query: %s has no historian.dir\n", pos[0])
+			return exitInvalid
+		}
+	}
+
+	rows, err := historian.Export(*dir, q, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "query: %v\n", err)
+		return exitInvalid
+	}
+	fmt.Fprintf(stderr, "%d rows\n", rows)
+	return exitOK
+}
+
+// parseAddresses parses "100" or "100-109".
+func parseAddresses(s string) (uint16, uint16, error) {
+	if !strings.Contains(s, "-") {
+		s += "-" + s
+	}
+	return parseSpan(s)
+}
+
+// parseTime accepts RFC 3339, or a UTC date with optional hh:mm.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not RFC 3339 or 2006-01-02[T15:04]", s)
+}
@@ -0,0 +1,52 @@
+// cmd/replicator/resolve.go
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// cmdResolve dumps the configuration exactly as the replicator would run
+// it: includes merged, ${...} interpolated, profiles and generators
+// expanded. Values read through ${file:...} are redacted by default.
+func cmdResolve(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("resolve", stderr)
+	format := fs.String("format", "yaml", "output format: yaml or json")
+	showSecrets := fs.Bool("show-secrets", false, "print ${file:...} values unredacted")
+	ref, ok := parseWithPath(fs, args)
+	if !ok {
+		return exitUsage
+	}
+	if *format != "yaml" && *format != "json" {
+		fmt.Fprintf(stderr, "unsupported --format %q (yaml, json)\n", *format)
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(ref)
+	if err != nil {
+		fmt.Fprintf(stderr, "config load failed: %v\n", err)
+		return exitInvalid
+	}
+	if !*showSecrets {
+		if cfg, err = cfg.Redacted(); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitInvalid
+		}
+	}
+
+	if *format == "json" {
+		writeJSON(stdout, cfg)
+		return exitOK
+	}
+
+	enc := yaml.NewEncoder(stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitInvalid
+	}
+	_ = enc.Close()
+	return exitOK
+}
@@ -0,0 +1,237 @@
+// cmd/replicator/run.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/api"
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/events"
+	"github.com/tamzrod/modbus-replicator/internal/historian"
+	"github.com/tamzrod/modbus-replicator/internal/logging"
+	"github.com/tamzrod/modbus-replicator/internal/mqttpub"
+	"github.com/tamzrod/modbus-replicator/internal/supervisor"
+)
+
+// cmdRun is the long-running replicator: today's behaviour, optionally
+// recording the poll streams or replaying them in place of the devices.
+func cmdRun(args []string, _, stderr io.Writer) int {
+	fs := newFlagSet("run", stderr)
+	var opts supervisor.BuildOptions
+	fs.StringVar(&opts.RecordDir, "record", "", "record every unit's poll results to `dir`/<unit id>.rec")
+	fs.StringVar(&opts.ReplayDir, "replay", "", "poll units from their recordings in `dir` instead of the devices")
+	fs.Float64Var(&opts.ReplaySpeed, "replay-speed", 1, "replay pace: 1 is as recorded, 60 plays an hour a minute")
+	ref, ok := parseWithPath(fs, args)
+	if !ok {
+		return exitUsage
+	}
+	if opts.ReplaySpeed <= 0 {
+		fmt.Fprintln(stderr, "run: --replay-speed must be > 0")
+		return exitUsage
+	}
+	if opts.RecordDir != "" {
+		if err := os.MkdirAll(opts.RecordDir, 0o755); err != nil {
+			fmt.Fprintf(stderr, "run: --record: %v\n", err)
+			return exitInvalid
+		}
+	}
+
+	// --------------------
+	// Load + validate config
+	// --------------------
+	cfg, err := loadConfig(ref)
+	if err != nil {
+		fmt.Fprintf(stderr, "config load failed: %v\n", err)
+		return exitInvalid
+	}
+
+	// Format and suppression window are fixed at startup; levels reload.
+	logger, levels, err := logging.Setup(stderr, cfg.Replicator.Logging)
+	if err != nil {
+		fmt.Fprintf(stderr, "logging setup failed: %v\n", err)
+		return exitInvalid
+	}
+	slog.SetDefault(logger)
+	logWarnings(logger, cfg)
+
+	// The historian, like the API listener, is fixed at startup.
+	opts.Historian = historian.FromConfig(cfg.Replicator.Historian)
+
+	// So is the MQTT session; it is closed after the units have said
+	// goodbye, so their shutdown health reaches the broker.
+	if m := cfg.Replicator.MQTT; m.Broker != "" {
+		opts.MQTT = mqttpub.New(mqttpub.FromConfig(m), logger)
+		defer opts.MQTT.Close()
+	}
+
+	// Change events are only worth detecting when someone can see them.
+	if cfg.Replicator.HTTP.Listen != "" || cfg.Replicator.Events.Log != "" {
+		hub, err := events.NewHub(cfg.Replicator.Events.Log)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitInvalid
+		}
+		defer hub.Close()
+		opts.Events = hub
+	}
+
+	// SIGINT / SIGTERM (docker stop) cancel ctx.
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// --------------------
+	// Start per-unit pipelines
+	// --------------------
+	sup := supervisor.New(opts.Builder(), logger)
+	if rep := sup.Apply(cfg, "startup"); rep.Err != "" {
+		// A unit that did start is stopped again: run with all or none.
+		sup.Shutdown()
+		logger.Error("startup failed", "err", rep.Err)
+		return exitInvalid
+	}
+
+	// Management API is fixed at startup, like file watching.
+	if addr := cfg.Replicator.HTTP.Listen; addr != "" {
+		srv := api.New(sup, logger)
+		srv.Mount("GET /api/events", opts.Events)
+		srv.OnShutdown(opts.Events.EndStreams)
+		go func() {
+			if err := srv.Serve(ctx, addr); err != nil {
+				logger.Error("api stopped", "err", err)
+			}
+		}()
+	}
+
+	// File watching is fixed at startup; SIGHUP always works.
+	var changes <-chan struct{}
+	if ms := cfg.Replicator.Reload.WatchIntervalMs; ms > 0 {
+		changes = config.Watch(ctx, ref.path, time.Duration(ms)*time.Millisecond)
+	}
+
+	reload := func(trigger string) {
+		next, err := loadConfig(ref)
+		if err != nil {
+			sup.Fail(err, trigger)
+			return
+		}
+		logWarnings(logger, next)
+		if rep := sup.Apply(next, trigger); rep.Err != "" {
+			return
+		}
+		// Levels were validated with the rest of the config; they follow
+		// the units only once the supervisor has accepted it.
+		if err := levels.Apply(next.Replicator.Logging); err != nil {
+			logger.Error("logging levels not applied", "trigger", trigger, "err", err)
+		}
+	}
+
+	// --------------------
+	// Run until signalled
+	// --------------------
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("signal received, draining units")
+			sup.Shutdown()
+			logger.Info("shutdown complete")
+			return exitOK
+
+		case <-hup:
+			reload("sighup")
+
+		case <-changes:
+			reload("file")
+		}
+	}
+}
+
+// configRef is the config file named on the command line.
+type configRef struct {
+	path   string
+	format config.Format // FormatAuto unless --config-format is given
+}
+
+// loadConfig is the single load path for every command and for reloads.
+// config.Load already applies defaults, validates and normalizes.
+func loadConfig(ref configRef) (*config.Config, error) {
+	return config.LoadFormat(ref.path, ref.format)
+}
+
+func logWarnings(log *slog.Logger, cfg *config.Config) {
+	for _, w := range cfg.Warnings() {
+		log.Warn("config", "warning", w)
+	}
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parseWithPath parses flags and requires exactly one config path.
+// Flags may appear before or after the path. Every command reading a
+// config gets --config-format here.
+func parseWithPath(fs *flag.FlagSet, args []string) (configRef, bool) {
+	refs, ok := parseWithPaths(fs, args, 1)
+	if !ok {
+		return configRef{}, false
+	}
+	return refs[0], true
+}
+
+// parseWithPaths is parseWithPath for commands taking n configs.
+func parseWithPaths(fs *flag.FlagSet, args []string, n int) ([]configRef, bool) {
+	var format config.Format
+	fs.Func("config-format", "config syntax: yaml, json or toml (default: by file extension)", func(s string) error {
+		f, err := config.ParseFormat(s)
+		format = f
+		return err
+	})
+
+	paths, ok := parseArgs(fs, args)
+	if !ok {
+		return nil, false
+	}
+	if len(paths) != n {
+		if n == 1 {
+			fmt.Fprintf(fs.Output(), "%s: exactly one config path required\n", fs.Name())
+		} else {
+			fmt.Fprintf(fs.Output(), "%s: exactly %d config paths required\n", fs.Name(), n)
+		}
+		return nil, false
+	}
+
+	refs := make([]configRef, n)
+	for i, p := range paths {
+		refs[i] = configRef{path: p, format: format}
+	}
+	return refs, true
+}
+
+// parseArgs parses flags placed anywhere among the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, bool) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, false
+		}
+		if fs.NArg() == 0 {
+			return pos, true
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,184 @@
+// cmd/replicator/scan.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+	"github.com/tamzrod/modbus-replicator/internal/scan"
+)
+
+// cmdScan sweeps unit ids on a Modbus TCP endpoint, maps the readable
+// ranges of every unit that answers, and emits replicator.units entries
+// (targets left empty) for them.
+func cmdScan(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("scan", stderr)
+	unitIDs := fs.String("unit-ids", "1-247", "unit ids to probe, e.g. 1-247 or 1,5,10-20")
+	fcs := fs.String("fc", "1,2,3,4", "function codes to map")
+	addrs := fs.String("range", "0-9999", "address range to map, inclusive")
+	step := fs.Uint("step", 10, "stride across unreadable addresses (1-125); smaller finds shorter runs, slower")
+	timeoutMs := fs.Int("timeout-ms", 500, "per-request timeout")
+	prefix := fs.String("id-prefix", "unit", "generated unit ids are <prefix>-<unit_id>")
+	format := fs.String("format", "yaml", "output format: yaml or json")
+
+	pos, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsage
+	}
+	if len(pos) != 1 {
+		fmt.Fprintln(stderr, "scan: exactly one endpoint (host:port) required")
+		return exitUsage
+	}
+	endpoint := pos[0]
+
+	ids, err := parseIntList(*unitIDs, 0, 255)
+	if err != nil {
+		fmt.Fprintf(stderr, "scan: --unit-ids: %v\n", err)
+		return exitUsage
+	}
+	fcList, err := parseIntList(*fcs, 1, 4)
+	if err != nil {
+		fmt.Fprintf(stderr, "scan: --fc: %v\n", err)
+		return exitUsage
+	}
+	start, end, err := parseSpan(*addrs)
+	if err != nil {
+		fmt.Fprintf(stderr, "scan: --range: %v\n", err)
+		return exitUsage
+	}
+	if *step < 1 || *step > 125 || *timeoutMs <= 0 {
+		fmt.Fprintln(stderr, "scan: --step must be 1-125 and --timeout-ms > 0")
+		return exitUsage
+	}
+	if *format != "yaml" && *format != "json" {
+		fmt.Fprintf(stderr, "unsupported --format %q (yaml, json)\n", *format)
+		return exitUsage
+	}
+
+	opt := scan.Options{
+		Start: start,
+		End:   end,
+		Step:  uint16(*step),
+	}
+	for _, fc := range fcList {
+		opt.FCs = append(opt.FCs, uint8(fc))
+	}
+
+	timeout := time.Duration(*timeoutMs) * time.Millisecond
+	dial := func(id uint8) (scan.Reader, error) {
+		return pmodbus.New(pmodbus.Config{Endpoint: endpoint, UnitID: id, Timeout: timeout})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	found, scanned, requests, err := scanIDs(ctx, dial, ids, opt, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "scan %s %v\n", endpoint, err)
+		return exitInvalid
+	}
+	var units []config.UnitConfig
+	for _, res := range found {
+		units = append(units, res.UnitConfig(fmt.Sprintf("%s-%d", *prefix, res.UnitID), endpoint, *timeoutMs))
+	}
+	fmt.Fprintf(stderr, "scanned %d unit ids on %s: %d answered (%d requests)\n", scanned, endpoint, len(units), requests)
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		fmt.Fprintf(stderr, "scan interrupted: %d of %d unit ids not probed\n", len(ids)-scanned, len(ids))
+	}
+
+	if len(units) == 0 {
+		return exitInvalid
+	}
+	if *format == "json" {
+		writeJSON(stdout, units)
+		return exitOK
+	}
+
+	fmt.Fprintf(stdout, "# replicator scan %s: add targets before use.\n", endpoint)
+	fmt.Fprintln(stdout, "# A device that ignores the unit id answers on every id; keep one.")
+	enc := yaml.NewEncoder(stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(units); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitInvalid
+	}
+	_ = enc.Close()
+	if interrupted {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// scanIDs probes ids in order and returns the units that answered. It
+// stops early, without error, once ctx is done: scan.Unit only looks at
+// ctx after a unit answers, so without the check an interrupted sweep
+// would keep waiting out the timeout of every absent id.
+func scanIDs(ctx context.Context, dial scan.Dialer, ids []int, opt scan.Options, stderr io.Writer) (found []scan.UnitResult, scanned, requests int, err error) {
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := scan.Unit(ctx, dial, uint8(id), opt)
+		requests += res.Requests
+		switch {
+		case ctx.Err() != nil:
+			return found, scanned, requests, nil
+		case errors.Is(err, scan.ErrAbsent):
+		case err != nil:
+			return found, scanned, requests, fmt.Errorf("unit %d: %w", id, err)
+		default:
+			fmt.Fprintf(stderr, "unit %d: %s\n", id, res.Summary())
+			found = append(found, res)
+		}
+		scanned++
+	}
+	return found, scanned, requests, nil
+}
+
+// parseIntList parses "1,5,10-20" into its values, each within [lo, hi].
+func parseIntList(s string, lo, hi int) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		a, b, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		from, err := strconv.Atoi(a)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", a)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(b); err != nil {
+				return nil, fmt.Errorf("%q is not a number", b)
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("%q outside %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v++ {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// parseSpan parses an inclusive address range "start-end".
+func parseSpan(s string) (uint16, uint16, error) {
+	a, b, ok := strings.Cut(s, "-")
+	from, err1 := strconv.ParseUint(a, 10, 16)
+	to, err2 := strconv.ParseUint(b, 10, 16)
+	if !ok || err1 != nil || err2 != nil || from > to {
+		return 0, 0, fmt.Errorf("%q is not start-end within 0-65535", s)
+	}
+	return uint16(from), uint16(to), nil
+}
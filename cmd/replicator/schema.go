@@ -0,0 +1,29 @@
+// cmd/replicator/schema.go
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+// cmdSchema prints the JSON Schema of the config file format.
+// docs/config.schema.json is this output, committed.
+func cmdSchema(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("schema", stderr)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		if err == nil {
+			fmt.Fprintln(stderr, "schema takes no arguments")
+		}
+		return exitUsage
+	}
+
+	b, err := config.Schema()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitInvalid
+	}
+	_, _ = stdout.Write(b)
+	return exitOK
+}
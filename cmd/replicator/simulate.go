@@ -0,0 +1,84 @@
+// cmd/replicator/simulate.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/tamzrod/modbus-replicator/internal/logging"
+	"github.com/tamzrod/modbus-replicator/internal/sim"
+)
+
+// cmdSimulate serves a simulated Modbus TCP device until interrupted.
+// The config file is optional; flags override it.
+func cmdSimulate(args []string, _, stderr io.Writer) int {
+	fs := newFlagSet("simulate", stderr)
+	listen := fs.String("listen", "", "host:port to serve on (default: config listen, then "+sim.DefaultListen+")")
+	seed := fs.String("seed", "", "CSV memory seed: area,address,value[,value...]")
+	unitIDs := fs.String("unit-ids", "", "unit ids to answer, e.g. 1,5-7 (default: config unit_ids, else all)")
+	gateway := fs.Bool("gateway", false, "answer unlisted unit ids with exception 0x0B instead of silence")
+	level := fs.String("log-level", "info", "debug logs every injected fault")
+
+	pos, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsage
+	}
+	if len(pos) > 1 {
+		fmt.Fprintln(stderr, "simulate: at most one config path")
+		return exitUsage
+	}
+	lv, err := logging.ParseLevel(*level)
+	if err != nil {
+		fmt.Fprintf(stderr, "simulate: --log-level: %v\n", err)
+		return exitUsage
+	}
+
+	var cfg sim.Config
+	if len(pos) == 1 {
+		if cfg, err = sim.LoadConfig(pos[0]); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitInvalid
+		}
+	}
+	if *seed != "" {
+		cfg.Seed = *seed
+	}
+	if *unitIDs != "" {
+		ids, err := parseIntList(*unitIDs, 0, 255)
+		if err != nil {
+			fmt.Fprintf(stderr, "simulate: --unit-ids: %v\n", err)
+			return exitUsage
+		}
+		cfg.UnitIDs = cfg.UnitIDs[:0]
+		for _, id := range ids {
+			cfg.UnitIDs = append(cfg.UnitIDs, uint8(id))
+		}
+	}
+	if *gateway {
+		cfg.Gateway = true
+	}
+
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: lv}))
+	srv, err := sim.New(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(stderr, "simulate: %v\n", err)
+		return exitInvalid
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := srv.Serve(ctx, *listen); err != nil {
+		fmt.Fprintf(stderr, "simulate: %v\n", err)
+		return exitInvalid
+	}
+	st := srv.Stats()
+	logger.Info("simulator stopped", "connections", st.Connections, "requests", st.Requests,
+		"exceptions", st.Exceptions, "faults", st.Faults)
+	return exitOK
+}
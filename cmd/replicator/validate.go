@@ -0,0 +1,81 @@
+// cmd/replicator/validate.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+// validateResult is the --format json shape of validate and lint.
+type validateResult struct {
+	Path     string              `json:"path"`
+	Valid    bool                `json:"valid"`
+	Error    string              `json:"error,omitempty"`
+	Errors   []config.FieldError `json:"errors,omitempty"`
+	Units    int                 `json:"units"`
+	Findings []config.Finding    `json:"findings"`
+}
+
+func cmdValidate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate", stderr)
+	format := formatFlag(fs)
+	strict := fs.Bool("strict", false, "exit 3 when lint findings are reported")
+	ref, ok := parseWithPath(fs, args)
+	if !ok || !checkFormat(*format, stderr) {
+		return exitUsage
+	}
+	return validate(ref, *format, *strict, stdout)
+}
+
+func cmdLint(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("lint", stderr)
+	format := formatFlag(fs)
+	ref, ok := parseWithPath(fs, args)
+	if !ok || !checkFormat(*format, stderr) {
+		return exitUsage
+	}
+	return validate(ref, *format, true, stdout)
+}
+
+func validate(ref configRef, format string, strict bool, stdout io.Writer) int {
+	res := validateResult{Path: ref.path, Findings: []config.Finding{}}
+
+	cfg, err := loadConfig(ref)
+	if err != nil {
+		res.Error = err.Error()
+		var verrs config.ValidationErrors
+		if errors.As(err, &verrs) {
+			res.Errors = verrs
+		}
+	} else {
+		res.Valid = true
+		res.Units = len(cfg.Replicator.Units)
+		if f := config.Lint(cfg); f != nil {
+			res.Findings = f
+		}
+	}
+
+	if format == "json" {
+		writeJSON(stdout, res)
+	} else {
+		for _, f := range res.Findings {
+			fmt.Fprintf(stdout, "warning: %s\n", f)
+		}
+		if res.Valid {
+			fmt.Fprintf(stdout, "%s: ok (%d units, %d findings)\n", ref.path, res.Units, len(res.Findings))
+		} else {
+			fmt.Fprintf(stdout, "%s: invalid: %s\n", ref.path, res.Error)
+		}
+	}
+
+	switch {
+	case !res.Valid:
+		return exitInvalid
+	case strict && len(res.Findings) > 0:
+		return exitFindings
+	}
+	return exitOK
+}
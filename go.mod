@@ -3,6 +3,7 @@ module github.com/tamzrod/modbus-replicator
 go 1.22
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/tamzrod/modbus v0.1.1
 	gopkg.in/yaml.v3 v3.0.1
 )
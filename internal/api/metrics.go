@@ -0,0 +1,99 @@
+// internal/api/metrics.go
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/tamzrod/modbus-replicator/internal/metrics"
+	"github.com/tamzrod/modbus-replicator/internal/runner"
+)
+
+// handleMetrics renders every unit in OpenMetrics text format.
+// Values are read from the same runner views as the JSON API;
+// nothing here keeps its own state.
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	families := s.metricFamilies(s.p.Units())
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	_ = metrics.Write(w, families)
+}
+
+func (s *Server) metricFamilies(units []runner.Info) []*metrics.Family {
+	var (
+		requests = &metrics.Family{Name: "replicator_poll_requests", Type: metrics.TypeCounter,
+			Help: "Poll cycles attempted."}
+		valid = &metrics.Family{Name: "replicator_poll_responses_valid", Type: metrics.TypeCounter,
+			Help: "Poll cycles where every read succeeded."}
+		timeouts = &metrics.Family{Name: "replicator_poll_timeouts", Type: metrics.TypeCounter,
+			Help: "Poll cycles failed by timeout."}
+		transportErrs = &metrics.Family{Name: "replicator_poll_transport_errors", Type: metrics.TypeCounter,
+			Help: "Poll cycles failed by a non-timeout error."}
+		consecutive = &metrics.Family{Name: "replicator_poll_consecutive_failures", Type: metrics.TypeGauge,
+			Help: "Current run of failed poll cycles."}
+		reconnects = &metrics.Family{Name: "replicator_source_reconnects", Type: metrics.TypeCounter,
+			Help: "Source client (re)connections, including the first lazy connect."}
+		pollLatency = &metrics.Family{Name: "replicator_poll_duration_seconds", Type: metrics.TypeHistogram,
+			Help: "Wall time of one poll cycle."}
+		health = &metrics.Family{Name: "replicator_unit_health", Type: metrics.TypeGauge,
+			Help: "Status block health code (0 unknown, 1 ok, 2 error, 3 stale, 4 disabled)."}
+		secondsInError = &metrics.Family{Name: "replicator_unit_seconds_in_error", Type: metrics.TypeGauge,
+			Help: "Status block seconds_in_error."}
+
+		writes = &metrics.Family{Name: "replicator_target_writes", Type: metrics.TypeCounter,
+			Help: "Data deliveries per target and poll result, by outcome."}
+		writeLatency = &metrics.Family{Name: "replicator_target_write_duration_seconds", Type: metrics.TypeHistogram,
+			Help: "Wall time to deliver one poll result to a target."}
+		packets = &metrics.Family{Name: "replicator_target_ingest_packets", Type: metrics.TypeCounter,
+			Help: "Raw Ingest packets fully sent to the target endpoint."}
+		bytes = &metrics.Family{Name: "replicator_target_ingest_bytes", Type: metrics.TypeCounter,
+			Help: "Raw Ingest bytes fully sent to the target endpoint."}
+	)
+
+	for _, info := range units {
+		ul := metrics.Labels{"unit": info.UnitID}
+		if u := s.unitConfig(info.UnitID); u != nil {
+			ul["source"] = u.Source.Endpoint
+		}
+
+		c := info.Counters
+		requests.Add(ul, float64(c.RequestsTotal))
+		valid.Add(ul, float64(c.ResponsesValidTotal))
+		timeouts.Add(ul, float64(c.TimeoutsTotal))
+		transportErrs.Add(ul, float64(c.TransportErrorsTotal))
+		consecutive.Add(ul, float64(c.ConsecutiveFailCurr))
+		reconnects.Add(ul, float64(c.ReconnectsTotal))
+		pollLatency.AddHistogram(ul, info.PollLatency)
+		health.Add(ul, float64(info.Snapshot.Health))
+		secondsInError.Add(ul, float64(info.Snapshot.SecondsInError))
+
+		for _, t := range info.Targets {
+			tl := metrics.Labels{
+				"unit":      info.UnitID,
+				"target":    t.Endpoint,
+				"target_id": strconv.FormatUint(uint64(t.TargetID), 10),
+			}
+
+			writes.Add(withLabel(tl, "result", "success"), float64(t.Successes))
+			writes.Add(withLabel(tl, "result", "failure"), float64(t.Failures))
+			writeLatency.AddHistogram(tl, t.Latency)
+			packets.Add(tl, float64(t.IngestPackets))
+			bytes.Add(tl, float64(t.IngestBytes))
+		}
+	}
+
+	return []*metrics.Family{
+		requests, valid, timeouts, transportErrs, consecutive, reconnects,
+		pollLatency, health, secondsInError,
+		writes, writeLatency, packets, bytes,
+	}
+}
+
+func withLabel(l metrics.Labels, k, v string) metrics.Labels {
+	out := make(metrics.Labels, len(l)+1)
+	for lk, lv := range l {
+		out[lk] = lv
+	}
+	out[k] = v
+	return out
+}
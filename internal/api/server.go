@@ -0,0 +1,206 @@
+// internal/api/server.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/logging"
+	"github.com/tamzrod/modbus-replicator/internal/runner"
+	"github.com/tamzrod/modbus-replicator/internal/supervisor"
+)
+
+// Provider is the read-only runtime view the API serves.
+// *supervisor.Supervisor satisfies it.
+type Provider interface {
+	Units() []runner.Info
+	Config() *config.Config
+	LastReload() supervisor.ReloadReport
+}
+
+// Server is the embedded HTTP management API.
+// It is strictly read-only: nothing served here can change runtime state.
+type Server struct {
+	p   Provider
+	mux *http.ServeMux
+	log *slog.Logger
+
+	onShutdown []func()
+}
+
+// New builds the API around p. log nil discards.
+func New(p Provider, log *slog.Logger) *Server {
+	s := &Server{p: p, mux: http.NewServeMux(), log: logging.OrDiscard(log)}
+
+	// ---- probes ----
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+
+	// ---- runtime ----
+	s.mux.HandleFunc("GET /api/units", s.handleUnits)
+	s.mux.HandleFunc("GET /api/units/{id}", s.handleUnit)
+	s.mux.HandleFunc("GET /api/units/{id}/poll", s.handleUnitPoll)
+	s.mux.HandleFunc("GET /api/units/{id}/targets", s.handleUnitTargets)
+	s.mux.HandleFunc("GET /api/config", s.handleConfig)
+	s.mux.HandleFunc("GET /api/reload", s.handleReload)
+
+	// ---- metrics ----
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	return s
+}
+
+// Handler exposes the routes for embedding or httptest.
+func (s *Server) Handler() http.Handler { return s.mux }
+
+// Mount registers an additional handler on the API mux.
+func (s *Server) Mount(pattern string, h http.Handler) { s.mux.Handle(pattern, h) }
+
+// OnShutdown registers f to run when Serve begins shutting down.
+// Long-lived handlers use it to return: shutdown does not cancel their
+// request contexts.
+func (s *Server) OnShutdown(f func()) { s.onShutdown = append(s.onShutdown, f) }
+
+// Serve listens on addr until ctx is cancelled, then shuts down gracefully.
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	for _, f := range s.onShutdown {
+		srv.RegisterOnShutdown(f)
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	s.log.Info("api listening", "addr", ln.Addr().String())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+// Handlers
+// ------------------------------------------------------------
+
+// handleHealthz is liveness: the process is up and serving.
+func (s *Server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz is readiness: a configuration has been applied.
+func (s *Server) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	if s.p.Config() == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "starting"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (s *Server) handleUnits(w http.ResponseWriter, _ *http.Request) {
+	units := s.p.Units()
+	out := make([]unitSummary, 0, len(units))
+	for _, info := range units {
+		out = append(out, summarize(info, s.unitConfig(info.UnitID)))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleUnit(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, detail(info, s.unitConfig(info.UnitID)))
+}
+
+func (s *Server) handleUnitPoll(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, pollOf(info.LastPoll))
+}
+
+func (s *Server) handleUnitTargets(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, targetsOf(info.Targets))
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, _ *http.Request) {
+	cfg := s.p.Config()
+	if cfg == nil {
+		writeError(w, http.StatusServiceUnavailable, "no configuration applied yet")
+		return
+	}
+	red, err := cfg.Redacted()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, red)
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.p.LastReload())
+}
+
+// ------------------------------------------------------------
+// Helpers
+// ------------------------------------------------------------
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (runner.Info, bool) {
+	id := r.PathValue("id")
+	for _, info := range s.p.Units() {
+		if info.UnitID == id {
+			return info, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "unit "+id+" not found")
+	return runner.Info{}, false
+}
+
+func (s *Server) unitConfig(id string) *config.UnitConfig {
+	cfg := s.p.Config()
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.Replicator.Units {
+		if cfg.Replicator.Units[i].ID == id {
+			return &cfg.Replicator.Units[i]
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
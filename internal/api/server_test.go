@@ -0,0 +1,264 @@
+// internal/api/server_test.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/runner"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+	"github.com/tamzrod/modbus-replicator/internal/supervisor"
+	"github.com/tamzrod/modbus-replicator/internal/writer"
+)
+
+type fakeProvider struct {
+	units  []runner.Info
+	cfg    *config.Config
+	reload supervisor.ReloadReport
+}
+
+func (f *fakeProvider) Units() []runner.Info                { return f.units }
+func (f *fakeProvider) Config() *config.Config              { return f.cfg }
+func (f *fakeProvider) LastReload() supervisor.ReloadReport { return f.reload }
+
+func newProvider() *fakeProvider {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	return &fakeProvider{
+		units: []runner.Info{
+			{
+				UnitID: "inv-1",
+				Snapshot: status.Snapshot{
+					Health:        status.HealthOK,
+					RequestsTotal: 5,
+				},
+				Counters: poller.TransportCounters{RequestsTotal: 5, ResponsesValidTotal: 5},
+				LastPoll: &poller.PollResult{
+					UnitID: "inv-1",
+					At:     at,
+					Blocks: []poller.BlockResult{
+						{FC: 3, Address: 0, Quantity: 2, Registers: []uint16{7, 8}},
+					},
+				},
+				Targets: []writer.TargetOutcome{
+					{TargetID: 1, Endpoint: "mma:1502", LastWriteAt: at, Successes: 5},
+				},
+			},
+			{
+				UnitID:   "inv-2",
+				Snapshot: status.Snapshot{Health: status.HealthError, LastErrorCode: 11},
+				LastPoll: &poller.PollResult{UnitID: "inv-2", At: at, Err: errors.New("timeout")},
+			},
+		},
+		cfg: &config.Config{Replicator: config.ReplicatorConfig{
+			Units: []config.UnitConfig{
+				{ID: "inv-1", Source: config.SourceConfig{Endpoint: "10.0.0.1:502"}},
+				{ID: "inv-2", Source: config.SourceConfig{Endpoint: "10.0.0.2:502"}},
+			},
+			MQTT: config.MQTTConfig{Broker: "127.0.0.1:1883", Password: "hunter2"},
+		}},
+		reload: supervisor.ReloadReport{Trigger: "sighup", Unchanged: []string{"inv-1", "inv-2"}},
+	}
+}
+
+func get(t *testing.T, srv *httptest.Server, path string, wantCode int, into any) {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantCode {
+		t.Fatalf("GET %s: status=%d want=%d", path, resp.StatusCode, wantCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("GET %s: content-type=%q", path, ct)
+	}
+	if into != nil {
+		if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+			t.Fatalf("GET %s: decode: %v", path, err)
+		}
+	}
+}
+
+func TestProbes(t *testing.T) {
+	p := newProvider()
+	srv := httptest.NewServer(New(p, nil).Handler())
+	defer srv.Close()
+
+	get(t, srv, "/healthz", http.StatusOK, nil)
+	get(t, srv, "/readyz", http.StatusOK, nil)
+
+	p.cfg = nil
+	get(t, srv, "/readyz", http.StatusServiceUnavailable, nil)
+	get(t, srv, "/healthz", http.StatusOK, nil)
+}
+
+func TestUnitsList(t *testing.T) {
+	srv := httptest.NewServer(New(newProvider(), nil).Handler())
+	defer srv.Close()
+
+	var units []unitSummary
+	get(t, srv, "/api/units", http.StatusOK, &units)
+
+	if len(units) != 2 {
+		t.Fatalf("expected 2 units, got %d", len(units))
+	}
+	if units[0].ID != "inv-1" || units[0].HealthName != "ok" || units[0].SourceEndpoint != "10.0.0.1:502" {
+		t.Fatalf("unexpected first unit: %+v", units[0])
+	}
+	if units[1].HealthName != "error" || units[1].LastErrorCode != 11 {
+		t.Fatalf("unexpected second unit: %+v", units[1])
+	}
+}
+
+func TestUnitDetail(t *testing.T) {
+	srv := httptest.NewServer(New(newProvider(), nil).Handler())
+	defer srv.Close()
+
+	var d unitDetail
+	get(t, srv, "/api/units/inv-1", http.StatusOK, &d)
+
+	if d.Counters.ResponsesValidTotal != 5 || d.Status.RequestsTotal != 5 {
+		t.Fatalf("counters/status not rendered: %+v", d)
+	}
+	if d.LastPoll == nil || len(d.LastPoll.Blocks) != 1 || d.LastPoll.Blocks[0].Registers[1] != 8 {
+		t.Fatalf("last poll not rendered: %+v", d.LastPoll)
+	}
+	if len(d.Targets) != 1 || !d.Targets[0].OK || d.Targets[0].Successes != 5 {
+		t.Fatalf("targets not rendered: %+v", d.Targets)
+	}
+
+	get(t, srv, "/api/units/missing", http.StatusNotFound, nil)
+}
+
+func TestUnitPollAndTargets(t *testing.T) {
+	srv := httptest.NewServer(New(newProvider(), nil).Handler())
+	defer srv.Close()
+
+	var pv pollView
+	get(t, srv, "/api/units/inv-2/poll", http.StatusOK, &pv)
+	if pv.Err != "timeout" || len(pv.Blocks) != 0 {
+		t.Fatalf("unexpected poll view: %+v", pv)
+	}
+
+	var tv []targetView
+	get(t, srv, "/api/units/inv-1/targets", http.StatusOK, &tv)
+	if len(tv) != 1 || tv[0].Endpoint != "mma:1502" {
+		t.Fatalf("unexpected targets: %+v", tv)
+	}
+}
+
+func TestConfigAndReload(t *testing.T) {
+	srv := httptest.NewServer(New(newProvider(), nil).Handler())
+	defer srv.Close()
+
+	var cfg config.Config
+	get(t, srv, "/api/config", http.StatusOK, &cfg)
+	if len(cfg.Replicator.Units) != 2 || cfg.Replicator.Units[1].Source.Endpoint != "10.0.0.2:502" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if cfg.Replicator.MQTT.Password != "<redacted>" {
+		t.Fatalf("inline mqtt password served: %q", cfg.Replicator.MQTT.Password)
+	}
+
+	var rep supervisor.ReloadReport
+	get(t, srv, "/api/reload", http.StatusOK, &rep)
+	if rep.Trigger != "sighup" || len(rep.Unchanged) != 2 {
+		t.Fatalf("unexpected reload report: %+v", rep)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	srv := httptest.NewServer(New(newProvider(), nil).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Fatalf("content-type=%q", ct)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`replicator_poll_requests_total{source="10.0.0.1:502",unit="inv-1"} 5`,
+		`replicator_unit_health{source="10.0.0.2:502",unit="inv-2"} 2`,
+		`replicator_target_writes_total{result="success",target="mma:1502",target_id="1",unit="inv-1"} 5`,
+		`replicator_poll_duration_seconds_count{source="10.0.0.1:502",unit="inv-1"} 0`,
+		"# EOF",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestServe_OnShutdownEndsLongLivedHandlers(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// A stream that only returns when told to, like /api/events.
+	release := make(chan struct{})
+	s := New(newProvider(), nil)
+	s.Mount("GET /stream", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	s.OnShutdown(func() { close(release) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, addr) }()
+
+	var resp *http.Response
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		if resp, err = http.Get("http://" + addr + "/stream"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET /stream: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	// Shutdown does not cancel request contexts: without the hook the
+	// stream would stay open past the shutdown deadline.
+	ended := make(chan error, 1)
+	go func() { _, err := io.ReadAll(resp.Body); ended <- err }()
+	select {
+	case err := <-ended:
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream still open after shutdown")
+	}
+}
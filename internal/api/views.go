@@ -0,0 +1,182 @@
+// internal/api/views.go
+package api
+
+import (
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/runner"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+	"github.com/tamzrod/modbus-replicator/internal/writer"
+)
+
+// JSON views. Runtime types carry no JSON tags on purpose:
+// the wire shape of the API is owned here, not by the engine.
+
+type unitSummary struct {
+	ID             string     `json:"id"`
+	SourceEndpoint string     `json:"source_endpoint,omitempty"`
+	Health         uint16     `json:"health"`
+	HealthName     string     `json:"health_name"`
+	LastErrorCode  uint16     `json:"last_error_code"`
+	SecondsInError uint16     `json:"seconds_in_error"`
+	LastPollAt     *time.Time `json:"last_poll_at,omitempty"`
+}
+
+type unitDetail struct {
+	unitSummary
+	Status   snapshotView `json:"status"`
+	Counters countersView `json:"counters"`
+	LastPoll *pollView    `json:"last_poll,omitempty"`
+	Targets  []targetView `json:"targets"`
+}
+
+type snapshotView struct {
+	Health               uint16 `json:"health"`
+	LastErrorCode        uint16 `json:"last_error_code"`
+	SecondsInError       uint16 `json:"seconds_in_error"`
+	LastPollDurationMs   uint16 `json:"last_poll_duration_ms"`
+	LastSuccessEpoch     uint32 `json:"last_success_epoch"`
+	SecondsSinceGood     uint16 `json:"seconds_since_good"`
+	RequestsTotal        uint32 `json:"requests_total"`
+	ResponsesValidTotal  uint32 `json:"responses_valid_total"`
+	TimeoutsTotal        uint32 `json:"timeouts_total"`
+	TransportErrorsTotal uint32 `json:"transport_errors_total"`
+	ConsecutiveFailCurr  uint16 `json:"consecutive_fail_current"`
+	ConsecutiveFailMax   uint16 `json:"consecutive_fail_max"`
+}
+
+type countersView struct {
+	RequestsTotal        uint32 `json:"requests_total"`
+	ResponsesValidTotal  uint32 `json:"responses_valid_total"`
+	TimeoutsTotal        uint32 `json:"timeouts_total"`
+	TransportErrorsTotal uint32 `json:"transport_errors_total"`
+	ConsecutiveFailCurr  uint16 `json:"consecutive_fail_current"`
+	ConsecutiveFailMax   uint16 `json:"consecutive_fail_max"`
+}
+
+type pollView struct {
+	At     time.Time   `json:"at"`
+	Err    string      `json:"error,omitempty"`
+	Blocks []blockView `json:"blocks"`
+}
+
+type blockView struct {
+	FC        uint8    `json:"fc"`
+	Address   uint16   `json:"address"`
+	Quantity  uint16   `json:"quantity"`
+	Bits      []bool   `json:"bits,omitempty"`
+	Registers []uint16 `json:"registers,omitempty"`
+}
+
+type targetView struct {
+	TargetID    uint32     `json:"target_id"`
+	Endpoint    string     `json:"endpoint"`
+	LastWriteAt *time.Time `json:"last_write_at,omitempty"`
+	LastErr     string     `json:"last_error,omitempty"`
+	OK          bool       `json:"ok"`
+	Successes   uint64     `json:"successes"`
+	Failures    uint64     `json:"failures"`
+
+	ConsecutiveFailures uint64     `json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+}
+
+func summarize(info runner.Info, u *config.UnitConfig) unitSummary {
+	s := unitSummary{
+		ID:             info.UnitID,
+		Health:         info.Snapshot.Health,
+		HealthName:     status.HealthName(info.Snapshot.Health),
+		LastErrorCode:  info.Snapshot.LastErrorCode,
+		SecondsInError: info.Snapshot.SecondsInError,
+	}
+	if u != nil {
+		s.SourceEndpoint = u.Source.Endpoint
+	}
+	if info.LastPoll != nil {
+		at := info.LastPoll.At
+		s.LastPollAt = &at
+	}
+	return s
+}
+
+func detail(info runner.Info, u *config.UnitConfig) unitDetail {
+	snap := info.Snapshot
+	c := info.Counters
+
+	return unitDetail{
+		unitSummary: summarize(info, u),
+		Status: snapshotView{
+			Health:               snap.Health,
+			LastErrorCode:        snap.LastErrorCode,
+			SecondsInError:       snap.SecondsInError,
+			LastPollDurationMs:   snap.LastPollDurationMs,
+			LastSuccessEpoch:     snap.LastSuccessEpoch,
+			SecondsSinceGood:     snap.SecondsSinceGood,
+			RequestsTotal:        snap.RequestsTotal,
+			ResponsesValidTotal:  snap.ResponsesValidTotal,
+			TimeoutsTotal:        snap.TimeoutsTotal,
+			TransportErrorsTotal: snap.TransportErrorsTotal,
+			ConsecutiveFailCurr:  snap.ConsecutiveFailCurr,
+			ConsecutiveFailMax:   snap.ConsecutiveFailMax,
+		},
+		Counters: countersView{
+			RequestsTotal:        c.RequestsTotal,
+			ResponsesValidTotal:  c.ResponsesValidTotal,
+			TimeoutsTotal:        c.TimeoutsTotal,
+			TransportErrorsTotal: c.TransportErrorsTotal,
+			ConsecutiveFailCurr:  c.ConsecutiveFailCurr,
+			ConsecutiveFailMax:   c.ConsecutiveFailMax,
+		},
+		LastPoll: pollOf(info.LastPoll),
+		Targets:  targetsOf(info.Targets),
+	}
+}
+
+func pollOf(res *poller.PollResult) *pollView {
+	if res == nil {
+		return nil
+	}
+
+	v := &pollView{At: res.At, Blocks: make([]blockView, 0, len(res.Blocks))}
+	if res.Err != nil {
+		v.Err = res.Err.Error()
+	}
+	for _, b := range res.Blocks {
+		v.Blocks = append(v.Blocks, blockView{
+			FC:        b.FC,
+			Address:   b.Address,
+			Quantity:  b.Quantity,
+			Bits:      b.Bits,
+			Registers: b.Registers,
+		})
+	}
+	return v
+}
+
+func targetsOf(outcomes []writer.TargetOutcome) []targetView {
+	out := make([]targetView, 0, len(outcomes))
+	for _, o := range outcomes {
+		v := targetView{
+			TargetID:  o.TargetID,
+			Endpoint:  o.Endpoint,
+			LastErr:   o.LastErr,
+			OK:        o.LastErr == "" && !o.LastWriteAt.IsZero(),
+			Successes: o.Successes,
+			Failures:  o.Failures,
+
+			ConsecutiveFailures: o.ConsecutiveFailures,
+		}
+		if !o.LastWriteAt.IsZero() {
+			at := o.LastWriteAt
+			v.LastWriteAt = &at
+		}
+		if !o.LastSuccessAt.IsZero() {
+			at := o.LastSuccessAt
+			v.LastSuccessAt = &at
+		}
+		out = append(out, v)
+	}
+	return out
+}
@@ -2,60 +2,242 @@
 package config
 
 type Config struct {
-	Replicator ReplicatorConfig `yaml:"replicator"`
+	// Version is the layout version of the file (0 => CurrentVersion).
+	// Older layouts are rewritten by Migrate.
+	Version int `yaml:"version,omitempty" json:"version"`
+
+	Replicator ReplicatorConfig `yaml:"replicator" json:"replicator"`
+
+	// secrets are values read through ${file:...}; see Redacted.
+	secrets []string
+
+	// warnings are non-fatal load findings; see Warnings.
+	warnings []string
+}
+
+// Warnings returns what Load accepted but wants a person to look at
+// (deprecated keys, normalized values).
+func (c *Config) Warnings() []string {
+	return append([]string(nil), c.warnings...)
 }
 
 type ReplicatorConfig struct {
-	Units []UnitConfig `yaml:"units"`
+	Profiles  map[string]ProfileConfig `yaml:"profiles" json:"profiles,omitempty"`
+	Units     []UnitConfig             `yaml:"units" json:"units"`
+	Shutdown  ShutdownConfig           `yaml:"shutdown" json:"shutdown"`
+	Reload    ReloadConfig             `yaml:"reload" json:"reload"`
+	HTTP      HTTPConfig               `yaml:"http" json:"http"`
+	Logging   LoggingConfig            `yaml:"logging" json:"logging"`
+	Historian HistorianConfig          `yaml:"historian" json:"historian"`
+	MQTT      MQTTConfig               `yaml:"mqtt" json:"mqtt"`
+	Events    EventsConfig             `yaml:"events" json:"events"`
+}
+
+// ---- SHUTDOWN ----
+
+// ShutdownConfig controls what happens on SIGINT/SIGTERM.
+type ShutdownConfig struct {
+	// TimeoutMs bounds how long in-flight writes may drain (0 => 5000).
+	TimeoutMs int `yaml:"timeout_ms" json:"timeout_ms"`
+
+	// Status is the health asserted to every status target on exit:
+	// "disabled" (default), "unknown" or "stale".
+	Status string `yaml:"status" json:"status"`
+}
+
+// ---- RELOAD ----
+
+// ReloadConfig controls hot configuration reload.
+// SIGHUP always triggers a reload; file watching is opt-in.
+type ReloadConfig struct {
+	// WatchIntervalMs polls the config file for changes (0 => disabled).
+	WatchIntervalMs int `yaml:"watch_interval_ms" json:"watch_interval_ms"`
+}
+
+// ---- HTTP ----
+
+// HTTPConfig enables the embedded management API.
+type HTTPConfig struct {
+	// Listen is the host:port to serve on (empty => disabled).
+	Listen string `yaml:"listen" json:"listen"`
+}
+
+// ---- LOGGING ----
+
+// LoggingConfig controls the structured process logger.
+type LoggingConfig struct {
+	// Level is the global minimum: debug, info (default), warn, error.
+	Level string `yaml:"level" json:"level"`
+
+	// Format is text (default) or json. Fixed at startup.
+	Format string `yaml:"format" json:"format"`
+
+	// Units overrides Level per unit id.
+	Units map[string]string `yaml:"units" json:"units"`
+
+	// SuppressWindowMs rate-limits identical warnings/errors (0 => 60000).
+	SuppressWindowMs int `yaml:"suppress_window_ms" json:"suppress_window_ms"`
+}
+
+// ---- HISTORIAN ----
+
+// HistorianConfig keeps trend data in local files. Fixed at startup.
+type HistorianConfig struct {
+	// Dir holds one directory of CSV files per unit (empty => disabled).
+	Dir string `yaml:"dir" json:"dir"`
+
+	// Rotate starts a new file every "daily" (default) or "hourly" period.
+	Rotate string `yaml:"rotate" json:"rotate"`
+
+	// RetentionDays deletes files older than N days (0 => keep forever).
+	RetentionDays int `yaml:"retention_days" json:"retention_days"`
+}
+
+// ---- MQTT ----
+
+// MQTTConfig publishes every unit's values and health to a broker.
+// Fixed at startup.
+type MQTTConfig struct {
+	// Broker is host:port of an MQTT 3.1.1 broker (empty => disabled).
+	Broker   string `yaml:"broker" json:"broker"`
+	ClientID string `yaml:"client_id" json:"client_id"` // "" => modbus-replicator
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+
+	// QoS is 0 (default) or 1. QoS 1 messages are resent after a
+	// reconnect until the broker acknowledges them.
+	QoS    int  `yaml:"qos" json:"qos"`
+	Retain bool `yaml:"retain" json:"retain"` // json data topics only
+
+	KeepAliveMs int `yaml:"keepalive_ms" json:"keepalive_ms"` // 0 => 30000
+	TimeoutMs   int `yaml:"timeout_ms" json:"timeout_ms"`     // 0 => 5000
+	ReconnectMs int `yaml:"reconnect_ms" json:"reconnect_ms"` // 0 => 5000
+
+	// QueueSize bounds messages held while the broker is unreachable;
+	// the oldest are dropped first (0 => 10000).
+	QueueSize int `yaml:"queue_size" json:"queue_size"`
+
+	// Format is "json" (default) or "sparkplug_b".
+	Format string `yaml:"format" json:"format"`
+
+	// TopicPrefix roots the json topics (empty => modbus-replicator).
+	TopicPrefix string `yaml:"topic_prefix" json:"topic_prefix"`
+
+	// GroupID and EdgeNodeID name the Sparkplug B edge node
+	// (empty => modbus-replicator / the client id).
+	GroupID    string `yaml:"group_id" json:"group_id"`
+	EdgeNodeID string `yaml:"edge_node_id" json:"edge_node_id"`
+}
+
+// ---- EVENTS ----
+
+// EventsConfig controls the change-of-value event stream. Events are
+// produced when the API is enabled (GET /api/events) or Log is set.
+// Fixed at startup.
+type EventsConfig struct {
+	// Log appends every event as one JSON line (empty => no log).
+	Log string `yaml:"log" json:"log"`
 }
 
 // ---- UNIT ----
 
 type UnitConfig struct {
-	ID      string         `yaml:"id"`
-	Source  SourceConfig   `yaml:"source"`
-	Reads   []ReadConfig   `yaml:"reads"`
-	Targets []TargetConfig `yaml:"targets"`
-	Poll    PollConfig     `yaml:"poll"`
+	ID      string         `yaml:"id" json:"id"`
+	Source  SourceConfig   `yaml:"source" json:"source"`
+	Reads   []ReadConfig   `yaml:"reads" json:"reads"`
+	Targets []TargetConfig `yaml:"targets" json:"targets"`
+	Poll    PollConfig     `yaml:"poll" json:"poll"`
+
+	// Profile names an entry of replicator.profiles to inherit from.
+	// Kept after expansion for reference only.
+	Profile string `yaml:"profile,omitempty" json:"profile,omitempty"`
+
+	// Generate expands this entry into N units at load time.
+	// Always nil after Load.
+	Generate *GenerateConfig `yaml:"generate,omitempty" json:"generate,omitempty"`
+}
+
+// ---- PROFILES / GENERATORS ----
+
+// ProfileConfig is a reusable unit template: everything except identity.
+type ProfileConfig struct {
+	Source  SourceConfig   `yaml:"source" json:"source"`
+	Reads   []ReadConfig   `yaml:"reads" json:"reads"`
+	Targets []TargetConfig `yaml:"targets" json:"targets"`
+	Poll    PollConfig     `yaml:"poll" json:"poll"`
+}
+
+// GenerateConfig expands one unit entry into Count units.
+// Instance n (Start, Start+1, …) replaces "{n}" in id and device_name.
+type GenerateConfig struct {
+	Count int `yaml:"count" json:"count"`
+	Start int `yaml:"start" json:"start"` // first {n}; 0 => 1
+
+	// IPStep / PortStep advance source.endpoint per instance
+	// (last IPv4 octet / TCP port).
+	IPStep   int `yaml:"ip_step" json:"ip_step"`
+	PortStep int `yaml:"port_step" json:"port_step"`
+
+	// UnitID is "fixed" (default) or "increment" (next free unit_id).
+	UnitID string `yaml:"unit_id" json:"unit_id"`
+
+	// OffsetStep advances every target memory offset per instance, by FC.
+	OffsetStep map[int]uint16 `yaml:"offset_step" json:"offset_step"`
 }
 
 // ---- SOURCE ----
 
 type SourceConfig struct {
-	Endpoint  string `yaml:"endpoint"`
-	UnitID    uint8  `yaml:"unit_id"`
-	TimeoutMs int    `yaml:"timeout_ms"`
+	Endpoint  string `yaml:"endpoint" json:"endpoint"`
+	UnitID    uint8  `yaml:"unit_id" json:"unit_id"`
+	TimeoutMs int    `yaml:"timeout_ms" json:"timeout_ms"`
 
 	// Device status block (optional, opt-in)
-	StatusSlot *uint16 `yaml:"status_slot"`
-	DeviceName string  `yaml:"device_name"`
+	StatusSlot *uint16 `yaml:"status_slot" json:"status_slot"`
+	DeviceName string  `yaml:"device_name" json:"device_name"`
+
+	// Target delivery block (optional, opt-in; in the status memory,
+	// so it needs status_slot)
+	DeliverySlot *uint16 `yaml:"delivery_slot,omitempty" json:"delivery_slot,omitempty"`
 }
 
 // ---- READ GEOMETRY ----
 
 type ReadConfig struct {
-	FC       uint8  `yaml:"fc"`
-	Address  uint16 `yaml:"address"`
-	Quantity uint16 `yaml:"quantity"`
+	FC       uint8  `yaml:"fc" json:"fc"`
+	Address  uint16 `yaml:"address" json:"address"`
+	Quantity uint16 `yaml:"quantity" json:"quantity"`
+
+	// Deadband filters change events of a register block (optional).
+	// It never affects what is written to targets.
+	Deadband *DeadbandConfig `yaml:"deadband,omitempty" json:"deadband,omitempty"`
+}
+
+// DeadbandConfig suppresses change events for small register moves.
+// A change is reported once it exceeds every limit set, measured from
+// the last reported value of the register.
+type DeadbandConfig struct {
+	Absolute float64 `yaml:"absolute" json:"absolute"` // raw register counts (0 => any change)
+	Percent  float64 `yaml:"percent" json:"percent"`   // of the last reported value (0 => any change)
 }
 
 // ---- TARGET ----
 
 type TargetConfig struct {
-	ID           uint32         `yaml:"id"`
-	Endpoint     string         `yaml:"endpoint"`
-	UnitID       uint8          `yaml:"unit_id"`        // data memory
-	StatusUnitID *uint8         `yaml:"status_unit_id"` // per-target status memory (optional)
-	Memories     []MemoryConfig `yaml:"memories"`
+	ID           uint32         `yaml:"id" json:"id"`
+	Endpoint     string         `yaml:"endpoint" json:"endpoint"`
+	UnitID       uint8          `yaml:"unit_id" json:"unit_id"`               // data memory
+	StatusUnitID *uint8         `yaml:"status_unit_id" json:"status_unit_id"` // per-target status memory (optional)
+	Memories     []MemoryConfig `yaml:"memories" json:"memories"`
 }
 
 type MemoryConfig struct {
-	MemoryID uint16         `yaml:"memory_id"`
-	Offsets  map[int]uint16 `yaml:"offsets"` // delta map; missing FC => 0
+	MemoryID uint16         `yaml:"memory_id" json:"memory_id"`
+	Offsets  map[int]uint16 `yaml:"offsets" json:"offsets"` // delta map; missing FC => 0
 }
 
 // ---- POLL ----
 
 type PollConfig struct {
-	IntervalMs int `yaml:"interval_ms"`
+	IntervalMs int `yaml:"interval_ms" json:"interval_ms"`
 }
@@ -0,0 +1,103 @@
+// internal/config/defaults.go
+package config
+
+// Defaults. This is the only place a zero value in the file is turned
+// into a runtime value; nothing downstream should pick its own.
+const (
+	DefaultSourceTimeoutMs   = 2000
+	DefaultPollIntervalMs    = 1000
+	DefaultShutdownTimeoutMs = 5000
+	DefaultShutdownStatus    = "disabled"
+	DefaultLogLevel          = "info"
+	DefaultLogFormat         = "text"
+	DefaultSuppressWindowMs  = 60000
+	DefaultHistorianRotate   = "daily"
+	DefaultMQTTClientID      = "modbus-replicator"
+	DefaultMQTTKeepAliveMs   = 30000
+	DefaultMQTTTimeoutMs     = 5000
+	DefaultMQTTReconnectMs   = 5000
+	DefaultMQTTQueueSize     = 10000
+	DefaultMQTTFormat        = "json"
+	DefaultMQTTTopicPrefix   = "modbus-replicator"
+	DefaultMQTTGroupID       = "modbus-replicator"
+)
+
+// ApplyDefaults fills every field whose zero value means "default".
+// source.timeout_ms defaults to DefaultSourceTimeoutMs capped at the
+// unit's poll interval.
+// Fields where zero means "off" (reload.watch_interval_ms, http.listen,
+// historian.dir, mqtt.broker, status_slot) are left alone; the rest of
+// the mqtt section is only filled in when a broker is set. Negative values are left
+// for Validate.
+func ApplyDefaults(cfg *Config) {
+	if cfg == nil {
+		return
+	}
+	if cfg.Version == 0 {
+		cfg.Version = CurrentVersion
+	}
+	r := &cfg.Replicator
+
+	for i := range r.Units {
+		u := &r.Units[i]
+		if u.Poll.IntervalMs == 0 {
+			u.Poll.IntervalMs = DefaultPollIntervalMs
+		}
+		// A poll must be able to time out before the next tick
+		// (Validate rejects interval_ms < timeout_ms).
+		if u.Source.TimeoutMs == 0 {
+			u.Source.TimeoutMs = min(DefaultSourceTimeoutMs, u.Poll.IntervalMs)
+		}
+	}
+
+	if r.Shutdown.TimeoutMs == 0 {
+		r.Shutdown.TimeoutMs = DefaultShutdownTimeoutMs
+	}
+	if r.Shutdown.Status == "" {
+		r.Shutdown.Status = DefaultShutdownStatus
+	}
+
+	if r.Logging.Level == "" {
+		r.Logging.Level = DefaultLogLevel
+	}
+	if r.Logging.Format == "" {
+		r.Logging.Format = DefaultLogFormat
+	}
+	if r.Logging.SuppressWindowMs == 0 {
+		r.Logging.SuppressWindowMs = DefaultSuppressWindowMs
+	}
+
+	if r.Historian.Rotate == "" {
+		r.Historian.Rotate = DefaultHistorianRotate
+	}
+
+	if m := &r.MQTT; m.Broker != "" {
+		if m.ClientID == "" {
+			m.ClientID = DefaultMQTTClientID
+		}
+		if m.KeepAliveMs == 0 {
+			m.KeepAliveMs = DefaultMQTTKeepAliveMs
+		}
+		if m.TimeoutMs == 0 {
+			m.TimeoutMs = DefaultMQTTTimeoutMs
+		}
+		if m.ReconnectMs == 0 {
+			m.ReconnectMs = DefaultMQTTReconnectMs
+		}
+		if m.QueueSize == 0 {
+			m.QueueSize = DefaultMQTTQueueSize
+		}
+		if m.Format == "" {
+			m.Format = DefaultMQTTFormat
+		}
+		if m.TopicPrefix == "" {
+			m.TopicPrefix = DefaultMQTTTopicPrefix
+		}
+		if m.GroupID == "" {
+			m.GroupID = DefaultMQTTGroupID
+		}
+		if m.EdgeNodeID == "" {
+			m.EdgeNodeID = m.ClientID
+		}
+	}
+}
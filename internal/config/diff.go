@@ -0,0 +1,244 @@
+// internal/config/diff.go
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change kinds reported by Diff.
+const (
+	ChangeUnitAdded   = "unit-added"
+	ChangeUnitRemoved = "unit-removed"
+	ChangeField       = "field" // a setting changed value
+	ChangeRange       = "range" // a destination address range appeared, vanished or moved
+)
+
+// Change is one semantic difference between two loaded configs.
+type Change struct {
+	Unit string `json:"unit,omitempty"` // empty for global settings
+	Kind string `json:"kind"`
+	What string `json:"what"` // field path, or destination for ranges
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeUnitAdded:
+		return "+ unit " + c.Unit
+	case ChangeUnitRemoved:
+		return "- unit " + c.Unit
+	}
+	prefix := "~ "
+	if c.Unit != "" {
+		prefix += "unit " + c.Unit + ": "
+	}
+	return fmt.Sprintf("%s%s: %s -> %s", prefix, c.What, orNone(c.Old), orNone(c.New))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// Diff compares two configs as they run (after Load): every global
+// setting, units added or removed, and for units in both every changed
+// field and every destination range that moved. Profiles are not
+// compared; their effect shows on the units using them.
+func Diff(a, b *Config) []Change {
+	out := diffFields("", "", reflect.ValueOf(globals(a)), reflect.ValueOf(globals(b)))
+	for i, c := range out {
+		if c.What == "replicator.mqtt.password" {
+			out[i].Old, out[i].New = redactedOrNone(c.Old), redactedOrNone(c.New)
+		}
+	}
+
+	oldUnits := make(map[string]UnitConfig)
+	for _, u := range a.Replicator.Units {
+		oldUnits[u.ID] = u
+	}
+	newUnits := make(map[string]UnitConfig)
+	for _, u := range b.Replicator.Units {
+		newUnits[u.ID] = u
+	}
+
+	ids := make(map[string]bool)
+	for id := range oldUnits {
+		ids[id] = true
+	}
+	for id := range newUnits {
+		ids[id] = true
+	}
+
+	for _, id := range sortedKeys(ids) {
+		ou, inOld := oldUnits[id]
+		nu, inNew := newUnits[id]
+		switch {
+		case !inOld:
+			out = append(out, Change{Unit: id, Kind: ChangeUnitAdded})
+		case !inNew:
+			out = append(out, Change{Unit: id, Kind: ChangeUnitRemoved})
+		default:
+			out = append(out, diffFields(id, "", reflect.ValueOf(ou), reflect.ValueOf(nu))...)
+			out = append(out, diffRanges(id, ou, nu)...)
+		}
+	}
+	return out
+}
+
+func diffFields(unit, prefix string, a, b reflect.Value) []Change {
+	oldKeys, oldVals := flatten(prefix, a)
+	newKeys, newVals := flatten(prefix, b)
+
+	var out []Change
+	seen := make(map[string]bool)
+	for _, k := range append(oldKeys, newKeys...) {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		if oldVals[k] != newVals[k] {
+			out = append(out, Change{Unit: unit, Kind: ChangeField, What: k, Old: oldVals[k], New: newVals[k]})
+		}
+	}
+	return out
+}
+
+// flatten lists every leaf of v by yaml path, in struct order.
+// Nil pointers and empty maps or slices have no leaves.
+func flatten(prefix string, v reflect.Value) ([]string, map[string]string) {
+	var keys []string
+	vals := make(map[string]string)
+
+	var walk func(p string, v reflect.Value)
+	walk = func(p string, v reflect.Value) {
+		join := func(name string) string {
+			if p == "" {
+				return name
+			}
+			return p + "." + name
+		}
+
+		switch v.Kind() {
+		case reflect.Pointer:
+			if !v.IsNil() {
+				walk(p, v.Elem())
+			}
+		case reflect.Struct:
+			t := v.Type()
+			for i := 0; i < t.NumField(); i++ {
+				if !t.Field(i).IsExported() {
+					continue
+				}
+				name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+				walk(join(name), v.Field(i))
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				walk(fmt.Sprintf("%s[%d]", p, i), v.Index(i))
+			}
+		case reflect.Map:
+			mk := v.MapKeys()
+			sort.Slice(mk, func(i, j int) bool { return fmt.Sprint(mk[i]) < fmt.Sprint(mk[j]) })
+			for _, k := range mk {
+				walk(join(fmt.Sprint(k)), v.MapIndex(k))
+			}
+		default:
+			keys = append(keys, p)
+			vals[p] = fmt.Sprint(v.Interface())
+		}
+	}
+	walk(prefix, v)
+	return keys, vals
+}
+
+// globals is c without its units and profiles, so every other section,
+// including ones added later, is compared field by field.
+func globals(c *Config) Config {
+	g := Config{Version: c.Version, Replicator: c.Replicator}
+	g.Replicator.Units, g.Replicator.Profiles = nil, nil
+	return g
+}
+
+// redactedOrNone keeps a credential out of diff output.
+func redactedOrNone(v string) string {
+	if v == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// destinations maps every destination a unit writes to onto the address
+// ranges it occupies there, e.g.
+//
+//	"10.0.0.1:9000 target_id=1 fc=3" -> "0-49, 100-124"
+//	"10.0.0.1:9000 status_unit_id=35" -> "hr 30-59"
+func destinations(u UnitConfig) map[string]string {
+	type span struct{ start, end uint32 }
+	ranges := make(map[string][]span)
+	status := make(map[string]bool)
+
+	for _, t := range u.Targets {
+		for _, m := range t.Memories {
+			for _, r := range u.Reads {
+				if r.Quantity == 0 {
+					continue
+				}
+				start := uint32(m.Offsets[int(r.FC)]) + uint32(r.Address)
+				// memory_id is not on the wire; the target id is the unit id.
+				key := fmt.Sprintf("%s target_id=%d fc=%d", t.Endpoint, t.ID, r.FC)
+				ranges[key] = append(ranges[key], span{start, start + uint32(r.Quantity) - 1})
+			}
+		}
+		if u.Source.StatusSlot != nil && t.StatusUnitID != nil {
+			start := uint32(*u.Source.StatusSlot) * statusBlockSlots
+			key := fmt.Sprintf("%s status_unit_id=%d", t.Endpoint, *t.StatusUnitID)
+			ranges[key] = append(ranges[key], span{start, start + statusBlockSlots - 1})
+			status[key] = true
+
+			if u.Source.DeliverySlot != nil {
+				start := uint32(*u.Source.DeliverySlot) * statusBlockSlots
+				ranges[key] = append(ranges[key], span{start, start + statusBlockSlots - 1})
+			}
+		}
+	}
+
+	out := make(map[string]string, len(ranges))
+	for k, spans := range ranges {
+		sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+		parts := make([]string, len(spans))
+		for i, sp := range spans {
+			parts[i] = fmt.Sprintf("%d-%d", sp.start, sp.end)
+		}
+		out[k] = strings.Join(parts, ", ")
+		if status[k] {
+			out[k] = "hr " + out[k]
+		}
+	}
+	return out
+}
+
+func diffRanges(unit string, a, b UnitConfig) []Change {
+	oldDest, newDest := destinations(a), destinations(b)
+
+	keys := make(map[string]bool)
+	for k := range oldDest {
+		keys[k] = true
+	}
+	for k := range newDest {
+		keys[k] = true
+	}
+
+	var out []Change
+	for _, k := range sortedKeys(keys) {
+		if oldDest[k] != newDest[k] {
+			out = append(out, Change{Unit: unit, Kind: ChangeRange, What: k, Old: oldDest[k], New: newDest[k]})
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+// internal/config/diff_test.go
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff_IdenticalIsEmpty(t *testing.T) {
+	a, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load("example.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(a, b); len(d) != 0 {
+		t.Fatalf("expected no changes, got %v", d)
+	}
+}
+
+func TestDiff_UnitsFieldsAndRanges(t *testing.T) {
+	a, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Replicator.Logging.Level = "debug"
+	d1 := &b.Replicator.Units[0]
+	d1.Source.TimeoutMs = 500
+	d1.Targets[0].Memories[0].Offsets = map[int]uint16{3: 100}
+	b.Replicator.Units[1] = unit("device-3", "127.0.0.1:1502", 2, 3, 0, 10, 0)
+
+	var got []string
+	for _, c := range Diff(a, b) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"~ replicator.logging.level: info -> debug",
+		"~ unit device-1: source.timeout_ms: 1000 -> 500",
+		"~ unit device-1: targets[0].memories[0].offsets.3: (none) -> 100",
+		"~ unit device-1: 127.0.0.1:1502 target_id=1 fc=3: 0-124 -> 100-224",
+		"- unit device-2",
+		"+ unit device-3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDiff_StatusDestinationMoved(t *testing.T) {
+	a, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot := uint16(2)
+	b.Replicator.Units[0].Source.StatusSlot = &slot
+
+	var moved bool
+	for _, c := range Diff(a, b) {
+		if c.Kind == ChangeRange && c.What == "127.0.0.1:1502 status_unit_id=10" {
+			moved = c.Old == "hr 0-29" && c.New == "hr 60-89"
+		}
+	}
+	if !moved {
+		t.Fatalf("status move not reported: %v", Diff(a, b))
+	}
+}
+
+func TestDiff_TargetIDMoved(t *testing.T) {
+	a, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same endpoint, memory and offsets: only the unit id on the wire moves.
+	b.Replicator.Units[0].Targets[0].ID = 5
+
+	var from, to bool
+	for _, c := range Diff(a, b) {
+		switch {
+		case c.Kind == ChangeRange && c.What == "127.0.0.1:1502 target_id=1 fc=3":
+			from = c.Old == "0-124" && c.New == ""
+		case c.Kind == ChangeRange && c.What == "127.0.0.1:1502 target_id=5 fc=3":
+			to = c.Old == "" && c.New == "0-124"
+		}
+	}
+	if !from || !to {
+		t.Fatalf("target id move not reported: %v", Diff(a, b))
+	}
+}
+
+func TestDiff_EveryGlobalSection(t *testing.T) {
+	a, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Replicator.MQTT.Password = "old"
+	b.Version = CurrentVersion + 1
+	b.Replicator.Historian.Dir = "/var/lib/replicator"
+	b.Replicator.MQTT.Password = "new"
+	b.Replicator.Events.Log = "/var/log/events.jsonl"
+
+	got := make(map[string]Change)
+	for _, c := range Diff(a, b) {
+		got[c.What] = c
+	}
+	for _, what := range []string{"version", "replicator.historian.dir", "replicator.mqtt.password", "replicator.events.log"} {
+		if _, ok := got[what]; !ok {
+			t.Errorf("%s change not reported: %v", what, Diff(a, b))
+		}
+	}
+	if c := got["replicator.mqtt.password"]; c.Old != redactedValue || c.New != redactedValue {
+		t.Errorf("password shown in diff: %+v", c)
+	}
+}
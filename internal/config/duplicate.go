@@ -127,6 +127,8 @@ func deepCopyUnit(u UnitConfig) UnitConfig {
 //     when all values from source+1 to 255 are occupied.
 //   - Source.StatusSlot – (if set) incremented from the source value until a
 //     slot that is not used by any existing unit is found.
+//   - Source.DeliverySlot – (if set) likewise; status and delivery blocks
+//     share one slot space.
 //
 // Every other field (Endpoint, Reads, Targets, Memories, Offsets, Poll, …) is
 // deep-copied with no shared references.
@@ -158,6 +160,9 @@ func DuplicateUnit(cfg *Config, sourceID string) (UnitConfig, error) {
 		if u.Source.StatusSlot != nil {
 			usedStatusSlots[*u.Source.StatusSlot] = true
 		}
+		if u.Source.DeliverySlot != nil {
+			usedStatusSlots[*u.Source.DeliverySlot] = true
+		}
 	}
 
 	// Resolve unique unit_id.
@@ -174,6 +179,17 @@ func DuplicateUnit(cfg *Config, sourceID string) (UnitConfig, error) {
 			return UnitConfig{}, fmt.Errorf("duplicate: %w", err)
 		}
 		newStatusSlot = &slot
+		usedStatusSlots[slot] = true
+	}
+
+	// The delivery block shares the status slot space.
+	var newDeliverySlot *uint16
+	if src.Source.DeliverySlot != nil {
+		slot, err := nextFreeStatusSlot(*src.Source.DeliverySlot, usedStatusSlots)
+		if err != nil {
+			return UnitConfig{}, fmt.Errorf("duplicate: %w", err)
+		}
+		newDeliverySlot = &slot
 	}
 
 	// Deep-copy and assign the new identity values.
@@ -186,6 +202,7 @@ func DuplicateUnit(cfg *Config, sourceID string) (UnitConfig, error) {
 	dup.ID = newID
 	dup.Source.UnitID = newUnitID
 	dup.Source.StatusSlot = newStatusSlot
+	dup.Source.DeliverySlot = newDeliverySlot
 
 	return dup, nil
 }
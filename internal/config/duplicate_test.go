@@ -163,6 +163,25 @@ func TestDuplicateUnit_StatusSlot_CollisionSkipped(t *testing.T) {
 	}
 }
 
+func TestDuplicateUnit_DeliverySlot_SharesSlotSpace(t *testing.T) {
+	// A holds slots 0 (status) and 1 (delivery) → 2 and 3.
+	a := makeUnit("A", 1, ptr(uint16(0)))
+	a.Source.DeliverySlot = ptr(uint16(1))
+	dup, err := DuplicateUnit(cfg1(a), "A")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dup.Source.StatusSlot == nil || *dup.Source.StatusSlot != 2 {
+		t.Fatalf("expected StatusSlot=2, got %v", dup.Source.StatusSlot)
+	}
+	if dup.Source.DeliverySlot == nil || *dup.Source.DeliverySlot != 3 {
+		t.Fatalf("expected DeliverySlot=3, got %v", dup.Source.DeliverySlot)
+	}
+	if *a.Source.DeliverySlot != 1 {
+		t.Errorf("source DeliverySlot changed to %d", *a.Source.DeliverySlot)
+	}
+}
+
 func TestDuplicateUnit_StatusSlot_ExhaustedReturnsError(t *testing.T) {
 	// Build a config where status_slot 65535 is in use, so start=65534 has
 	// nowhere left to go (only candidate is 65535, which is taken).
@@ -0,0 +1,278 @@
+// internal/config/format.go
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a config file syntax. Every format is parsed into the same
+// YAML node tree, so includes, interpolation, the known-fields check and
+// decoding behave identically and produce identical Config values.
+type Format string
+
+const (
+	FormatAuto Format = "" // by file extension; YAML when unknown
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+// ParseFormat accepts a --config-format value ("" means auto).
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatAuto, FormatYAML, FormatJSON, FormatTOML:
+		return f, nil
+	case "yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("unknown config format %q (yaml, json, toml)", s)
+}
+
+// DetectFormat picks the format from the file extension.
+func DetectFormat(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatYAML
+}
+
+// parseDocument returns the root node of one config file.
+// An empty document is an empty mapping.
+func parseDocument(path string, b []byte, f Format) (*yaml.Node, error) {
+	switch f {
+	case FormatJSON:
+		return parseJSON(path, b)
+	case FormatTOML:
+		return parseTOML(path, b)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, &PosError{File: path, Err: err}
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// ---- JSON ----
+
+// parseJSON walks the token stream so nodes keep line:col positions.
+func parseJSON(path string, b []byte) (*yaml.Node, error) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+
+	p := &jsonParser{dec: json.NewDecoder(bytes.NewReader(b)), src: b, path: path}
+	p.dec.UseNumber()
+
+	n, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, p.errorf("trailing data after document")
+	}
+	return n, nil
+}
+
+type jsonParser struct {
+	dec  *json.Decoder
+	src  []byte
+	path string
+}
+
+func (p *jsonParser) value() (*yaml.Node, error) {
+	off := p.dec.InputOffset()
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, p.wrap(err)
+	}
+	line, col := p.at(off)
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line, Column: col}
+			for p.dec.More() {
+				koff := p.dec.InputOffset()
+				kt, err := p.dec.Token()
+				if err != nil {
+					return nil, p.wrap(err)
+				}
+				kl, kc := p.at(koff)
+				k := &yaml.Node{Kind: yaml.ScalarNode, Value: kt.(string), Line: kl, Column: kc}
+				v, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				m.Content = append(m.Content, k, v)
+			}
+			_, err := p.dec.Token()
+			return m, p.wrap(err)
+		}
+		s := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line, Column: col}
+		for p.dec.More() {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			s.Content = append(s.Content, v)
+		}
+		_, err := p.dec.Token()
+		return s, p.wrap(err)
+
+	case string:
+		return stringNode(t, line, col), nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String(), Line: line, Column: col}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t), Line: line, Column: col}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null", Line: line, Column: col}, nil
+}
+
+func (p *jsonParser) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		line, col := lineCol(p.src, se.Offset)
+		return &PosError{File: p.path, Line: line, Col: col, Err: err}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &PosError{File: p.path, Err: err}
+}
+
+func (p *jsonParser) errorf(format string, args ...any) error {
+	line, col := lineCol(p.src, p.dec.InputOffset())
+	return &PosError{File: p.path, Line: line, Col: col, Err: fmt.Errorf(format, args...)}
+}
+
+// lineCol converts a byte offset into 1-based line and column.
+func lineCol(src []byte, off int64) (int, int) {
+	if off > int64(len(src)) {
+		off = int64(len(src))
+	}
+	before := src[:off]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(off) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// at is the position of the token read from off. InputOffset points
+// before the whitespace and the ':' or ',' the decoder consumes along
+// with the next token.
+func (p *jsonParser) at(off int64) (int, int) {
+	for off < int64(len(p.src)) && strings.IndexByte(" \t\r\n:,", p.src[off]) >= 0 {
+		off++
+	}
+	return lineCol(p.src, off)
+}
+
+// ---- TOML ----
+
+// parseTOML decodes into generic values and rebuilds them as nodes in
+// document order. TOML keeps no per-key positions, so errors after
+// parsing carry the file name only.
+func parseTOML(path string, b []byte) (*yaml.Node, error) {
+	var v map[string]any
+	md, err := toml.Decode(string(b), &v)
+	if err != nil {
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			return nil, &PosError{File: path, Line: pe.Position.Line, Col: pe.Position.Col, Err: errors.New(pe.Message)}
+		}
+		return nil, &PosError{File: path, Err: err}
+	}
+
+	order := make(map[string]int)
+	for i, k := range md.Keys() {
+		if _, ok := order[k.String()]; !ok {
+			order[k.String()] = i
+		}
+	}
+	return tomlNode(v, nil, order), nil
+}
+
+func tomlNode(v any, key toml.Key, order map[string]int) *yaml.Node {
+	switch t := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		pos := func(k string) int {
+			return order[append(key[:len(key):len(key)], k).String()]
+		}
+		sort.SliceStable(keys, func(i, j int) bool { return pos(keys[i]) < pos(keys[j]) })
+
+		m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			m.Content = append(m.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: k},
+				tomlNode(t[k], append(key[:len(key):len(key)], k), order))
+		}
+		return m
+
+	case []map[string]any:
+		s := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, e := range t {
+			s.Content = append(s.Content, tomlNode(e, key, order))
+		}
+		return s
+
+	case []any:
+		s := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, e := range t {
+			s.Content = append(s.Content, tomlNode(e, key, order))
+		}
+		return s
+
+	case string:
+		return stringNode(t, 0, 0)
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(t, 10)}
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(t, 'g', -1, 64)}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}
+	case time.Time:
+		return stringNode(t.Format(time.RFC3339Nano), 0, 0)
+	}
+	return stringNode(fmt.Sprint(v), 0, 0)
+}
+
+// stringNode is a string value from JSON or TOML. A value holding
+// ${...} is made plain, like an unquoted YAML scalar, so that after
+// interpolation `"interval_ms": "${POLL_MS}"` decodes as a number.
+func stringNode(s string, line, col int) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s, Style: yaml.DoubleQuotedStyle, Line: line, Column: col}
+	if strings.Contains(s, "${") {
+		n.Style = 0
+	}
+	return n
+}
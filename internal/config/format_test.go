@@ -0,0 +1,117 @@
+// internal/config/format_test.go
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad_ExampleFormatsIdentical(t *testing.T) {
+	want, err := Load("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"example.json", "example.toml"} {
+		got, err := Load(f)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: config differs from example.yaml\n got %+v\nwant %+v", f, got.Replicator, want.Replicator)
+		}
+	}
+}
+
+func TestLoad_JSONErrorsCarryPosition(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"bad.json":    "{\n  \"replicator\": {\n    \"unitz\": []\n  }\n}\n",
+		"syntax.json": "{\n  \"replicator\": {,}\n}\n",
+	})
+
+	_, err := Load(filepath.Join(dir, "bad.json"))
+	var pe *PosError
+	if !errors.As(err, &pe) || pe.Line != 3 || pe.Col != 5 {
+		t.Fatalf("unknown key: got %v", err)
+	}
+	if !strings.Contains(err.Error(), `did you mean "units"`) {
+		t.Fatalf("missing suggestion: %v", err)
+	}
+
+	_, err = Load(filepath.Join(dir, "syntax.json"))
+	if !errors.As(err, &pe) || pe.Line != 2 {
+		t.Fatalf("syntax error: got %v", err)
+	}
+}
+
+func TestLoad_JSONOffsetsAndInterpolation(t *testing.T) {
+	t.Setenv("POLL_MS", "2000")
+	dir := writeTree(t, map[string]string{
+		"c.json": `{"replicator": {"units": [{
+  "id": "a",
+  "source": {"endpoint": "10.0.0.1:502", "unit_id": 1},
+  "reads": [{"fc": 3, "address": 0, "quantity": 2}],
+  "targets": [{"id": 1, "endpoint": "127.0.0.1:9000",
+    "memories": [{"memory_id": 0, "offsets": {"3": 100}}]}],
+  "poll": {"interval_ms": "${POLL_MS}"}
+}]}}`,
+	})
+
+	cfg, err := Load(filepath.Join(dir, "c.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := cfg.Replicator.Units[0]
+	if got := u.Targets[0].Memories[0].Offsets[3]; got != 100 {
+		t.Fatalf("offset = %d, want 100", got)
+	}
+	if u.Poll.IntervalMs != 2000 {
+		t.Fatalf("interval = %d, want 2000", u.Poll.IntervalMs)
+	}
+}
+
+func TestLoad_TOMLParseErrorCarriesLine(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"c.toml": "[replicator]\n\nunits = [\n",
+	})
+	_, err := Load(filepath.Join(dir, "c.toml"))
+	var pe *PosError
+	if !errors.As(err, &pe) || pe.Line == 0 {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestLoad_MixedFormatIncludes(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.toml":    `include = ["units/*.yaml", "logging.json"]` + "\n",
+		"units/a.yaml": unitFile("a", 1),
+		"logging.json": `{"replicator": {"logging": {"level": "debug"}}}`,
+	})
+
+	cfg, err := Load(filepath.Join(dir, "main.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Replicator.Units) != 1 || cfg.Replicator.Logging.Level != "debug" {
+		t.Fatalf("got %+v", cfg.Replicator)
+	}
+}
+
+func TestLoadFormat_OverridesExtension(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"replicator.conf": `{"replicator": {"units": []}}`,
+		"broken.conf":     "replicator = {}\n",
+	})
+
+	if _, err := LoadFormat(filepath.Join(dir, "replicator.conf"), FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFormat(filepath.Join(dir, "broken.conf"), FormatJSON); err == nil {
+		t.Fatal("expected JSON parse error")
+	}
+	if _, err := ParseFormat("ini"); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+}
@@ -0,0 +1,265 @@
+// internal/config/generate.go
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// maxGenerateCount bounds one generator entry (a typo should not
+// allocate a million units).
+const maxGenerateCount = 1024
+
+// Expand resolves profiles and generators in place.
+//
+// After Expand every unit is concrete: Generate is nil and every field a
+// profile supplied is filled. Explicit units keep their identities;
+// generated units take the next free unit_id / status_slot around them,
+// using the same allocation as DuplicateUnit.
+//
+// Load calls Expand; it is idempotent.
+func Expand(cfg *Config) error {
+	if cfg == nil {
+		return nil
+	}
+	r := &cfg.Replicator
+
+	// Identities claimed by explicit units come first, wherever they
+	// appear in the list.
+	existingIDs := make(map[string]bool)
+	usedUnitIDs := make(map[uint8]bool)
+	usedStatusSlots := make(map[uint16]bool)
+	for _, u := range r.Units {
+		if u.Generate != nil {
+			continue
+		}
+		existingIDs[u.ID] = true
+		usedUnitIDs[u.Source.UnitID] = true
+		if u.Source.StatusSlot != nil {
+			usedStatusSlots[*u.Source.StatusSlot] = true
+		}
+		if u.Source.DeliverySlot != nil {
+			usedStatusSlots[*u.Source.DeliverySlot] = true
+		}
+	}
+
+	out := make([]UnitConfig, 0, len(r.Units))
+	for _, u := range r.Units {
+		u, err := applyProfile(u, r.Profiles)
+		if err != nil {
+			return err
+		}
+
+		if u.Generate == nil {
+			out = append(out, u)
+			continue
+		}
+
+		units, err := generate(u, existingIDs, usedUnitIDs, usedStatusSlots)
+		if err != nil {
+			return fmt.Errorf("unit %q: generate: %w", u.ID, err)
+		}
+		out = append(out, units...)
+	}
+
+	r.Units = out
+	return nil
+}
+
+// generate expands one template. The claim maps are updated as
+// instances are allocated.
+func generate(
+	tpl UnitConfig,
+	existingIDs map[string]bool,
+	usedUnitIDs map[uint8]bool,
+	usedStatusSlots map[uint16]bool,
+) ([]UnitConfig, error) {
+	g := *tpl.Generate
+	tpl.Generate = nil
+
+	if g.Count < 1 || g.Count > maxGenerateCount {
+		return nil, fmt.Errorf("count must be 1..%d", maxGenerateCount)
+	}
+	if g.Start == 0 {
+		g.Start = 1
+	}
+	switch g.UnitID {
+	case "", "fixed", "increment":
+	default:
+		return nil, fmt.Errorf("unit_id %q not supported (fixed, increment)", g.UnitID)
+	}
+
+	ep, err := parseEndpoint(tpl.Source.Endpoint, g)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]UnitConfig, 0, g.Count)
+
+	var (
+		unitID       = tpl.Source.UnitID
+		statusSlot   *uint16
+		deliverySlot *uint16
+	)
+
+	for i := 0; i < g.Count; i++ {
+		n := g.Start + i
+		u := deepCopyUnit(tpl)
+
+		// ---- id ----
+		u.ID = instanceName(tpl.ID, n, true)
+		if existingIDs[u.ID] {
+			return nil, fmt.Errorf("instance %d: id %q already in use", n, u.ID)
+		}
+		existingIDs[u.ID] = true
+		u.Source.DeviceName = instanceName(tpl.Source.DeviceName, n, false)
+
+		// ---- endpoint ----
+		if ep != nil {
+			e, err := ep.at(i, g)
+			if err != nil {
+				return nil, fmt.Errorf("instance %d: %w", n, err)
+			}
+			u.Source.Endpoint = e
+		}
+
+		// ---- unit_id ----
+		if g.UnitID == "increment" {
+			if i > 0 || usedUnitIDs[unitID] {
+				next, err := nextFreeUnitID(unitID, usedUnitIDs)
+				if err != nil {
+					return nil, fmt.Errorf("instance %d: %w", n, err)
+				}
+				unitID = next
+			}
+			usedUnitIDs[unitID] = true
+		}
+		u.Source.UnitID = unitID
+
+		// ---- status_slot (always unique when set) ----
+		if tpl.Source.StatusSlot != nil {
+			slot := *tpl.Source.StatusSlot
+			if statusSlot != nil || usedStatusSlots[slot] {
+				from := slot
+				if statusSlot != nil {
+					from = *statusSlot
+				}
+				next, err := nextFreeStatusSlot(from, usedStatusSlots)
+				if err != nil {
+					return nil, fmt.Errorf("instance %d: %w", n, err)
+				}
+				slot = next
+			}
+			usedStatusSlots[slot] = true
+			statusSlot = &slot
+			v := slot
+			u.Source.StatusSlot = &v
+		}
+
+		// ---- delivery_slot (same slot space, always unique when set) ----
+		if tpl.Source.DeliverySlot != nil {
+			slot := *tpl.Source.DeliverySlot
+			if deliverySlot != nil || usedStatusSlots[slot] {
+				from := slot
+				if deliverySlot != nil {
+					from = *deliverySlot
+				}
+				next, err := nextFreeStatusSlot(from, usedStatusSlots)
+				if err != nil {
+					return nil, fmt.Errorf("instance %d: %w", n, err)
+				}
+				slot = next
+			}
+			usedStatusSlots[slot] = true
+			deliverySlot = &slot
+			v := slot
+			u.Source.DeliverySlot = &v
+		}
+
+		// ---- target offsets ----
+		if len(g.OffsetStep) > 0 && i > 0 {
+			for ti := range u.Targets {
+				for mi := range u.Targets[ti].Memories {
+					m := &u.Targets[ti].Memories[mi]
+					if m.Offsets == nil {
+						m.Offsets = make(map[int]uint16, len(g.OffsetStep))
+					}
+					for fc, step := range g.OffsetStep {
+						v := uint32(m.Offsets[fc]) + uint32(step)*uint32(i)
+						if v > 0xFFFF {
+							return nil, fmt.Errorf("instance %d: fc %d offset %d exceeds 65535", n, fc, v)
+						}
+						m.Offsets[fc] = uint16(v)
+					}
+				}
+			}
+		}
+
+		out = append(out, u)
+	}
+
+	return out, nil
+}
+
+// instanceName substitutes {n}. Without a placeholder, ids get a "_n"
+// suffix (same shape as DuplicateUnit) and other names stay as they are.
+func instanceName(tpl string, n int, suffix bool) string {
+	if strings.Contains(tpl, "{n}") {
+		return strings.ReplaceAll(tpl, "{n}", strconv.Itoa(n))
+	}
+	if suffix {
+		return fmt.Sprintf("%s_%d", tpl, n)
+	}
+	return tpl
+}
+
+// endpointTemplate is a parsed source.endpoint for stepping.
+type endpointTemplate struct {
+	ip   net.IP // IPv4, nil for hostnames
+	host string
+	port int
+}
+
+// parseEndpoint parses the template endpoint when any step is set.
+// nil means the endpoint is kept verbatim.
+func parseEndpoint(ep string, g GenerateConfig) (*endpointTemplate, error) {
+	if g.IPStep == 0 && g.PortStep == 0 {
+		return nil, nil
+	}
+
+	h, p, err := net.SplitHostPort(ep)
+	if err != nil {
+		return nil, fmt.Errorf("source.endpoint %q: %v", ep, err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return nil, fmt.Errorf("source.endpoint %q: bad port", ep)
+	}
+
+	t := &endpointTemplate{ip: net.ParseIP(h).To4(), host: h, port: port}
+	if t.ip == nil && g.IPStep != 0 {
+		return nil, fmt.Errorf("ip_step needs an IPv4 source.endpoint, got %q", ep)
+	}
+	return t, nil
+}
+
+// at returns the endpoint of instance i (0-based).
+func (t *endpointTemplate) at(i int, g GenerateConfig) (string, error) {
+	h := t.host
+	if t.ip != nil {
+		last := int(t.ip[3]) + g.IPStep*i
+		if last < 1 || last > 254 {
+			return "", fmt.Errorf("ip_step runs past the /24 (last octet %d)", last)
+		}
+		ip := net.IPv4(t.ip[0], t.ip[1], t.ip[2], byte(last))
+		h = ip.String()
+	}
+
+	p := t.port + g.PortStep*i
+	if p < 1 || p > 65535 {
+		return "", fmt.Errorf("port_step gives invalid port %d", p)
+	}
+	return net.JoinHostPort(h, strconv.Itoa(p)), nil
+}
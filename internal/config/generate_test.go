@@ -0,0 +1,223 @@
+// internal/config/generate_test.go
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const generatorYAML = `
+replicator:
+  profiles:
+    inverter:
+      source:
+        timeout_ms: 500
+        device_name: "INV_{n}"
+      reads:
+        - { fc: 3, address: 0, quantity: 50 }
+      targets:
+        - id: 1
+          endpoint: "10.0.0.1:9000"
+          status_unit_id: 100
+          memories:
+            - memory_id: 0
+              offsets: { 3: 1000 }
+      poll:
+        interval_ms: 1000
+
+  units:
+    - id: "meter"
+      source:
+        endpoint: "10.0.1.5:502"
+        unit_id: 2
+        status_slot: 1
+      reads:
+        - { fc: 3, address: 0, quantity: 10 }
+      targets:
+        - id: 1
+          endpoint: "10.0.0.1:9000"
+          status_unit_id: 100
+          memories:
+            - memory_id: 1
+              offsets: {}
+      poll:
+        interval_ms: 1000
+
+    - id: "inv-{n}"
+      profile: inverter
+      source:
+        endpoint: "10.0.1.10:502"
+        unit_id: 1
+        status_slot: 0
+      generate:
+        count: 3
+        ip_step: 1
+        unit_id: increment
+        offset_step: { 3: 50 }
+`
+
+func loadString(t *testing.T, body string) (*Config, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "c.yaml")
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(p)
+}
+
+func TestExpand_ProfileAndGenerator(t *testing.T) {
+	cfg, err := loadString(t, generatorYAML)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expanded config should validate: %v", err)
+	}
+
+	units := cfg.Replicator.Units
+	if len(units) != 4 {
+		t.Fatalf("expected 4 units, got %d", len(units))
+	}
+
+	want := []struct {
+		id       string
+		endpoint string
+		unitID   uint8
+		slot     uint16
+		name     string
+		offset   uint16
+	}{
+		// meter holds unit_id 2 and slot 1, so the generator skips them.
+		{"inv-1", "10.0.1.10:502", 1, 0, "INV_1", 1000},
+		{"inv-2", "10.0.1.11:502", 3, 2, "INV_2", 1050},
+		{"inv-3", "10.0.1.12:502", 4, 3, "INV_3", 1100},
+	}
+	for i, w := range want {
+		u := units[i+1]
+		if u.ID != w.id || u.Source.Endpoint != w.endpoint || u.Source.UnitID != w.unitID ||
+			*u.Source.StatusSlot != w.slot || u.Source.DeviceName != w.name {
+			t.Errorf("unit %d: got id=%s ep=%s uid=%d slot=%d name=%s",
+				i, u.ID, u.Source.Endpoint, u.Source.UnitID, *u.Source.StatusSlot, u.Source.DeviceName)
+		}
+		if got := u.Targets[0].Memories[0].Offsets[3]; got != w.offset {
+			t.Errorf("unit %s: offset %d, want %d", u.ID, got, w.offset)
+		}
+		if u.Generate != nil || u.Source.TimeoutMs != 500 || u.Poll.IntervalMs != 1000 {
+			t.Errorf("unit %s: profile not applied: %+v", u.ID, u)
+		}
+	}
+
+	// Instances never share memory with each other or the profile.
+	units[1].Targets[0].Memories[0].Offsets[3] = 7
+	if units[2].Targets[0].Memories[0].Offsets[3] == 7 ||
+		cfg.Replicator.Profiles["inverter"].Targets[0].Memories[0].Offsets[3] != 1000 {
+		t.Fatal("offsets map shared between instances")
+	}
+
+	// Expand is idempotent.
+	before := len(cfg.Replicator.Units)
+	if err := Expand(cfg); err != nil || len(cfg.Replicator.Units) != before {
+		t.Fatalf("second expand changed the config: %v", err)
+	}
+}
+
+func TestExpand_ProfileDeliverySlot(t *testing.T) {
+	cfg, err := loadString(t, `
+replicator:
+  profiles:
+    meter:
+      source: { timeout_ms: 500, status_slot: 0, delivery_slot: 1 }
+      reads: [ { fc: 3, address: 0, quantity: 10 } ]
+      targets:
+        - { id: 1, endpoint: "10.0.0.1:9000", status_unit_id: 100, memories: [ { memory_id: 0 } ] }
+  units:
+    - id: "m1"
+      profile: meter
+      source: { endpoint: "10.0.1.1:502", unit_id: 1 }
+    - id: "m2"
+      profile: meter
+      source: { endpoint: "10.0.1.2:502", unit_id: 1, status_slot: 2, delivery_slot: 3 }
+      targets:
+        - { id: 2, endpoint: "10.0.0.1:9000", status_unit_id: 100, memories: [ { memory_id: 0 } ] }
+`)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expanded config should validate: %v", err)
+	}
+
+	m1, m2 := cfg.Replicator.Units[0], cfg.Replicator.Units[1]
+	if m1.Source.DeliverySlot == nil || *m1.Source.DeliverySlot != 1 {
+		t.Fatalf("m1 delivery_slot = %v, want 1 from the profile", m1.Source.DeliverySlot)
+	}
+	if m2.Source.DeliverySlot == nil || *m2.Source.DeliverySlot != 3 {
+		t.Fatalf("m2 delivery_slot = %v, want its own 3", m2.Source.DeliverySlot)
+	}
+
+	// The unit holds its own copy, not the profile's pointer.
+	*m1.Source.DeliverySlot = 9
+	if *cfg.Replicator.Profiles["meter"].Source.DeliverySlot != 1 {
+		t.Fatal("delivery_slot shared with the profile")
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	cases := map[string]UnitConfig{
+		"unknown profile": {ID: "a", Profile: "nope"},
+		"zero count":      {ID: "a", Generate: &GenerateConfig{}},
+		"bad unit_id":     {ID: "a", Generate: &GenerateConfig{Count: 1, UnitID: "random"}},
+		"ip overflow": {ID: "a", Source: SourceConfig{Endpoint: "10.0.0.250:502"},
+			Generate: &GenerateConfig{Count: 10, IPStep: 1}},
+		"ip_step on hostname": {ID: "a", Source: SourceConfig{Endpoint: "plc:502"},
+			Generate: &GenerateConfig{Count: 2, IPStep: 1}},
+		"id collision": {ID: "a_{n}", Generate: &GenerateConfig{Count: 2}},
+	}
+	for name, u := range cases {
+		cfg := cfg1(makeUnit("a_2", 9, nil), u)
+		if err := Expand(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestExpand_PortStepOnHostname(t *testing.T) {
+	tpl := UnitConfig{
+		ID:       "gw",
+		Source:   SourceConfig{Endpoint: "gateway.local:5020", UnitID: 7},
+		Generate: &GenerateConfig{Count: 2, PortStep: 1},
+	}
+	cfg := cfg1(tpl)
+	if err := Expand(cfg); err != nil {
+		t.Fatal(err)
+	}
+	got := []string{cfg.Replicator.Units[0].Source.Endpoint, cfg.Replicator.Units[1].Source.Endpoint}
+	if got[0] != "gateway.local:5020" || got[1] != "gateway.local:5021" {
+		t.Fatalf("endpoints: %v", got)
+	}
+	if cfg.Replicator.Units[0].ID != "gw_1" || cfg.Replicator.Units[1].Source.UnitID != 7 {
+		t.Fatalf("unexpected units: %+v", cfg.Replicator.Units)
+	}
+}
+
+// Status and delivery blocks of instances interleave in one slot space.
+func TestExpand_DeliverySlot(t *testing.T) {
+	tpl := makeUnit("m_{n}", 1, ptr(uint16(1)))
+	tpl.Source.DeliverySlot = ptr(uint16(2))
+	tpl.Generate = &GenerateConfig{Count: 3, UnitID: "increment", OffsetStep: map[int]uint16{3: 10}}
+
+	cfg := cfg1(tpl)
+	if err := Expand(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expanded config should validate: %v", err)
+	}
+	for i, want := range [][2]uint16{{1, 2}, {3, 4}, {5, 6}} {
+		src := cfg.Replicator.Units[i].Source
+		if *src.StatusSlot != want[0] || *src.DeliverySlot != want[1] {
+			t.Errorf("instance %d: status_slot=%d delivery_slot=%d, want %v", i, *src.StatusSlot, *src.DeliverySlot, want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// internal/config/include.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PosError is a load error tied to a position in a config file.
+type PosError struct {
+	File string
+	Line int
+	Col  int
+	Err  error
+}
+
+func (e *PosError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Col, e.Err)
+}
+
+func (e *PosError) Unwrap() error { return e.Err }
+
+// includeKey is the document-root key listing files to merge in.
+const includeKey = "include"
+
+// maxIncludeDepth stops runaway nesting independently of cycle checks.
+const maxIncludeDepth = 16
+
+// loader reads a root config file and everything it includes into one
+// merged YAML tree, remembering where every node came from.
+type loader struct {
+	origin   map[*yaml.Node]string
+	secrets  []string
+	warnings []string
+	env      func(string) (string, bool)
+	format   Format
+
+	// content, when set, is the root file's content; it is not read.
+	content []byte
+}
+
+func newLoader(format Format) *loader {
+	return &loader{
+		origin: make(map[*yaml.Node]string),
+		env:    os.LookupEnv,
+		format: format,
+	}
+}
+
+// load reads path and its includes (depth-first, in listed order).
+// The including file's own content comes first; included content is
+// merged after it.
+func (l *loader) load(path string, stack []string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+	if len(stack) >= maxIncludeDepth {
+		return nil, fmt.Errorf("%s: includes nested deeper than %d", path, maxIncludeDepth)
+	}
+	// A forced format applies to the root file; includes go by extension.
+	format := DetectFormat(path)
+	if len(stack) == 0 && l.format != FormatAuto {
+		format = l.format
+	}
+	stack = append(stack, abs)
+
+	var b []byte
+	if len(stack) == 1 && l.content != nil {
+		b = l.content
+	} else if b, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	root, err := parseDocument(path, b, format)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, &PosError{File: path, Line: root.Line, Col: root.Column, Err: errors.New("document root must be a mapping")}
+	}
+
+	l.mark(root, path)
+	if err := l.interpolate(root, path); err != nil {
+		return nil, err
+	}
+
+	includes, err := takeIncludes(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown keys and type errors are reported per file, where line
+	// numbers still mean something; the merged tree has no single file.
+	if err := l.checkKnownFields(root, reflect.TypeOf(Config{}), "", path); err != nil {
+		return nil, err
+	}
+	var probe Config
+	if err := root.Decode(&probe); err != nil {
+		return nil, &PosError{File: path, Err: err}
+	}
+
+	for _, inc := range includes {
+		files, err := l.resolveInclude(path, inc)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			sub, err := l.load(f, stack)
+			if err != nil {
+				return nil, err
+			}
+			if err := l.merge(root, sub); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return root, nil
+}
+
+// includeRef is one entry of an include list.
+type includeRef struct {
+	pattern string
+	node    *yaml.Node
+}
+
+// takeIncludes removes the include key from root and returns its entries.
+// A single string is accepted as a one-entry list.
+func takeIncludes(root *yaml.Node, path string) ([]includeRef, error) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+		if k.Value != includeKey {
+			continue
+		}
+		root.Content = append(root.Content[:i:i], root.Content[i+2:]...)
+
+		var items []*yaml.Node
+		switch v.Kind {
+		case yaml.ScalarNode:
+			items = []*yaml.Node{v}
+		case yaml.SequenceNode:
+			items = v.Content
+		default:
+			return nil, &PosError{File: path, Line: v.Line, Col: v.Column, Err: errors.New("include must be a path or a list of paths")}
+		}
+
+		out := make([]includeRef, 0, len(items))
+		for _, it := range items {
+			if it.Kind != yaml.ScalarNode || it.Value == "" {
+				return nil, &PosError{File: path, Line: it.Line, Col: it.Column, Err: errors.New("include entry must be a non-empty path")}
+			}
+			out = append(out, includeRef{pattern: it.Value, node: it})
+		}
+		return out, nil
+	}
+	return nil, nil
+}
+
+// resolveInclude expands one entry relative to the including file.
+// A glob may match nothing; a plain path must exist.
+func (l *loader) resolveInclude(from string, inc includeRef) ([]string, error) {
+	p := inc.pattern
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(from), p)
+	}
+
+	if !strings.ContainsAny(inc.pattern, "*?[") {
+		if _, err := os.Stat(p); err != nil {
+			return nil, &PosError{File: from, Line: inc.node.Line, Col: inc.node.Column, Err: fmt.Errorf("include %q: %w", inc.pattern, err)}
+		}
+		return []string{p}, nil
+	}
+
+	matches, err := filepath.Glob(p) // sorted
+	if err != nil {
+		return nil, &PosError{File: from, Line: inc.node.Line, Col: inc.node.Column, Err: fmt.Errorf("include %q: %w", inc.pattern, err)}
+	}
+	return matches, nil
+}
+
+// merge folds src into dst:
+//   - mappings merge key by key
+//   - sequences concatenate (units from several files)
+//   - any other overlap is a conflict reported with both positions
+func (l *loader) merge(dst, src *yaml.Node) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		sk, sv := src.Content[i], src.Content[i+1]
+
+		j := findKey(dst, sk.Value)
+		if j < 0 {
+			dst.Content = append(dst.Content, sk, sv)
+			continue
+		}
+		dk, dv := dst.Content[j], dst.Content[j+1]
+
+		switch {
+		case dv.Kind == yaml.MappingNode && sv.Kind == yaml.MappingNode:
+			if err := l.merge(dv, sv); err != nil {
+				return err
+			}
+		case dv.Kind == yaml.SequenceNode && sv.Kind == yaml.SequenceNode:
+			dv.Content = append(dv.Content, sv.Content...)
+		case isNull(dv):
+			dst.Content[j+1] = sv
+		case isNull(sv):
+		default:
+			return &PosError{
+				File: l.origin[sk], Line: sk.Line, Col: sk.Column,
+				Err: fmt.Errorf("%q already set at %s:%d", sk.Value, l.origin[dk], dk.Line),
+			}
+		}
+	}
+	return nil
+}
+
+func findKey(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+// mark records the file every node of a tree came from.
+func (l *loader) mark(n *yaml.Node, file string) {
+	l.origin[n] = file
+	for _, c := range n.Content {
+		l.mark(c, file)
+	}
+}
@@ -0,0 +1,113 @@
+// internal/config/interpolate.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Interpolation, applied to scalar values (never keys) at load time:
+//
+//	${NAME}             environment variable; unset is an error
+//	${NAME:-default}    environment variable, default when unset or empty
+//	${file:path}        file contents, trailing newline trimmed; relative
+//	                    to the config file. Recorded as a secret.
+//	$${                 literal "${"
+func (l *loader) interpolate(n *yaml.Node, file string) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if err := l.interpolate(n.Content[i+1], file); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := l.interpolate(c, file); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			return nil
+		}
+		v, err := l.expand(n.Value, file)
+		if err != nil {
+			return &PosError{File: file, Line: n.Line, Col: n.Column, Err: err}
+		}
+		n.Value = v
+
+		// Let plain scalars re-resolve, so `port: ${PORT}` is an int.
+		if n.Style == 0 {
+			n.Tag = ""
+		}
+	}
+	return nil
+}
+
+func (l *loader) expand(s, file string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", errors.New("unterminated ${")
+		}
+		v, err := l.lookup(s[i+2:i+end], file)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+		s = s[i+end+1:]
+	}
+}
+
+func (l *loader) lookup(expr, file string) (string, error) {
+	if ref, ok := strings.CutPrefix(expr, "file:"); ok {
+		if ref == "" {
+			return "", errors.New("${file:} needs a path")
+		}
+		if !filepath.IsAbs(ref) {
+			ref = filepath.Join(filepath.Dir(file), ref)
+		}
+		b, err := os.ReadFile(ref)
+		if err != nil {
+			return "", err
+		}
+		v := strings.TrimRight(string(b), "\r\n")
+		if v != "" {
+			l.secrets = append(l.secrets, v)
+		}
+		return v, nil
+	}
+
+	name, def, hasDef := strings.Cut(expr, ":-")
+	if name == "" {
+		return "", fmt.Errorf("${%s}: empty variable name", expr)
+	}
+	v, ok := l.env(name)
+	if hasDef && v == "" {
+		return def, nil
+	}
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set (use ${%s:-default})", name, name)
+	}
+	return v, nil
+}
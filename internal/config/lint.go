@@ -0,0 +1,72 @@
+// internal/config/lint.go
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Finding is one advisory lint result.
+// Findings never block a run; Validate owns hard errors.
+type Finding struct {
+	Unit    string `json:"unit,omitempty"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Unit == "" {
+		return fmt.Sprintf("%s: %s", f.Check, f.Message)
+	}
+	return fmt.Sprintf("unit %q: %s: %s", f.Unit, f.Check, f.Message)
+}
+
+// minSaneIntervalMs is the poll interval below which lint warns.
+const minSaneIntervalMs = 100
+
+// Lint runs advisory checks on a configuration that already passed
+// Validate, plus the warnings Load recorded. Results are sorted by unit,
+// then check.
+func Lint(cfg *Config) []Finding {
+	var out []Finding
+	add := func(unit, check, format string, args ...any) {
+		out = append(out, Finding{Unit: unit, Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, u := range cfg.Replicator.Units {
+		if u.Poll.IntervalMs > 0 && u.Poll.IntervalMs < minSaneIntervalMs {
+			add(u.ID, "poll-interval", "interval_ms %d is below %d; most field devices cannot keep up",
+				u.Poll.IntervalMs, minSaneIntervalMs)
+		}
+		if len(u.Targets) == 0 {
+			add(u.ID, "no-targets", "unit has no targets; polled data goes nowhere")
+		}
+		if u.Source.StatusSlot != nil && u.Source.DeviceName == "" {
+			add(u.ID, "device-name", "status_slot is set but device_name is empty")
+		}
+
+		for i, r := range u.Reads {
+			for j := 0; j < i; j++ {
+				p := u.Reads[j]
+				if p.FC == r.FC &&
+					uint32(r.Address) < uint32(p.Address)+uint32(p.Quantity) &&
+					uint32(p.Address) < uint32(r.Address)+uint32(r.Quantity) {
+					add(u.ID, "read-overlap", "reads[%d] and reads[%d] overlap on fc=%d", j, i, r.FC)
+				}
+			}
+		}
+	}
+
+	// Load warnings (deprecated keys, normalized values) come first.
+	for _, w := range cfg.warnings {
+		add("", "load", "%s", w)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Unit != out[j].Unit {
+			return out[i].Unit < out[j].Unit
+		}
+		return out[i].Check < out[j].Check
+	})
+	return out
+}
@@ -0,0 +1,48 @@
+// internal/config/lint_test.go
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint_CleanUnitHasNoFindings(t *testing.T) {
+	u := makeUnit("dev", 1, ptr(uint16(0)))
+	u.Source.TimeoutMs = 500
+
+	cfg := &Config{Replicator: ReplicatorConfig{Units: []UnitConfig{u}}}
+	if f := Lint(cfg); len(f) != 0 {
+		t.Fatalf("unexpected findings: %v", f)
+	}
+}
+
+func TestLint_Findings(t *testing.T) {
+	u := makeUnit("dev", 1, ptr(uint16(0)))
+	u.Source.DeviceName = ""
+	u.Reads = []ReadConfig{
+		{FC: 3, Address: 0, Quantity: 120},
+		{FC: 3, Address: 100, Quantity: 10},
+		{FC: 4, Address: 100, Quantity: 10},
+	}
+
+	slow := makeUnit("slow", 2, nil)
+	slow.Source.TimeoutMs = 500
+	slow.Poll.IntervalMs = 50
+	slow.Targets = nil
+
+	cfg := &Config{Replicator: ReplicatorConfig{Units: []UnitConfig{slow, u}}}
+
+	var got []string
+	for _, f := range Lint(cfg) {
+		got = append(got, f.Unit+"/"+f.Check)
+	}
+	want := []string{
+		"dev/device-name",
+		"dev/read-overlap",
+		"slow/no-targets",
+		"slow/poll-interval",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("findings:\n got %v\nwant %v", got, want)
+	}
+}
@@ -2,21 +2,112 @@
 package config
 
 import (
-	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Load is the single config pipeline:
+//
+//  1. read path and its includes, interpolate ${...} values
+//  2. reject unknown keys (deprecated ones only warn)
+//  3. decode, expand profiles and generators
+//  4. ApplyDefaults
+//  5. Validate
+//  6. Normalize
+//
+// What Load returns is exactly what runs. Errors from the file stages
+// carry file:line (see PosError). The format follows the file extension
+// (.yaml/.yml, .json, .toml); use LoadFormat to force one.
 func Load(path string) (*Config, error) {
-	b, err := os.ReadFile(path)
+	return LoadFormat(path, FormatAuto)
+}
+
+// LoadFormat is Load with the root file parsed as f. Included files are
+// always detected by extension.
+func LoadFormat(path string, f Format) (*Config, error) {
+	return loadWith(newLoader(f), path)
+}
+
+// LoadBytes is LoadFormat for content b not (yet) stored at path.
+// Includes resolve against path as if b were there.
+func LoadBytes(path string, b []byte, f Format) (*Config, error) {
+	l := newLoader(f)
+	l.content = b
+	return loadWith(l, path)
+}
+
+func loadWith(l *loader, path string) (*Config, error) {
+	root, err := l.load(path, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(b, &cfg); err != nil {
+	if err := root.Decode(&cfg); err != nil {
 		return nil, err
 	}
+	cfg.secrets = l.secrets
+	cfg.warnings = l.warnings
+
+	// Profiles and generators are resolved here so every later stage
+	// (Validate, the supervisor, the CLI) only ever sees concrete units.
+	if err := Expand(&cfg); err != nil {
+		return nil, err
+	}
+
+	ApplyDefaults(&cfg)
+
+	if err := Validate(&cfg); err != nil {
+		return nil, err
+	}
+	Normalize(&cfg)
 
 	return &cfg, nil
 }
+
+// redactedValue replaces secret material in Redacted output.
+const redactedValue = "<redacted>"
+
+// Redacted returns a copy of cfg with every value read through
+// ${file:...} masked, and credential fields masked however they were
+// supplied. Use it for anything shown to people.
+func (c *Config) Redacted() (*Config, error) {
+	if len(c.secrets) == 0 {
+		cp := *c
+		redactCredentials(&cp)
+		return &cp, nil
+	}
+
+	var n yaml.Node
+	if err := n.Encode(c); err != nil {
+		return nil, err
+	}
+	redactNode(&n, c.secrets)
+
+	var out Config
+	if err := n.Decode(&out); err != nil {
+		return nil, err
+	}
+	out.warnings = c.warnings
+	redactCredentials(&out)
+	return &out, nil
+}
+
+// redactCredentials masks the fields that hold credentials, inline or not.
+func redactCredentials(c *Config) {
+	if c.Replicator.MQTT.Password != "" {
+		c.Replicator.MQTT.Password = redactedValue
+	}
+}
+
+func redactNode(n *yaml.Node, secrets []string) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		for _, s := range secrets {
+			n.Value = strings.ReplaceAll(n.Value, s, redactedValue)
+		}
+	}
+	for _, c := range n.Content {
+		redactNode(c, secrets)
+	}
+}
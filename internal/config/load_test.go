@@ -0,0 +1,275 @@
+// internal/config/load_test.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree writes files (relative path → body) under a temp dir and
+// returns the dir.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const unitYAML = `
+replicator:
+  units:
+    - id: "%s"
+      source: { endpoint: "10.0.0.%d:502", unit_id: 1, timeout_ms: 500 }
+      reads: [ { fc: 3, address: 0, quantity: 1 } ]
+      targets:
+        - id: %d
+          endpoint: "127.0.0.1:9000"
+          memories: [ { memory_id: %d, offsets: {} } ]
+      poll: { interval_ms: 1000 }
+`
+
+func unitFile(id string, n int) string {
+	return fmt.Sprintf(unitYAML, id, n, n, n)
+}
+
+func TestLoad_IncludeGlobMergesUnits(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.yaml": `
+include:
+  - areas/*.yaml
+  - shared.yaml
+replicator:
+  http: { listen: "127.0.0.1:8080" }
+`,
+		"areas/a.yaml": unitFile("a", 1),
+		"areas/b.yaml": unitFile("b", 2),
+		"shared.yaml": `
+replicator:
+  logging: { level: debug }
+`,
+	})
+
+	cfg, err := Load(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	r := cfg.Replicator
+	if len(r.Units) != 2 || r.Units[0].ID != "a" || r.Units[1].ID != "b" {
+		t.Fatalf("units: %+v", r.Units)
+	}
+	if r.HTTP.Listen != "127.0.0.1:8080" || r.Logging.Level != "debug" {
+		t.Fatalf("sections not merged: %+v", r)
+	}
+}
+
+func TestLoad_IncludeErrorsCarryPosition(t *testing.T) {
+	cases := map[string]struct {
+		files map[string]string
+		want  string
+	}{
+		"missing file": {
+			files: map[string]string{"main.yaml": "include:\n  - nope.yaml\n"},
+			want:  "main.yaml:2:5: include \"nope.yaml\"",
+		},
+		"conflict": {
+			files: map[string]string{
+				"main.yaml": "include: [b.yaml]\nreplicator:\n  http: { listen: \":1\" }\n",
+				"b.yaml":    "replicator:\n  http:\n    listen: \":2\"\n",
+			},
+			want: "b.yaml:3:5: \"listen\" already set at",
+		},
+		"type error in include": {
+			files: map[string]string{
+				"main.yaml": "include: b.yaml\n",
+				"b.yaml":    "replicator:\n  shutdown:\n    timeout_ms: soon\n",
+			},
+			want: "b.yaml: yaml: unmarshal errors:\n  line 3",
+		},
+		"cycle": {
+			files: map[string]string{
+				"main.yaml": "include: b.yaml\n",
+				"b.yaml":    "include: main.yaml\n",
+			},
+			want: "include cycle",
+		},
+		"unset env": {
+			files: map[string]string{"main.yaml": "replicator:\n  http:\n    listen: ${REPLICATOR_TEST_UNSET}\n"},
+			want:  "main.yaml:3:13: environment variable REPLICATOR_TEST_UNSET is not set",
+		},
+	}
+
+	for name, c := range cases {
+		dir := writeTree(t, c.files)
+		_, err := Load(filepath.Join(dir, "main.yaml"))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want %q", name, err, c.want)
+		}
+	}
+}
+
+func TestLoad_InterpolationAndSecrets(t *testing.T) {
+	t.Setenv("REPLICATOR_TEST_PORT", "8081")
+	t.Setenv("REPLICATOR_TEST_EMPTY", "")
+
+	dir := writeTree(t, map[string]string{
+		"main.yaml": "include: b.yaml\n" + unitFile("a", 1) + `
+  http:
+    listen: "127.0.0.1:${REPLICATOR_TEST_PORT}"
+  shutdown:
+    timeout_ms: ${REPLICATOR_TEST_TIMEOUT:-1500}
+    status: ${REPLICATOR_TEST_EMPTY:-stale}
+`,
+		"b.yaml": `
+replicator:
+  units:
+    - id: "$${literal}"
+      source: { endpoint: "10.0.0.9:502", device_name: "${file:secrets/name}" }
+      targets: [ { id: 9, endpoint: "127.0.0.1:9000", memories: [ { memory_id: 9 } ] } ]
+      reads: [ { fc: 3, address: 0, quantity: 1 } ]
+`,
+		"secrets/name": "S3CR3T\n",
+	})
+	cfg, err := Load(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := cfg.Replicator
+	if r.HTTP.Listen != "127.0.0.1:8081" || r.Shutdown.TimeoutMs != 1500 || r.Shutdown.Status != "stale" {
+		t.Fatalf("interpolation: %+v %+v", r.HTTP, r.Shutdown)
+	}
+	b := r.Units[1]
+	if b.ID != "${literal}" || b.Source.DeviceName != "S3CR3T" {
+		t.Fatalf("values: id=%q name=%q", b.ID, b.Source.DeviceName)
+	}
+
+	red, err := cfg.Redacted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if red.Replicator.Units[1].Source.DeviceName != redactedValue {
+		t.Fatalf("secret not redacted: %q", red.Replicator.Units[1].Source.DeviceName)
+	}
+	if red.Replicator.Shutdown.TimeoutMs != 1500 || b.Source.DeviceName != "S3CR3T" {
+		t.Fatal("redaction changed other values or the original")
+	}
+}
+
+func TestRedacted_MasksInlinePassword(t *testing.T) {
+	cfg := &Config{Replicator: ReplicatorConfig{MQTT: MQTTConfig{
+		Broker:   "127.0.0.1:1883",
+		Username: "replicator",
+		Password: "hunter2",
+	}}}
+
+	red, err := cfg.Redacted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if red.Replicator.MQTT.Password != redactedValue {
+		t.Fatalf("inline password not redacted: %q", red.Replicator.MQTT.Password)
+	}
+	if red.Replicator.MQTT.Username != "replicator" || cfg.Replicator.MQTT.Password != "hunter2" {
+		t.Fatal("redaction changed other values or the original")
+	}
+}
+
+func TestPosError_Unwrap(t *testing.T) {
+	base := errors.New("boom")
+	err := error(&PosError{File: "a.yaml", Line: 3, Col: 1, Err: base})
+	if !errors.Is(err, base) || err.Error() != "a.yaml:3:1: boom" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestLoad_UnknownKeysRejected(t *testing.T) {
+	cases := map[string]struct {
+		body string
+		want string
+	}{
+		"typo with suggestion": {
+			body: strings.Replace(unitFile("a", 1), "interval_ms", "intervall_ms", 1),
+			want: `main.yaml:11:15: unknown key "intervall_ms" in replicator.units[0].poll (did you mean "interval_ms"?)`,
+		},
+		"case mismatch": {
+			body: "replicator:\n  HTTP: { listen: \":1\" }\n",
+			want: `unknown key "HTTP" in replicator (did you mean "http"?)`,
+		},
+		"root": {
+			body: "replicater: {}\n",
+			want: `main.yaml:1:1: unknown key "replicater" in document root (did you mean "replicator"?)`,
+		},
+		"no suggestion": {
+			body: "replicator:\n  units: []\n  frobnicate: 1\n",
+			want: `unknown key "frobnicate" in replicator`,
+		},
+	}
+	for name, c := range cases {
+		dir := writeTree(t, map[string]string{"main.yaml": c.body})
+		_, err := Load(filepath.Join(dir, "main.yaml"))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v\nwant %q", name, err, c.want)
+		}
+	}
+}
+
+func TestLoad_DefaultsNormalizeAndWarnings(t *testing.T) {
+	body := strings.NewReplacer(
+		"timeout_ms: 500", "device_name: \"A_VERY_LONG_DEVICE_NAME\", status_slot: 0",
+		"poll: { interval_ms: 1000 }", "",
+		"endpoint: \"127.0.0.1:9000\"", "endpoint: \"127.0.0.1:9000\"\n          status_unit_id: 100",
+	).Replace(unitFile("a", 1))
+	body = strings.Replace(body, "replicator:\n", "replicator:\n  Status_Memory:\n    endpoint: \"127.0.0.1:11502\"\n", 1)
+
+	dir := writeTree(t, map[string]string{"main.yaml": body})
+	cfg, err := Load(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := cfg.Replicator.Units[0]
+	// Timeout default is capped at the (default) poll interval.
+	if u.Source.TimeoutMs != DefaultPollIntervalMs || u.Poll.IntervalMs != DefaultPollIntervalMs {
+		t.Fatalf("unit defaults: timeout=%d interval=%d", u.Source.TimeoutMs, u.Poll.IntervalMs)
+	}
+	r := cfg.Replicator
+	if r.Shutdown.TimeoutMs != DefaultShutdownTimeoutMs || r.Shutdown.Status != DefaultShutdownStatus ||
+		r.Logging.Level != DefaultLogLevel || r.Logging.Format != DefaultLogFormat ||
+		r.Logging.SuppressWindowMs != DefaultSuppressWindowMs || r.Reload.WatchIntervalMs != 0 {
+		t.Fatalf("global defaults: %+v", r)
+	}
+	if u.Source.DeviceName != "A_VERY_LONG_DEVI" {
+		t.Fatalf("device_name not normalized: %q", u.Source.DeviceName)
+	}
+
+	w := cfg.Warnings()
+	if len(w) != 2 ||
+		!strings.Contains(w[0], "main.yaml:3: replicator.Status_Memory is deprecated") ||
+		!strings.Contains(w[1], "truncated to 16 characters") {
+		t.Fatalf("warnings: %q", w)
+	}
+
+	var checks []string
+	for _, f := range Lint(cfg) {
+		checks = append(checks, f.Check)
+	}
+	if strings.Join(checks, ",") != "load,load" {
+		t.Fatalf("lint should surface load warnings: %v", checks)
+	}
+}
@@ -0,0 +1,258 @@
+// internal/config/migrate.go
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config layout this build reads.
+//
+//	1  global replicator.Status_Memory (before 2026-03-03)
+//	2  per-target targets[].status_unit_id
+const CurrentVersion = 2
+
+// migration rewrites a layout from version from to from+1, in place.
+// It returns notes for the person running it.
+type migration struct {
+	from  int
+	apply func(root *yaml.Node) ([]string, error)
+}
+
+var migrations = []migration{
+	{from: 1, apply: migrateStatusMemory},
+}
+
+// MigrateResult is the outcome of Migrate.
+type MigrateResult struct {
+	From  int
+	To    int
+	Notes []string
+	Out   []byte // migrated YAML; comments are kept
+}
+
+// Migrate rewrites one YAML config file to CurrentVersion.
+//
+// The version is read from the root `version` key. A file without it is
+// version 1 if it still has replicator.Status_Memory, current otherwise.
+// Migrate works on the file as written: includes are neither followed
+// nor migrated, and ${...} values are left alone.
+func Migrate(b []byte) (MigrateResult, error) {
+	// yaml.v3 keeps the \r of CRLF files in comments; output is LF.
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return MigrateResult{}, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return MigrateResult{}, errors.New("document root must be a mapping")
+	}
+	root := doc.Content[0]
+
+	from, err := layoutVersion(root)
+	if err != nil {
+		return MigrateResult{}, err
+	}
+	res := MigrateResult{From: from, To: CurrentVersion}
+	if from > CurrentVersion {
+		return res, fmt.Errorf("version %d is newer than this build (%d)", from, CurrentVersion)
+	}
+
+	for _, m := range migrations {
+		if m.from < from {
+			continue
+		}
+		notes, err := m.apply(root)
+		if err != nil {
+			return res, fmt.Errorf("migrate %d -> %d: %w", m.from, m.from+1, err)
+		}
+		res.Notes = append(res.Notes, notes...)
+	}
+	if findKey(root, includeKey) >= 0 {
+		res.Notes = append(res.Notes, "included files are not migrated; run migrate on each of them")
+	}
+
+	setVersion(root, CurrentVersion)
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return res, err
+	}
+	_ = enc.Close()
+	res.Out = out.Bytes()
+	return res, nil
+}
+
+func layoutVersion(root *yaml.Node) (int, error) {
+	if i := findKey(root, "version"); i >= 0 {
+		v := root.Content[i+1]
+		n, err := strconv.Atoi(v.Value)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("line %d: version %q is not a layout version", v.Line, v.Value)
+		}
+		return n, nil
+	}
+	if r := mapValue(root, "replicator"); r != nil && mapValue(r, "Status_Memory") != nil {
+		return 1, nil
+	}
+	return CurrentVersion, nil
+}
+
+// setVersion writes `version: v` as the first root key.
+func setVersion(root *yaml.Node, v int) {
+	val := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(v)}
+	if i := findKey(root, "version"); i >= 0 {
+		root.Content[i+1] = val
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	root.Content = append([]*yaml.Node{key, val}, root.Content...)
+}
+
+// migrateStatusMemory (1 -> 2) drops the global Status_Memory and gives
+// every target of a status-enabled unit a status_unit_id.
+//
+// The id is Status_Memory.unit_id when the old file had one. Otherwise it
+// is, per target endpoint, the lowest id from 1 that no target id on that
+// endpoint uses: data is written to the target id (memory_id is never
+// sent), so the status block cannot land on replicated data.
+func migrateStatusMemory(root *yaml.Node) ([]string, error) {
+	rep := mapValue(root, "replicator")
+	if rep == nil {
+		return nil, nil
+	}
+
+	var notes []string
+	var oldEndpoint string
+	var fixedID *int
+
+	if i := findKey(rep, "Status_Memory"); i >= 0 {
+		sm := rep.Content[i+1]
+		if ep := mapValue(sm, "endpoint"); ep != nil {
+			oldEndpoint = ep.Value
+		}
+		if id := mapValue(sm, "unit_id"); id != nil {
+			n, err := strconv.Atoi(id.Value)
+			if err != nil || n < 0 || n > 255 {
+				return nil, fmt.Errorf("Status_Memory.unit_id %q is not a unit id", id.Value)
+			}
+			fixedID = &n
+		}
+		rep.Content = append(rep.Content[:i:i], rep.Content[i+2:]...)
+		notes = append(notes, fmt.Sprintf("removed replicator.Status_Memory (endpoint %s)", oldEndpoint))
+	}
+
+	units := mapValue(rep, "units")
+	if units == nil || units.Kind != yaml.SequenceNode {
+		return notes, nil
+	}
+
+	// Raw Ingest unit ids carrying data, per target endpoint
+	used := make(map[string]map[int]bool)
+	for _, u := range units.Content {
+		for _, t := range seqValue(u, "targets") {
+			ep := scalarValue(t, "endpoint")
+			if used[ep] == nil {
+				used[ep] = make(map[int]bool)
+			}
+			if n, err := strconv.Atoi(scalarValue(t, "id")); err == nil {
+				used[ep][n] = true
+			}
+		}
+	}
+	chosen := make(map[string]int)
+	statusID := func(ep string) int {
+		if fixedID != nil {
+			return *fixedID
+		}
+		if id, ok := chosen[ep]; ok {
+			return id
+		}
+		id := 1
+		for id < 255 && used[ep][id] {
+			id++
+		}
+		chosen[ep] = id
+		return id
+	}
+
+	for ui, u := range units.Content {
+		src := mapValue(u, "source")
+		if src == nil || mapValue(src, "status_slot") == nil || isNull(mapValue(src, "status_slot")) {
+			continue
+		}
+		name := scalarValue(u, "id")
+		if name == "" {
+			name = fmt.Sprintf("units[%d]", ui)
+		}
+
+		targets := seqValue(u, "targets")
+		if len(targets) == 0 {
+			notes = append(notes, fmt.Sprintf("unit %s: status_slot set but no targets; status has nowhere to go", name))
+			continue
+		}
+
+		reachesOld := false
+		for ti, t := range targets {
+			ep := scalarValue(t, "endpoint")
+			reachesOld = reachesOld || ep == oldEndpoint
+			if mapValue(t, "status_unit_id") != nil {
+				continue
+			}
+			id := statusID(ep)
+			insertAfter(t, "status_unit_id", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(id)}, "unit_id", "endpoint")
+			notes = append(notes, fmt.Sprintf("unit %s: targets[%d] (%s) status_unit_id: %d", name, ti, ep, id))
+		}
+		if oldEndpoint != "" && !reachesOld {
+			notes = append(notes, fmt.Sprintf("unit %s: status was written to %s and now goes to its targets; point status readers there", name, oldEndpoint))
+		}
+	}
+	return notes, nil
+}
+
+// ---- node helpers ----
+
+func mapValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	if i := findKey(m, key); i >= 0 {
+		return m.Content[i+1]
+	}
+	return nil
+}
+
+func scalarValue(m *yaml.Node, key string) string {
+	if v := mapValue(m, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+func seqValue(m *yaml.Node, key string) []*yaml.Node {
+	if v := mapValue(m, key); v != nil && v.Kind == yaml.SequenceNode {
+		return v.Content
+	}
+	return nil
+}
+
+// insertAfter adds key: val after the first of the after keys present
+// in m, or at the end.
+func insertAfter(m *yaml.Node, key string, val *yaml.Node, after ...string) {
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	at := len(m.Content)
+	for _, a := range after {
+		if i := findKey(m, a); i >= 0 {
+			at = i + 2
+			break
+		}
+	}
+	m.Content = append(m.Content[:at], append([]*yaml.Node{k, val}, m.Content[at:]...)...)
+}
@@ -0,0 +1,118 @@
+// internal/config/migrate_test.go
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func migrateFile(t *testing.T, path string) MigrateResult {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Migrate(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func loadBytes(t *testing.T, b []byte) *Config {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "migrated.yaml")
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("migrated config does not load: %v\n%s", err, b)
+	}
+	return cfg
+}
+
+func TestMigrate_StatusMemoryToPerTarget(t *testing.T) {
+	res := migrateFile(t, "testdata/migrate/v1.yaml")
+	if res.From != 1 || res.To != CurrentVersion {
+		t.Fatalf("from %d to %d", res.From, res.To)
+	}
+	if bytes.Contains(res.Out, []byte("Status_Memory")) {
+		t.Fatalf("Status_Memory kept:\n%s", res.Out)
+	}
+	if !bytes.Contains(res.Out, []byte("STATUS-ENABLED UNIT")) {
+		t.Fatalf("comments lost:\n%s", res.Out)
+	}
+
+	cfg := loadBytes(t, res.Out)
+	if len(cfg.Warnings()) != 0 {
+		t.Fatalf("warnings: %v", cfg.Warnings())
+	}
+	// target ids 1 and 2 carry data on 127.0.0.1:1502
+	if id := cfg.Replicator.Units[0].Targets[0].StatusUnitID; id == nil || *id != 3 {
+		t.Fatalf("device-1 status_unit_id = %v, want 3", id)
+	}
+	if cfg.Replicator.Units[1].Targets[0].StatusUnitID != nil {
+		t.Fatal("status-disabled unit got a status_unit_id")
+	}
+
+	var moved bool
+	for _, n := range res.Notes {
+		moved = moved || strings.Contains(n, "status was written to 127.0.0.1:11502")
+	}
+	if !moved {
+		t.Fatalf("no note about the old status endpoint: %v", res.Notes)
+	}
+}
+
+func TestMigrate_KeepsStatusMemoryUnitID(t *testing.T) {
+	res, err := Migrate([]byte(`
+replicator:
+  Status_Memory: { endpoint: "127.0.0.1:9000", unit_id: 35 }
+  units:
+    - id: a
+      source: { endpoint: "10.0.0.1:502", unit_id: 1, status_slot: 0 }
+      reads: [ { fc: 3, address: 0, quantity: 1 } ]
+      targets: [ { id: 1, endpoint: "127.0.0.1:9000", memories: [ { memory_id: 0 } ] } ]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loadBytes(t, res.Out)
+	if id := cfg.Replicator.Units[0].Targets[0].StatusUnitID; id == nil || *id != 35 {
+		t.Fatalf("status_unit_id = %v, want 35", id)
+	}
+}
+
+func TestMigrate_CurrentIsStampedAndIdempotent(t *testing.T) {
+	res := migrateFile(t, "example.yaml")
+	if res.From != CurrentVersion || len(res.Notes) != 0 {
+		t.Fatalf("from %d, notes %v", res.From, res.Notes)
+	}
+	if !bytes.HasPrefix(res.Out, []byte("version: 2\n")) {
+		t.Fatalf("version not stamped:\n%s", res.Out)
+	}
+
+	again, err := Migrate(res.Out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Out, res.Out) {
+		t.Fatalf("second migrate changed output:\n%s", again.Out)
+	}
+}
+
+func TestLoad_OldVersionNeedsMigrate(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.yaml": "version: 1\n" + unitFile("a", 1),
+	})
+	_, err := Load(filepath.Join(dir, "main.yaml"))
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || verrs[0].Path != "version" || !strings.Contains(verrs[0].Msg, "replicator migrate") {
+		t.Fatalf("got %v", err)
+	}
+}
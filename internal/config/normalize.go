@@ -1,9 +1,11 @@
 // internal/config/normalize.go
 package config
 
+import "fmt"
+
 // Normalize applies post-validation normalization.
 // It is allowed to mutate configuration.
-// It MUST be called only after Validate().
+// It MUST be called only after Validate(); Load does both.
 func Normalize(cfg *Config) {
 	if cfg == nil {
 		return
@@ -25,6 +27,10 @@ func Normalize(cfg *Config) {
 		// - ASCII already validated
 		// - Truncate to max 16 characters
 		if len(u.Source.DeviceName) > 16 {
+			cfg.warnings = append(cfg.warnings, fmt.Sprintf(
+				"unit %q: device_name %q truncated to 16 characters",
+				u.ID, u.Source.DeviceName,
+			))
 			u.Source.DeviceName = u.Source.DeviceName[:16]
 		}
 
@@ -0,0 +1,63 @@
+// internal/config/profile.go
+package config
+
+import "fmt"
+
+// applyProfile fills the unset fields of u from its profile.
+//
+// Merge rules are shallow and explicit:
+//   - source: each scalar field the unit leaves at zero is inherited
+//     (unit_id 0 is broadcast, never a real device, so 0 means unset)
+//   - reads, targets: the unit's list replaces the profile's when non-empty
+//   - poll: interval_ms inherited when 0
+//
+// Profile slices are deep-copied; no two units share memory.
+func applyProfile(u UnitConfig, profiles map[string]ProfileConfig) (UnitConfig, error) {
+	if u.Profile == "" {
+		return u, nil
+	}
+	p, ok := profiles[u.Profile]
+	if !ok {
+		return UnitConfig{}, fmt.Errorf("unit %q: profile %q not defined", u.ID, u.Profile)
+	}
+
+	base := deepCopyUnit(UnitConfig{
+		Source:  p.Source,
+		Reads:   p.Reads,
+		Targets: p.Targets,
+		Poll:    p.Poll,
+	})
+
+	if u.Source.Endpoint == "" {
+		u.Source.Endpoint = base.Source.Endpoint
+	}
+	if u.Source.UnitID == 0 {
+		u.Source.UnitID = base.Source.UnitID
+	}
+	if u.Source.TimeoutMs == 0 {
+		u.Source.TimeoutMs = base.Source.TimeoutMs
+	}
+	if u.Source.StatusSlot == nil && base.Source.StatusSlot != nil {
+		v := *base.Source.StatusSlot
+		u.Source.StatusSlot = &v
+	}
+	if u.Source.DeliverySlot == nil && base.Source.DeliverySlot != nil {
+		v := *base.Source.DeliverySlot
+		u.Source.DeliverySlot = &v
+	}
+	if u.Source.DeviceName == "" {
+		u.Source.DeviceName = base.Source.DeviceName
+	}
+
+	if len(u.Reads) == 0 {
+		u.Reads = base.Reads
+	}
+	if len(u.Targets) == 0 {
+		u.Targets = base.Targets
+	}
+	if u.Poll.IntervalMs == 0 {
+		u.Poll.IntervalMs = base.Poll.IntervalMs
+	}
+
+	return u, nil
+}
@@ -0,0 +1,289 @@
+// internal/config/schema.go
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fieldDoc annotates one config field in the JSON Schema.
+// Keyed by "StructName.FieldName"; schema_test.go fails when a field
+// has no entry, so the schema cannot drift from the structs.
+type fieldDoc struct {
+	desc     string
+	enum     []any
+	min, max *int64
+}
+
+func rng(lo, hi int64) (*int64, *int64) { return &lo, &hi }
+
+func doc(desc string) fieldDoc { return fieldDoc{desc: desc} }
+
+func docEnum(desc string, values ...any) fieldDoc { return fieldDoc{desc: desc, enum: values} }
+
+func docRange(desc string, lo, hi int64) fieldDoc {
+	d := fieldDoc{desc: desc}
+	d.min, d.max = rng(lo, hi)
+	return d
+}
+
+func docMin(desc string, lo int64) fieldDoc {
+	return fieldDoc{desc: desc, min: &lo}
+}
+
+var fieldDocs = map[string]fieldDoc{
+	"Config.Version":    docEnum("Config layout version (omit => current). Older files: `replicator migrate`.", CurrentVersion),
+	"Config.Replicator": doc("Replicator configuration."),
+
+	"ReplicatorConfig.Profiles":  doc("Reusable unit templates, referenced by units[].profile."),
+	"ReplicatorConfig.Units":     doc("Polled devices. Each unit is one source and its targets."),
+	"ReplicatorConfig.Shutdown":  doc("What happens on SIGINT/SIGTERM."),
+	"ReplicatorConfig.Reload":    doc("Hot configuration reload. SIGHUP always reloads."),
+	"ReplicatorConfig.HTTP":      doc("Embedded read-only management API."),
+	"ReplicatorConfig.Logging":   doc("Structured process logging."),
+	"ReplicatorConfig.Historian": doc("Local trend files of every unit's polled values."),
+	"ReplicatorConfig.MQTT":      doc("Publish every unit's values and health to an MQTT broker."),
+	"ReplicatorConfig.Events":    doc("Change-of-value event stream."),
+
+	"ShutdownConfig.TimeoutMs": docMin("How long in-flight writes may drain, in ms (0 => 5000).", 0),
+	"ShutdownConfig.Status":    docEnum("Health asserted to every status target on exit (empty => disabled).", "", "disabled", "unknown", "stale"),
+
+	"ReloadConfig.WatchIntervalMs": docMin("Poll the config file for changes every N ms (0 => off).", 0),
+
+	"HTTPConfig.Listen": doc("host:port to serve the API on (empty => off)."),
+
+	"LoggingConfig.Level":            docEnum("Global minimum level (empty => info).", "", "debug", "info", "warn", "warning", "error"),
+	"LoggingConfig.Format":           docEnum("Output format, fixed at startup (empty => text).", "", "text", "json"),
+	"LoggingConfig.Units":            doc("Per-unit level overrides, keyed by unit id."),
+	"LoggingConfig.SuppressWindowMs": docMin("Window for collapsing identical warnings/errors, in ms (0 => 60000).", 0),
+
+	"HistorianConfig.Dir":           doc("Directory for per-unit trend files (empty => off). Fixed at startup."),
+	"HistorianConfig.Rotate":        docEnum("Start a new file every day or hour, UTC (empty => daily).", "", "daily", "hourly"),
+	"HistorianConfig.RetentionDays": docMin("Delete files older than N days (0 => keep forever).", 0),
+
+	"MQTTConfig.Broker":      doc("host:port of an MQTT 3.1.1 broker (empty => off). Fixed at startup."),
+	"MQTTConfig.ClientID":    doc("MQTT client id (empty => modbus-replicator)."),
+	"MQTTConfig.Username":    doc("Broker user name (empty => anonymous)."),
+	"MQTTConfig.Password":    doc("Broker password; ${file:...} keeps it out of the file and out of resolve output."),
+	"MQTTConfig.QoS":         docRange("Publish QoS. QoS 1 messages are resent after a reconnect until acknowledged.", 0, 1),
+	"MQTTConfig.Retain":      doc("Retain json data messages (state and status are always retained)."),
+	"MQTTConfig.KeepAliveMs": docMin("MQTT keep-alive, in ms (0 => 30000).", 0),
+	"MQTTConfig.TimeoutMs":   docMin("Bound on connect and on every acknowledgement, in ms (0 => 5000).", 0),
+	"MQTTConfig.ReconnectMs": docMin("Minimum time between connect attempts, in ms (0 => 5000).", 0),
+	"MQTTConfig.QueueSize":   docMin("Messages held while the broker is unreachable; oldest dropped first (0 => 10000).", 0),
+	"MQTTConfig.Format":      docEnum("Topic and payload layout (empty => json).", "", "json", "sparkplug_b"),
+	"MQTTConfig.TopicPrefix": doc("Root of the json topics (empty => modbus-replicator)."),
+	"MQTTConfig.GroupID":     doc("Sparkplug B group id (empty => modbus-replicator)."),
+	"MQTTConfig.EdgeNodeID":  doc("Sparkplug B edge node id (empty => the client id)."),
+
+	"EventsConfig.Log": doc("Append every change event to this file as one JSON line (empty => no log). Fixed at startup."),
+
+	"UnitConfig.ID":       doc("Unique unit id. With generate, {n} is replaced by the instance number."),
+	"UnitConfig.Source":   doc("The polled field device."),
+	"UnitConfig.Reads":    doc("Read blocks polled every cycle (all-or-nothing)."),
+	"UnitConfig.Targets":  doc("Replica endpoints the poll result is written to."),
+	"UnitConfig.Poll":     doc("Poll timing."),
+	"UnitConfig.Profile":  doc("Name of a replicator.profiles entry to inherit unset fields from."),
+	"UnitConfig.Generate": doc("Expand this entry into several units at load time."),
+
+	"ProfileConfig.Source":  doc("Source defaults."),
+	"ProfileConfig.Reads":   doc("Default read blocks."),
+	"ProfileConfig.Targets": doc("Default targets, including memory offsets."),
+	"ProfileConfig.Poll":    doc("Default poll timing."),
+
+	"GenerateConfig.Count":      docRange("Number of units to generate.", 1, maxGenerateCount),
+	"GenerateConfig.Start":      docMin("First instance number (0 => 1).", 0),
+	"GenerateConfig.IPStep":     doc("Added to the last IPv4 octet of source.endpoint per instance."),
+	"GenerateConfig.PortStep":   doc("Added to the port of source.endpoint per instance."),
+	"GenerateConfig.UnitID":     docEnum("fixed keeps source.unit_id; increment takes the next free one.", "", "fixed", "increment"),
+	"GenerateConfig.OffsetStep": doc("Added to every target memory offset of that function code, per instance."),
+
+	"SourceConfig.Endpoint":     doc("Modbus TCP device, host:port."),
+	"SourceConfig.UnitID":       docRange("Modbus unit id of the device.", 0, 255),
+	"SourceConfig.TimeoutMs":    docMin("Connect/read timeout in ms (0 => 2000, capped at poll.interval_ms).", 0),
+	"SourceConfig.StatusSlot":   docRange("Status block slot (block at slot*30); omit to disable status.", 0, 2183),
+	"SourceConfig.DeliverySlot": docRange("Target delivery block slot in the status memory (block at slot*30); needs status_slot; omit to disable.", 0, 2183),
+	"SourceConfig.DeviceName":   doc("ASCII name written into the status block (16 characters max)."),
+
+	"ReadConfig.FC":       docEnum("Modbus read function code: 1 coils, 2 discrete inputs, 3 holding registers, 4 input registers.", 1, 2, 3, 4),
+	"ReadConfig.Address":  docRange("Start address.", 0, 65535),
+	"ReadConfig.Quantity": docRange("Number of bits (FC 1/2, max 2000) or registers (FC 3/4, max 125).", 1, maxReadBits),
+	"ReadConfig.Deadband": doc("Change-event filter for register blocks (FC 3/4). Targets always get every value."),
+
+	"DeadbandConfig.Absolute": docMin("Report a register change once it moved more than N counts (0 => any change).", 0),
+	"DeadbandConfig.Percent":  docMin("Report a register change once it moved more than N percent of the last reported value (0 => any change).", 0),
+
+	"TargetConfig.ID":           docRange("Target id; sent as the Raw Ingest unit id.", 0, 255),
+	"TargetConfig.Endpoint":     doc("Raw Ingest endpoint, host:port."),
+	"TargetConfig.UnitID":       docRange("Data memory unit id.", 0, 255),
+	"TargetConfig.StatusUnitID": docRange("Unit id of the status memory; required when source.status_slot is set.", 0, 255),
+	"TargetConfig.Memories":     doc("Destination memories on this endpoint."),
+
+	"MemoryConfig.MemoryID": docRange("Destination memory id.", 0, 65535),
+	"MemoryConfig.Offsets":  doc("Address delta per function code (\"1\".. \"4\"); missing => 0."),
+
+	"PollConfig.IntervalMs": docMin("Poll period in ms (0 => 1000). Must not be shorter than source.timeout_ms.", 0),
+}
+
+// Schema returns the JSON Schema (draft 2020-12) of the config file
+// format, generated from the Go structs.
+func Schema() ([]byte, error) {
+	g := &schemaGen{defs: make(map[string]any)}
+
+	root := g.object(reflect.TypeOf(Config{}))
+	props := root["properties"].(map[string]any)
+	props[includeKey] = map[string]any{
+		"description": "Files or globs merged into this one, relative to it.",
+		"anyOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	s := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "modbus-replicator configuration",
+		"description": "Generated by `replicator schema` from internal/config. Do not edit.",
+		"$defs":       g.defs,
+	}
+	for k, v := range root {
+		s[k] = v
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+type schemaGen struct {
+	defs map[string]any
+	err  error
+}
+
+// ref emits a struct once into $defs and returns a reference to it.
+func (g *schemaGen) ref(t reflect.Type) map[string]any {
+	if _, ok := g.defs[t.Name()]; !ok {
+		g.defs[t.Name()] = nil // reserve: recursion guard
+		g.defs[t.Name()] = g.object(t)
+	}
+	return map[string]any{"$ref": "#/$defs/" + t.Name()}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+
+		key := t.Name() + "." + f.Name
+		d, ok := fieldDocs[key]
+		if !ok {
+			g.err = fmt.Errorf("schema: no fieldDocs entry for %s", key)
+			continue
+		}
+
+		p := g.typeOf(f.Type, d)
+		p["description"] = d.desc
+		props[name] = p
+	}
+
+	obj := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if t == reflect.TypeOf(ReplicatorConfig{}) {
+		for key, msg := range deprecatedKeys {
+			if k, ok := strings.CutPrefix(key, "replicator."); ok {
+				props[k] = map[string]any{"deprecated": true, "description": "Deprecated: " + msg}
+			}
+		}
+	}
+	return obj
+}
+
+// interpolated accepts a ${...} string where a number is expected,
+// so files using interpolation still validate in editors.
+var interpolated = map[string]any{"type": "string", "pattern": `^\$\{.+\}$`}
+
+func (g *schemaGen) typeOf(t reflect.Type, d fieldDoc) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner := g.typeOf(t.Elem(), d)
+		return map[string]any{"anyOf": []any{inner, map[string]any{"type": "null"}}}
+
+	case reflect.Struct:
+		return g.ref(t)
+
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.typeOf(t.Elem(), fieldDoc{})}
+
+	case reflect.Map:
+		m := map[string]any{
+			"type":                 "object",
+			"additionalProperties": g.typeOf(t.Elem(), fieldDoc{}),
+		}
+		if t.Key().Kind() == reflect.Int {
+			m["propertyNames"] = map[string]any{"pattern": "^[1-4]$"}
+		}
+		return m
+
+	case reflect.String:
+		s := map[string]any{"type": "string"}
+		if d.enum != nil {
+			s["enum"] = d.enum
+		}
+		return s
+
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Int, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Float64:
+		n := map[string]any{"type": "integer"}
+		if t.Kind() == reflect.Float64 {
+			n["type"] = "number"
+		}
+		lo, hi := intBounds(t.Kind())
+		if d.min != nil {
+			lo = d.min
+		}
+		if d.max != nil {
+			hi = d.max
+		}
+		if lo != nil {
+			n["minimum"] = *lo
+		}
+		if hi != nil {
+			n["maximum"] = *hi
+		}
+		if d.enum != nil {
+			n["enum"] = d.enum
+		}
+		return map[string]any{"anyOf": []any{n, interpolated}}
+	}
+
+	g.err = fmt.Errorf("schema: unsupported type %s", t)
+	return map[string]any{}
+}
+
+func intBounds(k reflect.Kind) (*int64, *int64) {
+	switch k {
+	case reflect.Uint8:
+		return rng(0, 255)
+	case reflect.Uint16:
+		return rng(0, 65535)
+	case reflect.Uint32:
+		return rng(0, 1<<32-1)
+	}
+	return nil, nil
+}
@@ -0,0 +1,80 @@
+// internal/config/schema_test.go
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// schemaFile is the committed schema editors and CI point at.
+const schemaFile = "../../docs/config.schema.json"
+
+func TestSchema_InSync(t *testing.T) {
+	got, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s is out of date; regenerate with:\n  go run ./cmd/replicator schema > docs/config.schema.json", schemaFile)
+	}
+}
+
+func TestSchema_NoStaleFieldDocs(t *testing.T) {
+	types := map[string]reflect.Type{}
+	for _, v := range []any{
+		Config{}, ReplicatorConfig{}, ShutdownConfig{}, ReloadConfig{}, HTTPConfig{}, LoggingConfig{},
+		HistorianConfig{}, MQTTConfig{}, EventsConfig{}, UnitConfig{}, ProfileConfig{}, GenerateConfig{},
+		SourceConfig{}, ReadConfig{}, DeadbandConfig{}, TargetConfig{}, MemoryConfig{}, PollConfig{},
+	} {
+		types[reflect.TypeOf(v).Name()] = reflect.TypeOf(v)
+	}
+
+	for key := range fieldDocs {
+		typ, field, _ := strings.Cut(key, ".")
+		rt, ok := types[typ]
+		if !ok {
+			t.Errorf("fieldDocs %s: unknown type", key)
+			continue
+		}
+		if _, ok := rt.FieldByName(field); !ok {
+			t.Errorf("fieldDocs %s: no such field", key)
+		}
+	}
+}
+
+func TestSchema_Annotations(t *testing.T) {
+	b, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Defs map[string]struct {
+			Properties map[string]map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := s.Defs["ReadConfig"].Properties["fc"]["anyOf"].([]any)[0].(map[string]any)
+	if !reflect.DeepEqual(fc["enum"], []any{1.0, 2.0, 3.0, 4.0}) {
+		t.Fatalf("fc enum = %v", fc["enum"])
+	}
+
+	uid := s.Defs["SourceConfig"].Properties["unit_id"]["anyOf"].([]any)[0].(map[string]any)
+	if uid["minimum"] != 0.0 || uid["maximum"] != 255.0 {
+		t.Fatalf("unit_id range = %v..%v", uid["minimum"], uid["maximum"])
+	}
+
+	if s.Defs["TargetConfig"].Properties["status_unit_id"]["description"] == "" {
+		t.Fatal("missing description")
+	}
+}
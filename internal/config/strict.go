@@ -0,0 +1,163 @@
+// internal/config/strict.go
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// deprecatedKeys are accepted with a warning instead of failing the
+// known-fields check. Paths use "[]" for any list index.
+var deprecatedKeys = map[string]string{
+	"replicator.Status_Memory": "legacy global status memory is ignored; " +
+		"status is written per target via targets[].status_unit_id (run `replicator migrate`)",
+}
+
+// checkKnownFields rejects keys that do not map to a config field.
+//
+// yaml.v3 only offers KnownFields on a byte Decoder; checking the node
+// tree instead keeps file:line after includes and interpolation.
+func (l *loader) checkKnownFields(n *yaml.Node, t reflect.Type, path, file string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nil // type mismatch: reported by Decode
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			keyPath := joinPath(path, k.Value)
+
+			if msg, ok := deprecatedKeys[genericPath(keyPath)]; ok {
+				l.warnings = append(l.warnings, fmt.Sprintf("%s:%d: %s is deprecated: %s", file, k.Line, keyPath, msg))
+				continue
+			}
+
+			ft, ok := fields[k.Value]
+			if !ok {
+				err := fmt.Errorf("unknown key %q in %s", k.Value, orRoot(path))
+				if s := suggest(k.Value, fields); s != "" {
+					err = fmt.Errorf("%w (did you mean %q?)", err, s)
+				}
+				return &PosError{File: file, Line: k.Line, Col: k.Column, Err: err}
+			}
+			if err := l.checkKnownFields(v, ft, keyPath, file); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if err := l.checkKnownFields(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value), file); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, c := range n.Content {
+			if err := l.checkKnownFields(c, t.Elem(), fmt.Sprintf("%s[%d]", path, i), file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// yamlFields maps yaml key → field type for one struct.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		out[name] = f.Type
+	}
+	return out
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func orRoot(path string) string {
+	if path == "" {
+		return "document root"
+	}
+	return path
+}
+
+// genericPath replaces list indices with "[]".
+func genericPath(p string) string {
+	var b strings.Builder
+	skip := false
+	for _, r := range p {
+		switch {
+		case r == '[':
+			skip = true
+			b.WriteString("[]")
+		case r == ']':
+			skip = false
+		case !skip:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// suggest returns the closest known key within edit distance 2.
+func suggest(key string, fields map[string]reflect.Type) string {
+	best, bestD := "", 3
+	for k := range fields {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+		if d := editDistance(key, k); d < bestD || (d == bestD && k < best) {
+			best, bestD = k, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -2,136 +2,513 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-// Validate checks configuration correctness.
+// FieldError is one validation problem at one YAML path,
+// e.g. "replicator.units[2].reads[0].quantity".
+type FieldError struct {
+	Path string `json:"path"`
+	Msg  string `json:"message"`
+}
+
+func (e FieldError) Error() string { return e.Path + ": " + e.Msg }
+
+// ValidationErrors is every problem Validate found, in config order.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	if len(v) == 1 {
+		return v[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d validation errors:", len(v))
+	for _, e := range v {
+		b.WriteString("\n  ")
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+// Modbus protocol limits per read request (Modbus Application Protocol v1.1b3).
+const (
+	maxReadBits      = 2000 // FC 1, 2
+	maxReadRegisters = 125  // FC 3, 4
+)
+
+// statusArea is where status blocks land on a target (holding registers).
+const statusArea = 3
+
+// statusBlockSlots mirrors status.SlotsPerDevice (config must not import
+// runtime packages).
+const statusBlockSlots = 30
+
+// deliveryMaxTargets mirrors status.DeliveryMaxTargets.
+const deliveryMaxTargets = 5
+
+// Validate checks configuration correctness and reports every problem,
+// not just the first. The error is a ValidationErrors.
 // It performs declarative validation only.
 // It MUST NOT mutate configuration.
 func Validate(cfg *Config) error {
-	type span struct {
-		start uint16
-		end   uint16
-		unit  string
+	v := &validator{}
+	v.global(cfg)
+	v.units(cfg)
+	v.status(cfg)
+	v.destinations(cfg)
+
+	if len(v.errs) == 0 {
+		return nil
 	}
+	return v.errs
+}
 
-	// ------------------------------------------------------------
-	// DEVICE STATUS BLOCK VALIDATION (PER-TARGET, OPT-IN)
-	// ------------------------------------------------------------
+type validator struct {
+	errs ValidationErrors
+}
 
-	// key = endpoint | status_unit_id | status_slot
-	statusOwner := make(map[string]string)
+func (v *validator) add(path, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
 
-	for _, u := range cfg.Replicator.Units {
-		// device_name sanity (ASCII only)
-		if u.Source.DeviceName != "" {
-			for i := 0; i < len(u.Source.DeviceName); i++ {
-				if u.Source.DeviceName[i] > 0x7F {
-					return fmt.Errorf(
-						"unit %q: device_name must contain ASCII characters only",
-						u.ID,
-					)
+// ------------------------------------------------------------
+// GLOBAL SECTIONS
+// ------------------------------------------------------------
+
+func (v *validator) global(cfg *Config) {
+	r := cfg.Replicator
+
+	switch {
+	case cfg.Version < 0 || cfg.Version > CurrentVersion:
+		v.add("version", "%d not supported (this build reads up to %d)", cfg.Version, CurrentVersion)
+	case cfg.Version != 0 && cfg.Version < CurrentVersion:
+		v.add("version", "%d is an older layout; rewrite it with `replicator migrate`", cfg.Version)
+	}
+
+	if r.Shutdown.TimeoutMs < 0 {
+		v.add("replicator.shutdown.timeout_ms", "must be >= 0")
+	}
+	switch r.Shutdown.Status {
+	case "", "disabled", "unknown", "stale":
+	default:
+		v.add("replicator.shutdown.status", "%q not supported (disabled, unknown, stale)", r.Shutdown.Status)
+	}
+
+	if r.Reload.WatchIntervalMs < 0 {
+		v.add("replicator.reload.watch_interval_ms", "must be >= 0")
+	}
+
+	if l := r.HTTP.Listen; l != "" {
+		if _, _, err := net.SplitHostPort(l); err != nil {
+			v.add("replicator.http.listen", "%q: %v", l, err)
+		}
+	}
+
+	lc := r.Logging
+	if !validLogLevel(lc.Level) {
+		v.add("replicator.logging.level", "%q not supported (debug, info, warn, error)", lc.Level)
+	}
+	for _, id := range sortedKeys(lc.Units) {
+		if !validLogLevel(lc.Units[id]) {
+			v.add("replicator.logging.units."+id, "%q not supported (debug, info, warn, error)", lc.Units[id])
+		}
+	}
+	switch lc.Format {
+	case "", "text", "json":
+	default:
+		v.add("replicator.logging.format", "%q not supported (text, json)", lc.Format)
+	}
+	if lc.SuppressWindowMs < 0 {
+		v.add("replicator.logging.suppress_window_ms", "must be >= 0")
+	}
+
+	switch r.Historian.Rotate {
+	case "", "daily", "hourly":
+	default:
+		v.add("replicator.historian.rotate", "%q not supported (daily, hourly)", r.Historian.Rotate)
+	}
+	if r.Historian.RetentionDays < 0 {
+		v.add("replicator.historian.retention_days", "must be >= 0")
+	}
+
+	v.mqtt(r.MQTT)
+}
+
+func (v *validator) mqtt(m MQTTConfig) {
+	const p = "replicator.mqtt"
+	if m.Broker == "" {
+		return
+	}
+	if _, _, err := net.SplitHostPort(m.Broker); err != nil {
+		v.add(p+".broker", "%q: %v", m.Broker, err)
+	}
+	if m.QoS != 0 && m.QoS != 1 {
+		v.add(p+".qos", "%d not supported (0, 1)", m.QoS)
+	}
+	if m.KeepAliveMs < 1000 {
+		v.add(p+".keepalive_ms", "must be >= 1000")
+	}
+	for _, f := range []struct {
+		name string
+		ms   int
+	}{{"timeout_ms", m.TimeoutMs}, {"reconnect_ms", m.ReconnectMs}, {"queue_size", m.QueueSize}} {
+		if f.ms < 0 {
+			v.add(p+"."+f.name, "must be >= 0")
+		}
+	}
+
+	// Topic levels must not contain wildcards; Sparkplug ids are one level.
+	switch m.Format {
+	case "json":
+		if strings.ContainsAny(m.TopicPrefix, "+#") {
+			v.add(p+".topic_prefix", "%q: must not contain + or #", m.TopicPrefix)
+		}
+	case "sparkplug_b":
+		for _, f := range []struct{ name, id string }{{"group_id", m.GroupID}, {"edge_node_id", m.EdgeNodeID}} {
+			if strings.ContainsAny(f.id, "+#/") {
+				v.add(p+"."+f.name, "%q: must not contain +, # or /", f.id)
+			}
+		}
+	default:
+		v.add(p+".format", "%q not supported (json, sparkplug_b)", m.Format)
+	}
+}
+
+// ------------------------------------------------------------
+// PER-UNIT GEOMETRY
+// ------------------------------------------------------------
+
+func (v *validator) units(cfg *Config) {
+	seen := make(map[string]int)
+
+	for ui, u := range cfg.Replicator.Units {
+		p := fmt.Sprintf("replicator.units[%d]", ui)
+
+		// ---- identity ----
+		switch prev, dup := seen[u.ID]; {
+		case u.ID == "":
+			v.add(p+".id", "required")
+		case dup:
+			v.add(p+".id", "duplicate id %q (also units[%d])", u.ID, prev)
+		default:
+			seen[u.ID] = ui
+		}
+
+		// ---- source ----
+		v.endpoint(p+".source.endpoint", u.Source.Endpoint)
+		if u.Source.TimeoutMs < 0 {
+			v.add(p+".source.timeout_ms", "must be >= 0")
+		}
+		for i := 0; i < len(u.Source.DeviceName); i++ {
+			if u.Source.DeviceName[i] > 0x7F {
+				v.add(p+".source.device_name", "must contain ASCII characters only")
+				break
+			}
+		}
+
+		// ---- poll ----
+		switch {
+		case u.Poll.IntervalMs < 0:
+			v.add(p+".poll.interval_ms", "must be >= 0")
+		case u.Poll.IntervalMs > 0 && u.Poll.IntervalMs < u.Source.TimeoutMs:
+			v.add(p+".poll.interval_ms", "%d is shorter than source.timeout_ms %d; a slow device would overrun every tick",
+				u.Poll.IntervalMs, u.Source.TimeoutMs)
+		}
+
+		// ---- reads ----
+		if len(u.Reads) == 0 {
+			v.add(p+".reads", "at least one read is required")
+		}
+		for ri, r := range u.Reads {
+			rp := fmt.Sprintf("%s.reads[%d]", p, ri)
+			v.read(rp, r)
+		}
+
+		// ---- targets ----
+		for ti, t := range u.Targets {
+			tp := fmt.Sprintf("%s.targets[%d]", p, ti)
+
+			// The writer sends target id as the Raw Ingest unit id.
+			if t.ID > 255 {
+				v.add(tp+".id", "%d out of range 0..255 (sent as the Raw Ingest unit id)", t.ID)
+			}
+			v.endpoint(tp+".endpoint", t.Endpoint)
+			if len(t.Memories) == 0 {
+				v.add(tp+".memories", "at least one memory is required")
+			}
+
+			for mi, m := range t.Memories {
+				mp := fmt.Sprintf("%s.memories[%d]", tp, mi)
+				for _, fc := range sortedKeys(m.Offsets) {
+					if fc < 1 || fc > 4 {
+						v.add(fmt.Sprintf("%s.offsets.%d", mp, fc), "function code must be 1..4")
+					}
+				}
+				for ri, r := range u.Reads {
+					if !validFC(r.FC) || r.Quantity == 0 {
+						continue // reported on the read
+					}
+					start := uint32(m.Offsets[int(r.FC)]) + uint32(r.Address)
+					if end := start + uint32(r.Quantity) - 1; end > 0xFFFF {
+						v.add(fmt.Sprintf("%s.offsets.%d", mp, r.FC),
+							"reads[%d] lands at %d-%d, past address 65535", ri, start, end)
+					}
 				}
 			}
 		}
+	}
+}
+
+func (v *validator) read(p string, r ReadConfig) {
+	if !validFC(r.FC) {
+		v.add(p+".fc", "%d not supported (1, 2, 3, 4)", r.FC)
+		return
+	}
+	if r.Quantity == 0 {
+		v.add(p+".quantity", "must be >= 1")
+		return
+	}
+
+	limit := uint16(maxReadRegisters)
+	if r.FC == 1 || r.FC == 2 {
+		limit = maxReadBits
+	}
+	if r.Quantity > limit {
+		v.add(p+".quantity", "%d exceeds the protocol limit %d for fc %d", r.Quantity, limit, r.FC)
+	}
+
+	if end := uint32(r.Address) + uint32(r.Quantity) - 1; end > 0xFFFF {
+		v.add(p, "address %d + quantity %d runs past address 65535", r.Address, r.Quantity)
+	}
+
+	if db := r.Deadband; db != nil {
+		if r.FC == 1 || r.FC == 2 {
+			v.add(p+".deadband", "only applies to registers (fc 3, 4); bits report every change")
+		}
+		if db.Absolute < 0 {
+			v.add(p+".deadband.absolute", "must be >= 0")
+		}
+		if db.Percent < 0 {
+			v.add(p+".deadband.percent", "must be >= 0")
+		}
+	}
+}
+
+func (v *validator) endpoint(p, ep string) {
+	if ep == "" {
+		v.add(p, "required")
+		return
+	}
+	host, port, err := net.SplitHostPort(ep)
+	if err != nil {
+		v.add(p, "%q is not host:port: %v", ep, err)
+		return
+	}
+	if host == "" {
+		v.add(p, "%q has no host", ep)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		v.add(p, "%q: port must be 1..65535", ep)
+	}
+}
+
+// ------------------------------------------------------------
+// DEVICE STATUS BLOCK VALIDATION (PER-TARGET, OPT-IN)
+// ------------------------------------------------------------
+
+func (v *validator) status(cfg *Config) {
+	// key = endpoint | status_unit_id | status_slot
+	statusOwner := make(map[string]string)
+
+	for ui, u := range cfg.Replicator.Units {
+		p := fmt.Sprintf("replicator.units[%d]", ui)
 
 		// status is opt-in
 		if u.Source.StatusSlot == nil {
+			if u.Source.DeliverySlot != nil {
+				v.add(p+".source.delivery_slot", "requires source.status_slot (the block lives in the status memory)")
+			}
 			continue
 		}
+		slot := *u.Source.StatusSlot
+
+		if end := uint32(slot)*statusBlockSlots + statusBlockSlots - 1; end > 0xFFFF {
+			v.add(p+".source.status_slot", "%d puts the status block past address 65535", slot)
+		}
 
 		// status requires at least one target
 		if len(u.Targets) == 0 {
-			return fmt.Errorf(
-				"unit %q: status_slot is set but no targets are defined",
-				u.ID,
-			)
+			v.add(p+".source.status_slot", "set but no targets are defined")
 		}
 
-		slot := *u.Source.StatusSlot
+		for ti, t := range u.Targets {
+			tp := fmt.Sprintf("%s.targets[%d]", p, ti)
 
-		for _, t := range u.Targets {
 			// each target must declare status_unit_id
 			if t.StatusUnitID == nil {
-				return fmt.Errorf(
-					"unit %q: status_slot is set but target %q has no status_unit_id",
-					u.ID,
-					t.Endpoint,
-				)
+				v.add(tp+".status_unit_id", "required when source.status_slot is set")
+				continue
 			}
 
-			key := fmt.Sprintf(
-				"%s|%d|%d",
-				t.Endpoint,
-				*t.StatusUnitID,
-				slot,
-			)
-
+			key := fmt.Sprintf("%s|%d|%d", t.Endpoint, *t.StatusUnitID, slot)
 			if prev, exists := statusOwner[key]; exists {
-				return fmt.Errorf(
-					"status_slot collision: endpoint=%s status_unit_id=%d slot=%d used by units %q and %q",
-					t.Endpoint,
-					*t.StatusUnitID,
-					slot,
-					prev,
-					u.ID,
-				)
+				v.add(p+".source.status_slot",
+					"collision: endpoint=%s status_unit_id=%d slot=%d already used by unit %q",
+					t.Endpoint, *t.StatusUnitID, slot, prev)
+				continue
 			}
-
 			statusOwner[key] = u.ID
 		}
+
+		if u.Source.DeliverySlot != nil {
+			v.delivery(p, u, statusOwner)
+		}
 	}
+}
 
-	// ------------------------------------------------------------
-	// DESTINATION MEMORY GEOMETRY VALIDATION
-	// ------------------------------------------------------------
+// delivery checks the delivery block of a unit with status enabled.
+// It shares the slot space of the status memory with status blocks.
+func (v *validator) delivery(p string, u UnitConfig, owner map[string]string) {
+	slot := *u.Source.DeliverySlot
+	sp := p + ".source.delivery_slot"
 
-	// key = endpoint | memory_id | fc
+	if slot == *u.Source.StatusSlot {
+		v.add(sp, "%d is also the status_slot", slot)
+		return
+	}
+	if end := uint32(slot)*statusBlockSlots + statusBlockSlots - 1; end > 0xFFFF {
+		v.add(sp, "%d puts the delivery block past address 65535", slot)
+	}
+	if len(u.Targets) > deliveryMaxTargets {
+		v.add(sp, "%d targets; the delivery block holds %d", len(u.Targets), deliveryMaxTargets)
+	}
+
+	for _, t := range u.Targets {
+		if t.StatusUnitID == nil {
+			continue // reported by status
+		}
+		key := fmt.Sprintf("%s|%d|%d", t.Endpoint, *t.StatusUnitID, slot)
+		if prev, exists := owner[key]; exists {
+			v.add(sp,
+				"collision: endpoint=%s status_unit_id=%d slot=%d already used by unit %q",
+				t.Endpoint, *t.StatusUnitID, slot, prev)
+			continue
+		}
+		owner[key] = u.ID
+	}
+}
+
+// ------------------------------------------------------------
+// DESTINATION MEMORY GEOMETRY VALIDATION
+// ------------------------------------------------------------
+
+// destinations rejects overlapping writes on the same target memory.
+// On the wire a memory is (endpoint, Raw Ingest unit id, area): data goes
+// to the target id (memory_id is never sent) in the area of its fc, and
+// status and delivery blocks to status_unit_id in holding registers.
+func (v *validator) destinations(cfg *Config) {
+	type span struct {
+		start, end uint32
+		what       string
+	}
+
+	// key = endpoint | unit id | area
 	spans := make(map[string][]span)
 
-	for _, u := range cfg.Replicator.Units {
-		for _, t := range u.Targets {
-			for _, m := range t.Memories {
-				for _, r := range u.Reads {
-					offset := uint16(0)
-					if m.Offsets != nil {
-						if v, ok := m.Offsets[int(r.FC)]; ok {
-							offset = v
-						}
-					}
+	check := func(p, key string, s span) {
+		for _, o := range spans[key] {
+			// overlap check (inclusive)
+			if s.start <= o.end && o.start <= s.end {
+				v.add(p, "%s range %d-%d overlaps %s range %d-%d", s.what, s.start, s.end, o.what, o.start, o.end)
+				return
+			}
+		}
+		spans[key] = append(spans[key], s)
+	}
 
-					start := offset + r.Address
-					end := start + r.Quantity - 1
-
-					key := fmt.Sprintf("%s|%d|%d", t.Endpoint, m.MemoryID, r.FC)
-
-					existing := spans[key]
-					for _, s := range existing {
-						// overlap check (inclusive)
-						if !(end < s.start || start > s.end) {
-							return fmt.Errorf(
-								"memory overlap: endpoint=%s memory_id=%d fc=%d range=%d-%d overlaps with unit=%s range=%d-%d",
-								t.Endpoint,
-								m.MemoryID,
-								r.FC,
-								start,
-								end,
-								s.unit,
-								s.start,
-								s.end,
-							)
-						}
-					}
+	// Status blocks first, so data overlapping them is reported on data.
+	// Identical blocks are slot collisions, already reported by status.
+	sameBlock := make(map[string]bool)
+	for ui, u := range cfg.Replicator.Units {
+		if u.Source.StatusSlot == nil {
+			continue
+		}
+		start := uint32(*u.Source.StatusSlot) * statusBlockSlots
+		for ti, t := range u.Targets {
+			if t.StatusUnitID == nil {
+				continue
+			}
+			key := fmt.Sprintf("%s|%d|%d", t.Endpoint, *t.StatusUnitID, statusArea)
+			if sameBlock[fmt.Sprintf("%s|%d", key, start)] {
+				continue
+			}
+			sameBlock[fmt.Sprintf("%s|%d", key, start)] = true
+			check(fmt.Sprintf("replicator.units[%d].targets[%d].status_unit_id", ui, ti), key, span{
+				start: start,
+				end:   start + statusBlockSlots - 1,
+				what:  fmt.Sprintf("unit %q status block", u.ID),
+			})
 
-					spans[key] = append(spans[key], span{
-						start: start,
-						end:   end,
-						unit:  u.ID,
-					})
+			if u.Source.DeliverySlot == nil || *u.Source.DeliverySlot == *u.Source.StatusSlot {
+				continue
+			}
+			dstart := uint32(*u.Source.DeliverySlot) * statusBlockSlots
+			if sameBlock[fmt.Sprintf("%s|%d", key, dstart)] {
+				continue
+			}
+			sameBlock[fmt.Sprintf("%s|%d", key, dstart)] = true
+			check(fmt.Sprintf("replicator.units[%d].targets[%d].status_unit_id", ui, ti), key, span{
+				start: dstart,
+				end:   dstart + statusBlockSlots - 1,
+				what:  fmt.Sprintf("unit %q delivery block", u.ID),
+			})
+		}
+	}
+
+	for ui, u := range cfg.Replicator.Units {
+		for ti, t := range u.Targets {
+			for mi, m := range t.Memories {
+				for ri, r := range u.Reads {
+					if !validFC(r.FC) || r.Quantity == 0 {
+						continue
+					}
+					start := uint32(m.Offsets[int(r.FC)]) + uint32(r.Address)
+					key := fmt.Sprintf("%s|%d|%d", t.Endpoint, t.ID, r.FC)
+					check(
+						fmt.Sprintf("replicator.units[%d].targets[%d].memories[%d]", ui, ti, mi),
+						key,
+						span{
+							start: start,
+							end:   start + uint32(r.Quantity) - 1,
+							what:  fmt.Sprintf("unit %q reads[%d] (endpoint=%s target id=%d fc=%d)", u.ID, ri, t.Endpoint, t.ID, r.FC),
+						},
+					)
 				}
 			}
 		}
 	}
+}
+
+func validFC(fc uint8) bool { return fc >= 1 && fc <= 4 }
 
-	return nil
+func validLogLevel(s string) bool {
+	switch s {
+	case "", "debug", "info", "warn", "warning", "error":
+		return true
+	}
+	return false
+}
+
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	out := make([]K, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
 }
@@ -1,12 +1,24 @@
 // internal/config/validate_test.go
 package config
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 // helper to build a unit quickly
 func unit(id string, endpoint string, memoryID uint16, fc uint8, addr, qty uint16, offset uint16) UnitConfig {
 	return UnitConfig{
 		ID: id,
+		Source: SourceConfig{
+			Endpoint:  "127.0.0.1:502",
+			UnitID:    1,
+			TimeoutMs: 500,
+		},
+		Poll: PollConfig{IntervalMs: 1000},
 		Reads: []ReadConfig{
 			{
 				FC:       fc,
@@ -37,8 +49,8 @@ func TestValidate_NoOverlapDifferentEndpoints(t *testing.T) {
 	cfg := &Config{
 		Replicator: ReplicatorConfig{
 			Units: []UnitConfig{
-				unit("u1", "ep1", 0, 3, 0, 10, 0),
-				unit("u2", "ep2", 0, 3, 0, 10, 0),
+				unit("u1", "10.0.0.1:9000", 0, 3, 0, 10, 0),
+				unit("u2", "10.0.0.2:9000", 0, 3, 0, 10, 0),
 			},
 		},
 	}
@@ -48,27 +60,44 @@ func TestValidate_NoOverlapDifferentEndpoints(t *testing.T) {
 	}
 }
 
-func TestValidate_NoOverlapDifferentMemory(t *testing.T) {
+func TestValidate_NoOverlapDifferentTarget(t *testing.T) {
 	cfg := &Config{
 		Replicator: ReplicatorConfig{
 			Units: []UnitConfig{
-				unit("u1", "ep1", 0, 3, 0, 10, 0),
-				unit("u2", "ep1", 1, 3, 0, 10, 0),
+				unit("u1", "10.0.0.1:9000", 0, 3, 0, 10, 0),
+				unit("u2", "10.0.0.1:9000", 0, 3, 0, 10, 0),
 			},
 		},
 	}
+	cfg.Replicator.Units[1].Targets[0].ID = 2
 
 	if err := Validate(cfg); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
 
+// memory_id is not sent on the wire: both memories land in target 1.
+func TestValidate_OverlapDifferentMemorySameTarget(t *testing.T) {
+	cfg := &Config{
+		Replicator: ReplicatorConfig{
+			Units: []UnitConfig{
+				unit("u1", "10.0.0.1:9000", 0, 3, 0, 10, 0),
+				unit("u2", "10.0.0.1:9000", 1, 3, 0, 10, 0),
+			},
+		},
+	}
+
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "overlaps") {
+		t.Fatalf("expected overlap error, got %v", err)
+	}
+}
+
 func TestValidate_NoOverlapDifferentFC(t *testing.T) {
 	cfg := &Config{
 		Replicator: ReplicatorConfig{
 			Units: []UnitConfig{
-				unit("u1", "ep1", 0, 3, 0, 10, 0),
-				unit("u2", "ep1", 0, 4, 0, 10, 0),
+				unit("u1", "10.0.0.1:9000", 0, 3, 0, 10, 0),
+				unit("u2", "10.0.0.1:9000", 0, 4, 0, 10, 0),
 			},
 		},
 	}
@@ -82,8 +111,8 @@ func TestValidate_TouchingRangesAllowed(t *testing.T) {
 	cfg := &Config{
 		Replicator: ReplicatorConfig{
 			Units: []UnitConfig{
-				unit("u1", "ep1", 0, 3, 0, 10, 0),   // 0–9
-				unit("u2", "ep1", 0, 3, 10, 10, 0), // 10–19
+				unit("u1", "10.0.0.1:9000", 0, 3, 0, 10, 0),  // 0–9
+				unit("u2", "10.0.0.1:9000", 0, 3, 10, 10, 0), // 10–19
 			},
 		},
 	}
@@ -97,14 +126,14 @@ func TestValidate_OverlapDetected(t *testing.T) {
 	cfg := &Config{
 		Replicator: ReplicatorConfig{
 			Units: []UnitConfig{
-				unit("u1", "ep1", 0, 3, 0, 10, 0), // 0–9
-				unit("u2", "ep1", 0, 3, 5, 10, 0), // 5–14 → overlap
+				unit("u1", "10.0.0.1:9000", 0, 3, 0, 10, 0), // 0–9
+				unit("u2", "10.0.0.1:9000", 0, 3, 5, 10, 0), // 5–14 → overlap
 			},
 		},
 	}
 
-	if err := Validate(cfg); err == nil {
-		t.Fatalf("expected overlap error, got nil")
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "overlaps") {
+		t.Fatalf("expected overlap error, got %v", err)
 	}
 }
 
@@ -112,13 +141,92 @@ func TestValidate_OverlapViaOffsetDetected(t *testing.T) {
 	cfg := &Config{
 		Replicator: ReplicatorConfig{
 			Units: []UnitConfig{
-				unit("u1", "ep1", 0, 3, 0, 10, 0),   // 0–9
-				unit("u2", "ep1", 0, 3, 0, 10, 5),  // 5–14 → overlap
+				unit("u1", "10.0.0.1:9000", 0, 3, 0, 10, 0), // 0–9
+				unit("u2", "10.0.0.1:9000", 0, 3, 0, 10, 5), // 5–14 → overlap
 			},
 		},
 	}
 
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "overlaps") {
+		t.Fatalf("expected overlap error, got %v", err)
+	}
+}
+
+func TestValidate_ShutdownStatus(t *testing.T) {
+	for _, st := range []string{"", "disabled", "unknown", "stale"} {
+		cfg := &Config{Replicator: ReplicatorConfig{Shutdown: ShutdownConfig{Status: st}}}
+		if err := Validate(cfg); err != nil {
+			t.Fatalf("status %q: unexpected error: %v", st, err)
+		}
+	}
+
+	cfg := &Config{Replicator: ReplicatorConfig{Shutdown: ShutdownConfig{Status: "ok"}}}
 	if err := Validate(cfg); err == nil {
-		t.Fatalf("expected overlap error, got nil")
+		t.Fatalf("expected error for shutdown status ok, got nil")
+	}
+}
+
+// ---- fixtures ----
+//
+// testdata/valid/*.yaml must load cleanly.
+// testdata/invalid/*.yaml list every expected error in "# want: <path>: <message prefix>"
+// header lines; Validate must report exactly those, in order.
+
+func TestValidate_ValidFixtures(t *testing.T) {
+	files, _ := filepath.Glob("testdata/valid/*.yaml")
+	if len(files) == 0 {
+		t.Fatal("no fixtures")
+	}
+	for _, f := range files {
+		if _, err := Load(f); err != nil {
+			t.Errorf("%s: %v", f, err)
+		}
+	}
+}
+
+func TestValidate_InvalidFixtures(t *testing.T) {
+	files, _ := filepath.Glob("testdata/invalid/*.yaml")
+	if len(files) == 0 {
+		t.Fatal("no fixtures")
+	}
+	for _, f := range files {
+		t.Run(filepath.Base(f), func(t *testing.T) {
+			want := fixtureWants(t, f)
+
+			_, err := Load(f)
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("expected ValidationErrors, got %v", err)
+			}
+
+			for i := 0; i < len(want) || i < len(verrs); i++ {
+				switch {
+				case i >= len(verrs):
+					t.Errorf("missing: %s", want[i])
+				case i >= len(want):
+					t.Errorf("unexpected: %s", verrs[i])
+				case !strings.HasPrefix(verrs[i].Error(), want[i]):
+					t.Errorf("error %d:\n got %s\nwant %s", i, verrs[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func fixtureWants(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if w, ok := strings.CutPrefix(line, "# want: "); ok {
+			out = append(out, w)
+		}
+	}
+	if len(out) == 0 {
+		t.Fatalf("%s: no '# want:' lines", path)
 	}
+	return out
 }
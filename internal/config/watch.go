@@ -0,0 +1,61 @@
+// internal/config/watch.go
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watch polls path every interval and signals on the returned channel
+// whenever its modification time or size changes.
+//
+// Polling is deliberate: it works the same on bind mounts, NFS and
+// Docker volumes, and needs no platform-specific notification API.
+// Signals are coalesced; a slow reader sees at most one pending signal.
+func Watch(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	out := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last, lastErr := os.Stat(path)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			fi, err := os.Stat(path)
+			if !fileChanged(last, lastErr, fi, err) {
+				continue
+			}
+			last, lastErr = fi, err
+
+			// A missing file is not a reload trigger; wait for it to return.
+			if err != nil {
+				continue
+			}
+
+			select {
+			case out <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return out
+}
+
+func fileChanged(prev os.FileInfo, prevErr error, curr os.FileInfo, currErr error) bool {
+	if (prevErr != nil) != (currErr != nil) {
+		return true
+	}
+	if currErr != nil {
+		return false
+	}
+	return !prev.ModTime().Equal(curr.ModTime()) || prev.Size() != curr.Size()
+}
@@ -0,0 +1,38 @@
+// internal/config/watch_test.go
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch_SignalsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replicator.yaml")
+	if err := os.WriteFile(path, []byte("replicator: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := Watch(ctx, path, 10*time.Millisecond)
+
+	select {
+	case <-changes:
+		t.Fatalf("unexpected signal before any change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("replicator:\n  units: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no signal after file change")
+	}
+}
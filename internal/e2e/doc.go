@@ -0,0 +1,7 @@
+// internal/e2e/doc.go
+
+// Package e2e holds end-to-end tests: simulated sources (internal/sim)
+// → the full replicator (config.Load + supervisor) → reference ingest
+// receivers (internal/ingestserver), all in-process on loopback
+// ephemeral ports. Everything lives in _test files; see harness_test.go.
+package e2e
@@ -0,0 +1,484 @@
+// internal/e2e/e2e_test.go
+package e2e
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/events"
+	"github.com/tamzrod/modbus-replicator/internal/ingestserver"
+	"github.com/tamzrod/modbus-replicator/internal/mqtt"
+	"github.com/tamzrod/modbus-replicator/internal/mqttpub"
+	"github.com/tamzrod/modbus-replicator/internal/recording"
+	"github.com/tamzrod/modbus-replicator/internal/sim"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+// One metered device, status in slot 1 of status unit 100. Data goes
+// to target id 1 with holding registers shifted by 1000.
+const meterConfig = `
+replicator:
+  shutdown:
+    status: stale
+  units:
+    - id: meter
+      source:
+        endpoint: "{{source "meter"}}"
+        unit_id: 1
+        timeout_ms: 40
+        device_name: "METER-7"
+        status_slot: 1
+      reads:
+        - fc: 3
+          address: 0
+          quantity: 10
+        - fc: 1
+          address: 0
+          quantity: 12
+      targets:
+        - id: 1
+          endpoint: "{{sink "mma"}}"
+          status_unit_id: 100
+          memories:
+            - memory_id: 0
+              offsets: { 3: 1000 }
+      poll:
+        interval_ms: 50
+`
+
+var meterRegs = []uint16{1, 2, 3, 4, 5, 6, 7, 8, 9, 0xABCD}
+
+func startMeter(t *testing.T) (*harness, *source, *sink) {
+	t.Helper()
+	h := newHarness(t)
+	src := h.source("meter", sim.Config{
+		UnitIDs: []uint8{1},
+		Memory: []sim.SeedBlock{
+			{Area: "holding", Address: 0, Values: meterRegs},
+			{Area: "coils", Address: 0, Values: []uint16{1, 0, 1, 1, 0, 0, 0, 0, 0, 0, 0, 1}},
+		},
+	})
+	mma := h.sink("mma", ingestserver.Config{AutoCreate: true})
+	h.run(meterConfig)
+	return h, src, mma
+}
+
+func health(s *sink) func() uint16 {
+	return func() uint16 { return s.statusBlock(100, 1)[status.SlotHealthCode] }
+}
+
+func TestE2E_ReplicatesDataAndStatus(t *testing.T) {
+	h, src, mma := startMeter(t)
+
+	eventually(t, "registers at 1000", func() bool {
+		return slices.Equal(mma.holding(1, 1000, 10), meterRegs)
+	})
+	eventually(t, "coils at 0", func() bool {
+		m := mma.Memory(1)
+		return m != nil && slices.Equal(m.Get(sim.Coils, 0, 12), []uint16{1, 0, 1, 1, 0, 0, 0, 0, 0, 0, 0, 1})
+	})
+	// Nothing outside the planned ranges.
+	if got := mma.holding(1, 0, 10); slices.ContainsFunc(got, func(v uint16) bool { return v != 0 }) {
+		t.Fatalf("unshifted registers written: %v", got)
+	}
+
+	eventually(t, "status ok", func() bool { return health(mma)() == status.HealthOK })
+	block := mma.statusBlock(100, 1)
+	if name := block[status.SlotDeviceNameStart : status.SlotDeviceNameStart+4]; !slices.Equal(name, []uint16{'M'<<8 | 'E', 'T'<<8 | 'E', 'R'<<8 | '-', '7' << 8}) {
+		t.Fatalf("device name slots: %x", name)
+	}
+	eventually(t, "transport counters", func() bool {
+		b := mma.statusBlock(100, 1)
+		return b[status.SlotRequestsTotalLow] > 0 && b[status.SlotResponsesValidTotalLow] > 0
+	})
+	eventually(t, "diagnostics", func() bool {
+		b := mma.statusBlock(100, 1)
+		epoch := int64(b[status.SlotLastSuccessEpochLow]) | int64(b[status.SlotLastSuccessEpochHigh])<<16
+		return b[status.SlotLayoutVersion] == status.LayoutVersion && b[status.SlotSecondsSinceGood] == 0 &&
+			time.Since(time.Unix(epoch, 0)).Abs() < time.Minute
+	})
+	if other := mma.statusBlock(100, 0); slices.ContainsFunc(other, func(v uint16) bool { return v != 0 }) {
+		t.Fatalf("slot 0 touched: %v", other)
+	}
+
+	// Changes at the source follow.
+	_ = src.Memory().Set(sim.HoldingRegisters, 9, 4242)
+	eventually(t, "changed register", func() bool { return mma.holding(1, 1009, 1)[0] == 4242 })
+
+	if u := h.unit("meter"); len(u.Targets) != 1 || u.Targets[0].Failures != 0 {
+		t.Fatalf("target outcomes: %+v", u.Targets)
+	}
+}
+
+// Source down: status turns ERROR with a code and counts seconds; the
+// target keeps the last good data untouched. Source back: OK, seconds
+// reset, fresh data.
+func TestE2E_SourceOutageAndRecovery(t *testing.T) {
+	_, src, mma := startMeter(t)
+	eventually(t, "status ok", func() bool { return health(mma)() == status.HealthOK })
+	tl := watch(t, health(mma))
+
+	src.stop()
+	eventually(t, "status error", func() bool { return health(mma)() == status.HealthError })
+	eventually(t, "seconds in error", func() bool {
+		b := mma.statusBlock(100, 1)
+		return b[status.SlotSecondsInError] >= 1 && b[status.SlotSecondsSinceGood] >= 1
+	})
+	block := mma.statusBlock(100, 1)
+	if block[status.SlotLastErrorCode] != 1 || block[status.SlotConsecutiveFailCurr] == 0 {
+		t.Fatalf("error slots: %v", block)
+	}
+	if got := mma.holding(1, 1000, 10); !slices.Equal(got, meterRegs) {
+		t.Fatalf("data changed during outage: %v", got)
+	}
+
+	_ = src.Memory().Set(sim.HoldingRegisters, 0, 77)
+	src.start()
+	eventually(t, "fresh data", func() bool { return mma.holding(1, 1000, 1)[0] == 77 })
+	eventually(t, "recovered", func() bool {
+		b := mma.statusBlock(100, 1)
+		return b[status.SlotHealthCode] == status.HealthOK && b[status.SlotSecondsInError] == 0 &&
+			b[status.SlotLastErrorCode] == 0 && b[status.SlotConsecutiveFailCurr] == 0
+	})
+
+	if got := tl.end(); !slices.Equal(got, []uint16{status.HealthOK, status.HealthError, status.HealthOK}) {
+		t.Fatalf("health timeline: %v", got)
+	}
+}
+
+// The raw exception code is what the target sees.
+func TestE2E_SourceExceptionCode(t *testing.T) {
+	_, src, mma := startMeter(t)
+	eventually(t, "status ok", func() bool { return health(mma)() == status.HealthOK })
+
+	_ = src.AddFault(sim.Fault{Kind: sim.FaultException, FC: 3, Code: 0x0B})
+	eventually(t, "exception code", func() bool {
+		b := mma.statusBlock(100, 1)
+		return b[status.SlotHealthCode] == status.HealthError && b[status.SlotLastErrorCode] == 0x0B
+	})
+
+	src.ClearFaults()
+	eventually(t, "recovered", func() bool { return health(mma)() == status.HealthOK })
+}
+
+// Two replicas. One MMA goes away and comes back empty: the other never
+// notices, the source status stays OK everywhere, and the returning one
+// gets data and a full status block (device name included) again.
+func TestE2E_TargetOutageAndRecovery(t *testing.T) {
+	h := newHarness(t)
+	src := h.source("meter", sim.Config{Memory: []sim.SeedBlock{{Area: "holding", Values: meterRegs}}})
+	a := h.sink("a", ingestserver.Config{AutoCreate: true})
+	b := h.sink("b", ingestserver.Config{AutoCreate: true})
+	h.run(`
+replicator:
+  units:
+    - id: meter
+      source:
+        endpoint: "{{source "meter"}}"
+        unit_id: 1
+        timeout_ms: 40
+        device_name: "METER-7"
+        status_slot: 1
+      reads:
+        - fc: 3
+          address: 0
+          quantity: 10
+      targets:
+        - id: 1
+          endpoint: "{{sink "a"}}"
+          status_unit_id: 100
+          memories:
+            - memory_id: 0
+              offsets: { 3: 1000 }
+        - id: 1
+          endpoint: "{{sink "b"}}"
+          status_unit_id: 100
+          memories:
+            - memory_id: 0
+              offsets: { 3: 1000 }
+      poll:
+        interval_ms: 50
+`)
+	for _, s := range []*sink{a, b} {
+		eventually(t, "both replicas", func() bool { return slices.Equal(s.holding(1, 1000, 10), meterRegs) })
+		eventually(t, "status ok", func() bool { return health(s)() == status.HealthOK })
+	}
+	tl := watch(t, health(b))
+
+	a.stop()
+	a.Reset()
+	_ = src.Memory().Set(sim.HoldingRegisters, 0, 500)
+	eventually(t, "replica b updated", func() bool { return b.holding(1, 1000, 1)[0] == 500 })
+	eventually(t, "replica a failures recorded", func() bool {
+		u := h.unit("meter")
+		return len(u.Targets) == 2 && u.Targets[0].Failures > 0 && u.Targets[0].LastErr != "" && u.Targets[1].LastErr == ""
+	})
+
+	a.start()
+	eventually(t, "replica a data", func() bool { return slices.Equal(a.holding(1, 1000, 1), []uint16{500}) })
+	eventually(t, "replica a status re-asserted", func() bool {
+		blk := a.statusBlock(100, 1)
+		return blk[status.SlotHealthCode] == status.HealthOK && blk[status.SlotDeviceNameStart] == 'M'<<8|'E'
+	})
+
+	if got := tl.end(); !slices.Equal(got, []uint16{status.HealthOK}) {
+		t.Fatalf("replica b saw source health %v during a target outage", got)
+	}
+}
+
+// With a delivery block, replica b's readers see that replica a is
+// stale, while b's status block keeps reporting the source only.
+func TestE2E_DeliveryBlock(t *testing.T) {
+	h := newHarness(t)
+	h.source("meter", sim.Config{Memory: []sim.SeedBlock{{Area: "holding", Values: meterRegs}}})
+	a := h.sink("a", ingestserver.Config{AutoCreate: true})
+	b := h.sink("b", ingestserver.Config{AutoCreate: true})
+	h.run(`
+replicator:
+  units:
+    - id: meter
+      source:
+        endpoint: "{{source "meter"}}"
+        unit_id: 1
+        timeout_ms: 40
+        device_name: "METER-7"
+        status_slot: 1
+        delivery_slot: 2
+      reads:
+        - fc: 3
+          address: 0
+          quantity: 10
+      targets:
+        - id: 1
+          endpoint: "{{sink "a"}}"
+          status_unit_id: 100
+          memories:
+            - memory_id: 0
+              offsets: { 3: 1000 }
+        - id: 2
+          endpoint: "{{sink "b"}}"
+          status_unit_id: 100
+          memories:
+            - memory_id: 0
+              offsets: { 3: 1000 }
+      poll:
+        interval_ms: 50
+`)
+	// entry i of the delivery block on s
+	entry := func(s *sink, i int) []uint16 {
+		blk := s.statusBlock(100, 2)
+		return blk[i*status.DeliverySlotsPerTarget : (i+1)*status.DeliverySlotsPerTarget]
+	}
+	for _, s := range []*sink{a, b} {
+		eventually(t, "both targets delivered", func() bool {
+			return entry(s, 0)[status.DeliverySlotLastOutcome] == status.DeliveryOK &&
+				entry(s, 1)[status.DeliverySlotLastOutcome] == status.DeliveryOK
+		})
+	}
+	if e := entry(b, 1); e[status.DeliverySlotTargetID] != 2 || e[status.DeliverySlotLastSuccessEpochHigh] == 0 {
+		t.Fatalf("entry of b: %v", e)
+	}
+	tl := watch(t, health(b))
+
+	a.stop()
+	eventually(t, "b reports a failing", func() bool {
+		e := entry(b, 0)
+		return e[status.DeliverySlotTargetID] == 1 &&
+			e[status.DeliverySlotLastOutcome] == status.DeliveryFailed &&
+			e[status.DeliverySlotConsecutiveFailures] >= 2
+	})
+	if e := entry(b, 1); e[status.DeliverySlotLastOutcome] != status.DeliveryOK || e[status.DeliverySlotConsecutiveFailures] != 0 {
+		t.Fatalf("entry of b during a's outage: %v", e)
+	}
+
+	a.start()
+	eventually(t, "b reports a recovered", func() bool {
+		e := entry(b, 0)
+		return e[status.DeliverySlotLastOutcome] == status.DeliveryOK && e[status.DeliverySlotConsecutiveFailures] == 0
+	})
+
+	if got := tl.end(); !slices.Equal(got, []uint16{status.HealthOK}) {
+		t.Fatalf("replica b saw source health %v during a target outage", got)
+	}
+}
+
+// A deliberate stop asserts the configured shutdown status.
+func TestE2E_ShutdownStatus(t *testing.T) {
+	h, _, mma := startMeter(t)
+	eventually(t, "status ok", func() bool { return health(mma)() == status.HealthOK })
+
+	h.shutdown()
+	if got := health(mma)(); got != status.HealthStale {
+		t.Fatalf("health after shutdown = %d, want stale", got)
+	}
+}
+
+// A session recorded against the simulator replays into a fresh MMA
+// through the normal writer path, with the device switched off: same
+// data changes, same exception code, in order. When the recording runs
+// out the unit goes to ERROR like a lost device.
+func TestE2E_RecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	hold := func() { time.Sleep(300 * time.Millisecond) } // several polls per phase
+
+	h := newHarness(t)
+	h.opts.RecordDir = dir
+	src := h.source("meter", sim.Config{Memory: []sim.SeedBlock{{Area: "holding", Values: meterRegs}}})
+	mma := h.sink("mma", ingestserver.Config{AutoCreate: true})
+	h.run(meterConfig)
+
+	eventually(t, "status ok", func() bool { return health(mma)() == status.HealthOK })
+	hold()
+	_ = src.Memory().Set(sim.HoldingRegisters, 0, 77)
+	eventually(t, "changed register", func() bool { return mma.holding(1, 1000, 1)[0] == 77 })
+	hold()
+	_ = src.AddFault(sim.Fault{Kind: sim.FaultException, FC: 3, Code: 0x0B})
+	eventually(t, "exception", func() bool { return health(mma)() == status.HealthError })
+	hold()
+	src.ClearFaults()
+	eventually(t, "recovered", func() bool { return health(mma)() == status.HealthOK })
+	hold()
+	h.shutdown()
+
+	// ---- replay ----
+	r := newHarness(t)
+	r.opts.ReplayDir = dir
+	r.source("meter", sim.Config{}).stop()
+	replica := r.sink("mma", ingestserver.Config{AutoCreate: true})
+	r.run(meterConfig)
+
+	eventually(t, "replayed data", func() bool { return slices.Equal(replica.holding(1, 1000, 10), meterRegs) })
+	data := watch(t, func() uint16 { return replica.holding(1, 1000, 1)[0] })
+	codes := watch(t, func() uint16 { return replica.statusBlock(100, 1)[status.SlotLastErrorCode] })
+
+	eventually(t, "replayed exception", func() bool {
+		return replica.statusBlock(100, 1)[status.SlotLastErrorCode] == 0x0B
+	})
+	eventually(t, "replayed recovery", func() bool { return health(replica)() == status.HealthOK })
+	eventually(t, "end of recording", func() bool {
+		return health(replica)() == status.HealthError && r.unit("meter").LastPoll != nil &&
+			errors.Is(r.unit("meter").LastPoll.Err, recording.ErrEndOfRecording)
+	})
+	if got := data.end(); !slices.Equal(got, []uint16{1, 77}) {
+		t.Fatalf("replayed register 1000: %v", got)
+	}
+	if got := codes.end(); len(got) < 3 || !slices.Equal(got[:3], []uint16{0, 0x0B, 0}) {
+		t.Fatalf("replayed error codes: %v", got)
+	}
+	if c := r.unit("meter").Counters; c.ResponsesValidTotal == 0 {
+		t.Fatalf("counters: %+v", c)
+	}
+}
+
+// A register and a coil flipped on the device become change events,
+// with the value they had before.
+func TestE2E_ChangeEvents(t *testing.T) {
+	h := newHarness(t)
+	src := h.source("meter", sim.Config{
+		UnitIDs: []uint8{1},
+		Memory:  []sim.SeedBlock{{Area: "holding", Address: 0, Values: meterRegs}},
+	})
+	h.sink("mma", ingestserver.Config{AutoCreate: true})
+	hub, err := events.NewHub("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Close()
+	evs, cancel := hub.Subscribe("meter")
+	defer cancel()
+	h.opts.Events = hub
+	h.run(meterConfig)
+	eventually(t, "baseline", func() bool { return h.unit("meter").LastPoll != nil && h.unit("meter").LastPoll.Err == nil })
+
+	_ = src.Memory().Set(sim.HoldingRegisters, 3, 400)
+	_ = src.Memory().Set(sim.Coils, 5, 1)
+	var got []events.Event
+	for len(got) < 2 {
+		select {
+		case e := <-evs:
+			got = append(got, e)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("events so far: %+v", got)
+		}
+	}
+	slices.SortFunc(got, func(a, b events.Event) int { return int(a.FC) - int(b.FC) })
+	if g := got[0]; g.FC != 1 || g.Address != 5 || g.Old != 0 || g.New != 1 {
+		t.Fatalf("coil event %+v", g)
+	}
+	if g := got[1]; g.Unit != "meter" || g.FC != 3 || g.Address != 3 || g.Old != 4 || g.New != 400 {
+		t.Fatalf("register event %+v", g)
+	}
+}
+
+// The meter as a Sparkplug B device next to the MMA: born with every
+// metric, changes in DDATA, dead while the source is down, reborn when
+// it returns, and dead again on shutdown.
+func TestE2E_MQTTSparkplug(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := mqtt.NewBroker(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = broker.ServeListener(ctx, ln) }()
+	msgs, unsub := broker.Subscribe("spBv1.0/plant/+/edge/meter")
+	defer unsub()
+
+	// await reads device messages until one of kind typ satisfies ok.
+	await := func(typ string, ok func(map[string]uint64) bool) {
+		t.Helper()
+		deadline := time.After(3 * time.Second)
+		for {
+			select {
+			case m := <-msgs:
+				p, err := mqttpub.DecodePayload(m.Payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				vals := make(map[string]uint64)
+				for _, x := range p.Metrics {
+					vals[x.Name] = x.Value
+				}
+				if m.Topic == "spBv1.0/plant/"+typ+"/edge/meter" && ok(vals) {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("no %s", typ)
+			}
+		}
+	}
+	always := func(map[string]uint64) bool { return true }
+
+	h := newHarness(t)
+	src := h.source("meter", sim.Config{
+		UnitIDs: []uint8{1},
+		Memory:  []sim.SeedBlock{{Area: "holding", Address: 0, Values: meterRegs}},
+	})
+	h.sink("mma", ingestserver.Config{AutoCreate: true})
+	h.opts.MQTT = mqttpub.New(mqttpub.Options{
+		Broker: ln.Addr().String(), ClientID: "edge", Timeout: time.Second, Reconnect: 10 * time.Millisecond,
+		QueueSize: 1000, Sparkplug: true, GroupID: "plant", EdgeNodeID: "edge",
+	}, nil)
+	defer h.opts.MQTT.Close()
+	h.run(meterConfig)
+
+	await("DBIRTH", func(v map[string]uint64) bool {
+		return len(v) == 22 && v["holding/9"] == 0xABCD && v["coil/0"] == 0
+	})
+
+	_ = src.Memory().Set(sim.HoldingRegisters, 0, 500)
+	await("DDATA", func(v map[string]uint64) bool { return len(v) == 1 && v["holding/0"] == 500 })
+
+	src.stop()
+	await("DDEATH", always)
+	src.start()
+	await("DBIRTH", func(v map[string]uint64) bool { return v["holding/0"] == 500 })
+
+	h.shutdown()
+	await("DDEATH", always)
+}
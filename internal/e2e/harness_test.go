@@ -0,0 +1,304 @@
+// internal/e2e/harness_test.go
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/ingestserver"
+	"github.com/tamzrod/modbus-replicator/internal/runner"
+	"github.com/tamzrod/modbus-replicator/internal/sim"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+	"github.com/tamzrod/modbus-replicator/internal/supervisor"
+)
+
+// harness owns every moving part of one scenario. Cleanup stops the
+// replicator first, then the servers; logs are printed if the test
+// failed.
+type harness struct {
+	t       *testing.T
+	log     *slog.Logger
+	logs    *syncBuffer
+	sources map[string]*source
+	sinks   map[string]*sink
+	sup     *supervisor.Supervisor
+
+	// opts are the build options run starts the units with.
+	opts supervisor.BuildOptions
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	logs := &syncBuffer{}
+	h := &harness{
+		t:       t,
+		log:     slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		logs:    logs,
+		sources: map[string]*source{},
+		sinks:   map[string]*sink{},
+	}
+	t.Cleanup(func() {
+		h.shutdown()
+		for _, s := range h.sources {
+			s.stop()
+		}
+		for _, s := range h.sinks {
+			s.stop()
+		}
+		if t.Failed() {
+			t.Logf("replicator log:\n%s", logs.String())
+		}
+	})
+	return h
+}
+
+// ---- servers ----
+
+// endpoint is a restartable server on a fixed loopback port: stop is
+// an outage (listener and connections closed), start brings the same
+// server back on the same address.
+type endpoint struct {
+	t     *testing.T
+	addr  string
+	serve func(context.Context, net.Listener) error
+
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func (e *endpoint) start() {
+	e.t.Helper()
+	if e.cancel != nil {
+		e.t.Fatalf("%s already running", e.addr)
+	}
+	addr := e.addr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	e.addr = ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel, e.done = cancel, make(chan error, 1)
+	go func() { e.done <- e.serve(ctx, ln) }()
+}
+
+func (e *endpoint) stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	if err := <-e.done; err != nil {
+		e.t.Errorf("%s: %v", e.addr, err)
+	}
+	e.cancel = nil
+}
+
+type source struct {
+	*sim.Server
+	endpoint
+}
+
+// source starts a simulated Modbus TCP device named for templates.
+func (h *harness) source(name string, cfg sim.Config) *source {
+	h.t.Helper()
+	srv, err := sim.New(cfg, h.log.With("sim", name))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	s := &source{Server: srv, endpoint: endpoint{t: h.t, serve: srv.ServeListener}}
+	s.start()
+	h.sources[name] = s
+	return s
+}
+
+type sink struct {
+	*ingestserver.Server
+	endpoint
+}
+
+// sink starts a Raw Ingest receiver named for templates.
+func (h *harness) sink(name string, cfg ingestserver.Config) *sink {
+	h.t.Helper()
+	srv, err := ingestserver.New(cfg, h.log.With("sink", name))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	s := &sink{Server: srv, endpoint: endpoint{t: h.t, serve: srv.ServeListener}}
+	s.start()
+	h.sinks[name] = s
+	return s
+}
+
+// holding reads n holding registers of unit, zeros if it has no memory.
+func (s *sink) holding(unit, addr, n uint16) []uint16 {
+	m := s.Memory(unit)
+	if m == nil {
+		return make([]uint16, n)
+	}
+	return m.Get(sim.HoldingRegisters, addr, n)
+}
+
+// statusBlock reads the 30-slot block of slot on status unit id unit.
+func (s *sink) statusBlock(unit, slot uint16) []uint16 {
+	return s.holding(unit, slot*status.SlotsPerDevice, status.SlotsPerDevice)
+}
+
+// ---- replicator ----
+
+// run renders tmpl ({{source "name"}} and {{sink "name"}} give
+// addresses), loads it through the normal config pipeline and starts
+// every unit under a supervisor.
+func (h *harness) run(tmpl string) *config.Config {
+	h.t.Helper()
+	tp, err := template.New("config").Funcs(template.FuncMap{
+		"source": func(name string) (string, error) {
+			if s := h.sources[name]; s != nil {
+				return s.addr, nil
+			}
+			return "", fmt.Errorf("no source %q", name)
+		},
+		"sink": func(name string) (string, error) {
+			if s := h.sinks[name]; s != nil {
+				return s.addr, nil
+			}
+			return "", fmt.Errorf("no sink %q", name)
+		},
+	}).Parse(tmpl)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := tp.Execute(&b, nil); err != nil {
+		h.t.Fatal(err)
+	}
+
+	path := filepath.Join(h.t.TempDir(), "replicator.yaml")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		h.t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		h.t.Fatalf("generated config: %v\n%s", err, b.String())
+	}
+
+	h.sup = supervisor.New(h.opts.Builder(), h.log)
+	if rep := h.sup.Apply(cfg, "startup"); rep.Err != "" {
+		h.t.Fatalf("apply: %s", rep.Err)
+	}
+	return cfg
+}
+
+// shutdown drains the replicator (final shutdown status included).
+func (h *harness) shutdown() {
+	if h.sup != nil {
+		h.sup.Shutdown()
+		h.sup = nil
+	}
+}
+
+// unit is the runner's observer view of one unit.
+func (h *harness) unit(id string) runner.Info {
+	h.t.Helper()
+	for _, u := range h.sup.Units() {
+		if u.UnitID == id {
+			return u
+		}
+	}
+	h.t.Fatalf("no unit %q", id)
+	return runner.Info{}
+}
+
+// ---- assertions ----
+
+// eventually polls cond until it holds or 3s pass.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// timeline samples a value and keeps every change: the order of states
+// a SCADA poller reading the target would have seen.
+type timeline struct {
+	mu     sync.Mutex
+	values []uint16
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func watch(t *testing.T, sample func() uint16) *timeline {
+	tl := &timeline{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(tl.done)
+		tick := time.NewTicker(5 * time.Millisecond)
+		defer tick.Stop()
+		record := func() {
+			v := sample()
+			tl.mu.Lock()
+			defer tl.mu.Unlock()
+			if n := len(tl.values); n == 0 || tl.values[n-1] != v {
+				tl.values = append(tl.values, v)
+			}
+		}
+		for {
+			record()
+			select {
+			case <-tl.stop:
+				record() // the state the caller just waited for
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+	t.Cleanup(func() { tl.end() })
+	return tl
+}
+
+// end stops sampling and returns the distinct values seen, in order.
+func (tl *timeline) end() []uint16 {
+	select {
+	case <-tl.stop:
+	default:
+		close(tl.stop)
+	}
+	<-tl.done
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return append([]uint16(nil), tl.values...)
+}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
@@ -0,0 +1,131 @@
+// internal/events/detect.go
+package events
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+)
+
+// Event is one change of value. Bits (FC 1/2) are 0 or 1.
+type Event struct {
+	Unit    string    `json:"unit"`
+	At      time.Time `json:"at"`
+	FC      uint8     `json:"fc"`
+	Address uint16    `json:"address"`
+	Old     uint16    `json:"old"`
+	New     uint16    `json:"new"`
+}
+
+// Sink receives the events of one poll, in address order per block.
+type Sink interface {
+	Publish(evs []Event) error
+}
+
+// Detector compares successive poll results of one unit. It is a
+// writer.Writer output; it never touches what targets receive.
+//
+// The first good poll is the baseline and reports nothing. Failed polls
+// are skipped: the poll after an outage is compared with the values
+// known before it. Values are compared with the last *reported* value,
+// so slow drift inside a deadband is reported once it adds up.
+type Detector struct {
+	unit string
+	sink Sink
+
+	// deadbands by block; nil reports every change.
+	deadbands map[blockKey]*config.DeadbandConfig
+
+	mu   sync.Mutex
+	last map[blockKey][]uint16
+}
+
+type blockKey struct {
+	fc       uint8
+	address  uint16
+	quantity uint16
+}
+
+// NewDetector builds the detector of unit with the deadbands of reads.
+func NewDetector(unit string, reads []config.ReadConfig, sink Sink) *Detector {
+	d := &Detector{
+		unit:      unit,
+		sink:      sink,
+		deadbands: make(map[blockKey]*config.DeadbandConfig),
+		last:      make(map[blockKey][]uint16),
+	}
+	for _, r := range reads {
+		if r.Deadband != nil {
+			db := *r.Deadband
+			d.deadbands[blockKey{r.FC, r.Address, r.Quantity}] = &db
+		}
+	}
+	return d
+}
+
+// Write reports the changes since the last reported values.
+func (d *Detector) Write(res poller.PollResult) error {
+	if res.Err != nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	var evs []Event
+	for _, b := range res.Blocks {
+		k := blockKey{b.FC, b.Address, b.Quantity}
+		cur := b.Registers
+		if b.Bits != nil {
+			cur = make([]uint16, len(b.Bits))
+			for i, bit := range b.Bits {
+				if bit {
+					cur[i] = 1
+				}
+			}
+		}
+
+		last, ok := d.last[k]
+		if !ok || len(last) != len(cur) {
+			d.last[k] = append([]uint16(nil), cur...)
+			continue
+		}
+		db := d.deadbands[k]
+		for i, v := range cur {
+			if v == last[i] || !exceeds(db, last[i], v) {
+				continue
+			}
+			evs = append(evs, Event{
+				Unit:    d.unit,
+				At:      res.At,
+				FC:      b.FC,
+				Address: b.Address + uint16(i),
+				Old:     last[i],
+				New:     v,
+			})
+			last[i] = v
+		}
+	}
+	d.mu.Unlock()
+
+	if len(evs) == 0 {
+		return nil
+	}
+	return d.sink.Publish(evs)
+}
+
+// exceeds reports whether old → v moved past every limit of db.
+func exceeds(db *config.DeadbandConfig, old, v uint16) bool {
+	if db == nil {
+		return true
+	}
+	delta := math.Abs(float64(v) - float64(old))
+	if delta <= db.Absolute {
+		return false
+	}
+	if db.Percent > 0 && delta*100 <= db.Percent*float64(old) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+// internal/events/events_test.go
+package events
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+)
+
+type sliceSink struct{ evs []Event }
+
+func (s *sliceSink) Publish(evs []Event) error {
+	s.evs = append(s.evs, evs...)
+	return nil
+}
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func res(sec int, coils []bool, regs ...uint16) poller.PollResult {
+	return poller.PollResult{
+		UnitID: "inv-1",
+		At:     t0.Add(time.Duration(sec) * time.Second),
+		Blocks: []poller.BlockResult{
+			{FC: 1, Address: 10, Quantity: uint16(len(coils)), Bits: coils},
+			{FC: 3, Address: 100, Quantity: uint16(len(regs)), Registers: regs},
+		},
+	}
+}
+
+// short renders events as "fc/address old>new".
+func short(evs []Event) []string {
+	var out []string
+	for _, e := range evs {
+		out = append(out, fmt.Sprintf("%d/%d %d>%d", e.FC, e.Address, e.Old, e.New))
+	}
+	return out
+}
+
+func reads(db *config.DeadbandConfig) []config.ReadConfig {
+	return []config.ReadConfig{
+		{FC: 1, Address: 10, Quantity: 3},
+		{FC: 3, Address: 100, Quantity: 2, Deadband: db},
+	}
+}
+
+func TestDetector_BitsAndRegisters(t *testing.T) {
+	var sink sliceSink
+	d := NewDetector("inv-1", reads(nil), &sink)
+
+	steps := []poller.PollResult{
+		res(0, []bool{false, true, false}, 5, 7), // baseline
+		res(1, []bool{false, true, false}, 5, 7), // nothing changed
+		res(2, []bool{true, false, false}, 5, 8),
+		{UnitID: "inv-1", At: t0.Add(3 * time.Second), Err: errors.New("timeout")},
+		res(4, []bool{true, false, false}, 6, 8), // compared with before the outage
+	}
+	for _, r := range steps {
+		if err := d.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"1/10 0>1", "1/11 1>0", "3/101 7>8", "3/100 5>6"}
+	if got := short(sink.evs); !slices.Equal(got, want) {
+		t.Fatalf("events %v, want %v", got, want)
+	}
+	if e := sink.evs[2]; e.Unit != "inv-1" || !e.At.Equal(t0.Add(2*time.Second)) {
+		t.Fatalf("event identity: %+v", e)
+	}
+}
+
+func TestDetector_Deadbands(t *testing.T) {
+	cases := []struct {
+		name string
+		db   config.DeadbandConfig
+		regs []uint16 // register 100 per poll; the first is the baseline
+		want []string
+	}{
+		{"absolute", config.DeadbandConfig{Absolute: 5}, []uint16{100, 103, 105, 106, 100}, []string{"3/100 100>106", "3/100 106>100"}},
+		{"drift adds up", config.DeadbandConfig{Absolute: 5}, []uint16{100, 102, 104, 106}, []string{"3/100 100>106"}},
+		{"percent", config.DeadbandConfig{Percent: 10}, []uint16{1000, 1090, 1101, 1000}, []string{"3/100 1000>1101"}},
+		{"percent from zero", config.DeadbandConfig{Percent: 10}, []uint16{0, 1}, []string{"3/100 0>1"}},
+		{"both", config.DeadbandConfig{Absolute: 2, Percent: 50}, []uint16{4, 7, 10, 2}, []string{"3/100 4>7", "3/100 7>2"}},
+		{"downwards", config.DeadbandConfig{Absolute: 10}, []uint16{65535, 65530, 65000}, []string{"3/100 65535>65000"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sink sliceSink
+			d := NewDetector("inv-1", reads(&tc.db), &sink)
+			for i, v := range tc.regs {
+				_ = d.Write(res(i, []bool{false, false, false}, v, 0))
+			}
+			if got := short(sink.evs); !slices.Equal(got, tc.want) {
+				t.Fatalf("events %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHub_LogAndStream(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "events.jsonl")
+	h, err := NewHub(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?unit=inv-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+
+	evs := []Event{
+		{Unit: "inv-1", At: t0, FC: 3, Address: 1, Old: 1, New: 2},
+		{Unit: "inv-2", At: t0, FC: 1, Address: 7, Old: 0, New: 1},
+	}
+	// The subscription is registered once the headers are out.
+	if err := h.Publish(evs); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `data: {"unit":"inv-2","at":"2026-03-01T12:00:00Z","fc":1,"address":7,"old":0,"new":1}` + "\n"
+	if line != want {
+		t.Fatalf("stream: %q\nwant   %q", line, want)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Publish(evs); err != nil { // after Close: ignored
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"unit":"inv-1"`) {
+		t.Fatalf("log:\n%s", b)
+	}
+}
+
+func TestHub_EndStreamsKeepsLogging(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "events.jsonl")
+	h, err := NewHub(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	h.EndStreams()
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatalf("stream did not end cleanly: %v", err)
+	}
+
+	if err := h.Publish([]Event{{Unit: "inv-1", At: t0, FC: 3, Address: 1, Old: 1, New: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"unit":"inv-1"`) {
+		t.Fatalf("log after EndStreams:\n%s", b)
+	}
+}
@@ -0,0 +1,180 @@
+// internal/events/hub.go
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Hub fans events out to the stream subscribers and the log.
+// Subscribers that fall behind lose events rather than slowing a poll.
+type Hub struct {
+	mu      sync.Mutex
+	log     *os.File
+	subs    map[*subscriber]struct{}
+	dropped uint64
+	closed  bool
+
+	done chan struct{}
+}
+
+type subscriber struct {
+	unit string // "" => every unit
+	ch   chan Event
+}
+
+// subscriberBuffer is how many events a slow stream may lag behind.
+const subscriberBuffer = 1024
+
+// keepAliveEvery sends an SSE comment so idle proxies keep the stream.
+const keepAliveEvery = 15 * time.Second
+
+// NewHub returns a hub appending to logPath ("" => no log).
+func NewHub(logPath string) (*Hub, error) {
+	h := &Hub{subs: make(map[*subscriber]struct{}), done: make(chan struct{})}
+	if logPath != "" {
+		f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("events log: %w", err)
+		}
+		h.log = f
+	}
+	return h, nil
+}
+
+// Publish logs evs and hands them to every subscriber.
+// The error is the log's; subscribers never fail a publish.
+func (h *Hub) Publish(evs []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range evs {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+
+	var err error
+	if h.log != nil {
+		// One write per poll: concurrent units never interleave lines.
+		if _, werr := h.log.Write(buf.Bytes()); werr != nil {
+			err = fmt.Errorf("events log: %w", werr)
+		}
+	}
+	for s := range h.subs {
+		for _, e := range evs {
+			if s.unit != "" && s.unit != e.Unit {
+				continue
+			}
+			select {
+			case s.ch <- e:
+			default:
+				h.dropped++
+			}
+		}
+	}
+	return err
+}
+
+// Subscribe streams the events of unit ("" => all). cancel ends it.
+func (h *Hub) Subscribe(unit string) (<-chan Event, func()) {
+	s := &subscriber{unit: unit, ch: make(chan Event, subscriberBuffer)}
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s.ch, func() {
+		h.mu.Lock()
+		delete(h.subs, s)
+		h.mu.Unlock()
+	}
+}
+
+// Dropped counts events lost to slow subscribers.
+func (h *Hub) Dropped() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.dropped
+}
+
+// EndStreams ends every open stream and refuses new ones; the log keeps
+// recording. http.Server.Shutdown waits for streams to return, so the
+// API calls this as it shuts down.
+func (h *Hub) EndStreams() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.endStreams()
+}
+
+func (h *Hub) endStreams() {
+	select {
+	case <-h.done:
+	default:
+		close(h.done)
+	}
+}
+
+// Close ends every stream and closes the log.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	h.endStreams()
+	if h.log != nil {
+		return h.log.Close()
+	}
+	return nil
+}
+
+// ServeHTTP is the Server-Sent Events stream: one `data:` line of JSON
+// per event, optionally only for ?unit=ID. Nothing is replayed; a client
+// sees the changes from the moment it connects.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	ch, cancel := h.Subscribe(r.URL.Query().Get("unit"))
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return // not streamable
+	}
+
+	tick := time.NewTicker(keepAliveEvery)
+	defer tick.Stop()
+	for {
+		var err error
+		select {
+		case e := <-ch:
+			var b []byte
+			if b, err = json.Marshal(e); err == nil {
+				_, err = fmt.Fprintf(w, "data: %s\n\n", b)
+			}
+		case <-tick.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return // client gone
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// internal/historian/historian.go
+package historian
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+)
+
+// Files are CSV, one directory per unit and one file per period (UTC):
+//
+//	<dir>/<unit>/2026-03-01.csv      rotate: daily
+//	<dir>/<unit>/2026-03-01T14.csv   rotate: hourly
+//
+// with rows
+//
+//	time,fc,address,value
+//	2026-03-01T14:00:00.250Z,3,100,1234
+//	2026-03-01T14:00:07.250Z,,,
+//
+// A value is only written when it changed since the previous poll, and
+// every file starts with the full set, so each file stands on its own.
+// A row with empty fc, address and value marks a failed poll: from then
+// on nothing is known until the next row of that address.
+const header = "time,fc,address,value\n"
+
+// TimeFormat is the time column: RFC 3339, UTC, milliseconds.
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+const (
+	dailyName  = "2006-01-02"
+	hourlyName = "2006-01-02T15"
+)
+
+// Options are the historian settings shared by every unit.
+type Options struct {
+	Dir       string
+	Hourly    bool          // rotate every hour instead of every day
+	Retention time.Duration // 0 keeps files forever
+}
+
+// FromConfig converts the validated config section.
+func FromConfig(c config.HistorianConfig) Options {
+	return Options{
+		Dir:       c.Dir,
+		Hourly:    c.Rotate == "hourly",
+		Retention: time.Duration(c.RetentionDays) * 24 * time.Hour,
+	}
+}
+
+func (o Options) period() (time.Duration, string) {
+	if o.Hourly {
+		return time.Hour, hourlyName
+	}
+	return 24 * time.Hour, dailyName
+}
+
+// key identifies one value: fc and address.
+type key struct {
+	fc   uint8
+	addr uint16
+}
+
+// Writer stores one unit's poll results. It implements writer.Writer and
+// is driven by the runner goroutine; Close may come from another.
+//
+// The file is opened on the first result, and a new one at each period
+// boundary of the results' own timestamps. Expired files of the unit are
+// deleted on every rotation.
+type Writer struct {
+	opt  Options
+	dir  string
+	unit string
+
+	mu     sync.Mutex
+	f      *os.File
+	start  time.Time // period of f
+	prev   map[key]uint16
+	gap    bool
+	closed bool
+}
+
+// NewWriter returns the historian of unit. Nothing is opened yet.
+func NewWriter(opt Options, unit string) *Writer {
+	return &Writer{opt: opt, dir: filepath.Join(opt.Dir, unit), unit: unit}
+}
+
+// Write appends the changes in res.
+func (w *Writer) Write(res poller.PollResult) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errors.New("historian: closed")
+	}
+	at := res.At.UTC()
+	if err := w.rotate(at); err != nil {
+		return err
+	}
+
+	ts := at.Format(TimeFormat)
+	var b []byte
+	if res.Err != nil {
+		if w.gap {
+			return nil
+		}
+		b = append(b, ts...)
+		b = append(b, ",,,\n"...)
+		w.gap, w.prev = true, nil
+	} else {
+		next := make(map[key]uint16, len(w.prev))
+		for _, blk := range res.Blocks {
+			for i := 0; i < int(blk.Quantity); i++ {
+				k := key{blk.FC, blk.Address + uint16(i)}
+				v, ok := value(blk, i)
+				if !ok {
+					continue
+				}
+				next[k] = v
+				if old, seen := w.prev[k]; seen && old == v {
+					continue
+				}
+				b = appendRow(b, ts, k, v)
+			}
+		}
+		w.gap, w.prev = false, next
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	_, err := w.f.Write(b)
+	return err
+}
+
+func value(blk poller.BlockResult, i int) (uint16, bool) {
+	switch {
+	case blk.FC == 1 || blk.FC == 2:
+		if i >= len(blk.Bits) {
+			return 0, false
+		}
+		if blk.Bits[i] {
+			return 1, true
+		}
+		return 0, true
+	case i < len(blk.Registers):
+		return blk.Registers[i], true
+	}
+	return 0, false
+}
+
+func appendRow(b []byte, ts string, k key, v uint16) []byte {
+	b = append(b, ts...)
+	b = append(b, ',')
+	b = strconv.AppendUint(b, uint64(k.fc), 10)
+	b = append(b, ',')
+	b = strconv.AppendUint(b, uint64(k.addr), 10)
+	b = append(b, ',')
+	b = strconv.AppendUint(b, uint64(v), 10)
+	return append(b, '\n')
+}
+
+// rotate makes f the file of at's period.
+func (w *Writer) rotate(at time.Time) error {
+	length, layout := w.opt.period()
+	start := at.Truncate(length)
+	if w.f != nil && start.Equal(w.start) {
+		return nil
+	}
+	if w.f != nil {
+		_ = w.f.Close()
+		w.f = nil
+	}
+	if err := os.MkdirAll(w.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(w.dir, start.Format(layout)+".csv"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if st, err := f.Stat(); err == nil && st.Size() == 0 {
+		if _, err := f.WriteString(header); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	w.f, w.start = f, start
+	w.prev, w.gap = nil, false // a new file starts with the full set
+
+	if w.opt.Retention > 0 {
+		return prune(w.dir, at.Add(-w.opt.Retention))
+	}
+	return nil
+}
+
+// Close closes the current file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// ---- files ----
+
+// file is one period file of a unit.
+type file struct {
+	path       string
+	start, end time.Time
+}
+
+// files lists the period files in dir, oldest first. Other files are
+// ignored.
+func files(dir string) ([]file, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []file
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".csv")
+		if !ok || e.IsDir() {
+			continue
+		}
+		f := file{path: filepath.Join(dir, e.Name())}
+		if t, err := time.Parse(hourlyName, name); err == nil {
+			f.start, f.end = t, t.Add(time.Hour)
+		} else if t, err := time.Parse(dailyName, name); err == nil {
+			f.start, f.end = t, t.Add(24*time.Hour)
+		} else {
+			continue
+		}
+		out = append(out, f)
+	}
+	slices.SortFunc(out, func(a, b file) int { return a.start.Compare(b.start) })
+	return out, nil
+}
+
+// prune deletes the files in dir whose period ended before cutoff.
+func prune(dir string, cutoff time.Time) error {
+	fs, err := files(dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range fs {
+		if !f.end.After(cutoff) {
+			if err := os.Remove(f.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("historian: retention: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// internal/historian/historian_test.go
+package historian
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+)
+
+var t0 = time.Date(2026, 3, 1, 23, 59, 58, 0, time.UTC)
+
+func poll(at time.Duration, coil bool, regs ...uint16) poller.PollResult {
+	return poller.PollResult{
+		UnitID: "meter",
+		At:     t0.Add(at),
+		Blocks: []poller.BlockResult{
+			{FC: 1, Address: 0, Quantity: 1, Bits: []bool{coil}},
+			{FC: 3, Address: 100, Quantity: uint16(len(regs)), Registers: regs},
+		},
+	}
+}
+
+func failed(at time.Duration) poller.PollResult {
+	return poller.PollResult{UnitID: "meter", At: t0.Add(at), Err: errors.New("i/o timeout")}
+}
+
+func write(t *testing.T, w *Writer, results ...poller.PollResult) {
+	t.Helper()
+	for _, res := range results {
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// Changes only, a gap row per outage, a full set at each new file.
+func TestWriter_ChangesAndDailyRotation(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(Options{Dir: dir}, "meter")
+	write(t, w,
+		poll(0, true, 10, 20),
+		poll(500*time.Millisecond, true, 10, 20),
+		poll(time.Second, false, 10, 21),
+		failed(1500*time.Millisecond),
+		failed(1600*time.Millisecond),
+		poll(2*time.Second, false, 10, 21), // next day
+		poll(3*time.Second, false, 11, 21),
+	)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	day1 := header +
+		"2026-03-01T23:59:58.000Z,1,0,1\n" +
+		"2026-03-01T23:59:58.000Z,3,100,10\n" +
+		"2026-03-01T23:59:58.000Z,3,101,20\n" +
+		"2026-03-01T23:59:59.000Z,1,0,0\n" +
+		"2026-03-01T23:59:59.000Z,3,101,21\n" +
+		"2026-03-01T23:59:59.500Z,,,\n"
+	if got := read(t, filepath.Join(dir, "meter", "2026-03-01.csv")); got != day1 {
+		t.Fatalf("day 1:\n%s\nwant:\n%s", got, day1)
+	}
+	day2 := header +
+		"2026-03-02T00:00:00.000Z,1,0,0\n" +
+		"2026-03-02T00:00:00.000Z,3,100,10\n" +
+		"2026-03-02T00:00:00.000Z,3,101,21\n" +
+		"2026-03-02T00:00:01.000Z,3,100,11\n"
+	if got := read(t, filepath.Join(dir, "meter", "2026-03-02.csv")); got != day2 {
+		t.Fatalf("day 2:\n%s\nwant:\n%s", got, day2)
+	}
+
+	if err := w.Write(poll(4*time.Second, false, 1, 2)); err == nil {
+		t.Fatal("write after Close")
+	}
+}
+
+func TestWriter_HourlyRetention(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(Options{Dir: dir, Hourly: true, Retention: 3 * time.Hour}, "meter")
+	defer w.Close()
+
+	// A foreign file is left alone.
+	if err := os.MkdirAll(filepath.Join(dir, "meter"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meter", "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for h := 0; h < 6; h++ {
+		write(t, w, poll(time.Duration(h)*time.Hour, true, uint16(h)))
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "meter"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	// Last poll at 04:59:58: files that ended by 01:59:58 are gone.
+	want := "2026-03-02T01.csv 2026-03-02T02.csv 2026-03-02T03.csv 2026-03-02T04.csv notes.txt"
+	if got := strings.Join(names, " "); got != want {
+		t.Fatalf("files: %s\nwant:  %s", got, want)
+	}
+}
+
+func TestWriter_AppendsAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(Options{Dir: dir}, "meter")
+	write(t, w, poll(0, true, 10))
+	w.Close()
+
+	w = NewWriter(Options{Dir: dir}, "meter")
+	write(t, w, poll(time.Second, true, 10))
+	w.Close()
+
+	// One header; the reopened file starts with the full set again.
+	got := read(t, filepath.Join(dir, "meter", "2026-03-01.csv"))
+	if strings.Count(got, "time,") != 1 || strings.Count(got, ",3,100,10\n") != 2 {
+		t.Fatalf("after restart:\n%s", got)
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(Options{Dir: dir}, "meter")
+	write(t, w,
+		poll(0, true, 10, 20),
+		poll(time.Second, true, 11, 20),
+		failed(1500*time.Millisecond),
+		poll(2*time.Second, true, 11, 22), // next day
+		poll(3*time.Second, true, 12, 22),
+	)
+	w.Close()
+
+	// A torn row from a crash is skipped.
+	f, err := os.OpenFile(filepath.Join(dir, "meter", "2026-03-01.csv"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("2026-03-01T23:59:59.900Z,3,1")
+	f.Close()
+
+	cases := []struct {
+		name string
+		q    Query
+		want string
+	}{
+		{
+			name: "everything",
+			q:    Query{Unit: "meter", FC: 3, First: 100, Last: 101},
+			want: "2026-03-01T23:59:58.000Z,100,10\n" +
+				"2026-03-01T23:59:58.000Z,101,20\n" +
+				"2026-03-01T23:59:59.000Z,100,11\n" +
+				"2026-03-01T23:59:59.500Z,,\n" +
+				"2026-03-02T00:00:00.000Z,100,11\n" +
+				"2026-03-02T00:00:00.000Z,101,22\n" +
+				"2026-03-02T00:00:01.000Z,100,12\n",
+		},
+		{
+			name: "value in effect at from",
+			q:    Query{Unit: "meter", FC: 3, First: 100, Last: 100, From: t0.Add(1200 * time.Millisecond), To: t0.Add(3 * time.Second)},
+			want: "2026-03-01T23:59:59.200Z,100,11\n" +
+				"2026-03-01T23:59:59.500Z,,\n" +
+				"2026-03-02T00:00:00.000Z,100,11\n",
+		},
+		{
+			name: "from inside an outage",
+			q:    Query{Unit: "meter", FC: 3, First: 101, Last: 101, From: t0.Add(1700 * time.Millisecond), To: t0.Add(time.Hour)},
+			want: "2026-03-02T00:00:00.000Z,101,22\n",
+		},
+		{
+			name: "coils",
+			q:    Query{Unit: "meter", FC: 1, First: 0, Last: 0, From: t0.Add(time.Hour)},
+			want: "2026-03-02T00:59:58.000Z,0,1\n",
+		},
+	}
+	for _, c := range cases {
+		var b strings.Builder
+		n, err := Export(dir, c.q, &b)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if want := "time,address,value\n" + c.want; b.String() != want || n != strings.Count(c.want, "\n") {
+			t.Fatalf("%s: %d rows\n%s\nwant:\n%s", c.name, n, b.String(), want)
+		}
+	}
+
+	if _, err := Export(dir, Query{Unit: "nope", FC: 3}, &strings.Builder{}); err == nil {
+		t.Fatal("exported an unknown unit")
+	}
+}
@@ -0,0 +1,150 @@
+// internal/historian/query.go
+package historian
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query selects one unit's values of one function code, over an
+// address range and a time window.
+type Query struct {
+	Unit        string
+	FC          uint8
+	First, Last uint16    // inclusive
+	From, To    time.Time // [From, To); zero From means from the start
+}
+
+// Export writes the values selected by q as CSV rows
+// "time,address,value", in time order:
+//
+//   - at From, the value each address had then (if it was known)
+//   - every change inside the window
+//   - a row with empty address and value where a poll failed: every
+//     address is unknown until its next row
+//
+// It returns the number of rows written. Lines that do not parse (a
+// row torn by a crash) are skipped.
+func Export(dir string, q Query, w io.Writer) (int, error) {
+	fs, err := files(filepath.Join(dir, q.Unit))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, errors.New("historian: no history for unit " + strconv.Quote(q.Unit))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	out := bufio.NewWriter(w)
+	ex := exporter{q: q, w: out, known: map[uint16]uint16{}}
+	ex.row("time", "address", "value")
+	for _, f := range fs {
+		// Every file starts with the full set, so files that ended
+		// before From do not matter.
+		if !f.end.After(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !f.start.Before(q.To) {
+			break
+		}
+		if err := ex.file(f.path); err != nil {
+			return ex.rows, err
+		}
+	}
+	ex.reachFrom()
+	return ex.rows, out.Flush()
+}
+
+type exporter struct {
+	q     Query
+	w     *bufio.Writer
+	rows  int
+	known map[uint16]uint16 // values before From
+	live  bool              // past From: rows go straight out
+}
+
+func (e *exporter) row(t, addr, v string) {
+	e.w.WriteString(t + "," + addr + "," + v + "\n")
+}
+
+func (e *exporter) file(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if line+"\n" == header {
+			continue
+		}
+		parts := strings.Split(line, ",")
+		if len(parts) != 4 {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, parts[0])
+		if err != nil {
+			continue
+		}
+		if !e.q.To.IsZero() && !at.Before(e.q.To) {
+			return nil
+		}
+		if !at.Before(e.q.From) {
+			e.reachFrom()
+		}
+
+		if parts[1] == "" && parts[2] == "" && parts[3] == "" {
+			e.gap(parts[0])
+			continue
+		}
+		fc, err1 := strconv.ParseUint(parts[1], 10, 8)
+		addr, err2 := strconv.ParseUint(parts[2], 10, 16)
+		v, err3 := strconv.ParseUint(parts[3], 10, 16)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		if uint8(fc) != e.q.FC || uint16(addr) < e.q.First || uint16(addr) > e.q.Last {
+			continue
+		}
+		if e.live {
+			e.row(parts[0], parts[2], parts[3])
+			e.rows++
+			continue
+		}
+		e.known[uint16(addr)] = uint16(v)
+	}
+	return sc.Err()
+}
+
+// gap handles a failed poll at ts.
+func (e *exporter) gap(ts string) {
+	if e.live {
+		e.row(ts, "", "")
+		e.rows++
+		return
+	}
+	clear(e.known)
+}
+
+// reachFrom emits the values known at From, once.
+func (e *exporter) reachFrom() {
+	if e.live {
+		return
+	}
+	e.live = true
+	ts := e.q.From.UTC().Format(TimeFormat)
+	for a := int(e.q.First); a <= int(e.q.Last); a++ {
+		if v, ok := e.known[uint16(a)]; ok {
+			e.row(ts, strconv.Itoa(a), strconv.Itoa(int(v)))
+			e.rows++
+		}
+	}
+	e.known = nil
+}
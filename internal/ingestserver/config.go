@@ -0,0 +1,86 @@
+// internal/ingestserver/config.go
+package ingestserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults for an empty Config.
+const (
+	DefaultListen  = ":1502"
+	DefaultTimeout = 2000 // ms
+)
+
+// Config describes the receiver: its memories and where it listens.
+type Config struct {
+	// Listen is the Raw Ingest host:port. Default ":1502".
+	Listen string `yaml:"listen,omitempty"`
+
+	// ModbusListen serves the memories over Modbus TCP (FC 1-4, unit id
+	// = ingest unit id). Empty disables.
+	ModbusListen string `yaml:"modbus_listen,omitempty"`
+
+	// TimeoutMs bounds reading one packet. Default 2000.
+	TimeoutMs int `yaml:"timeout_ms,omitempty"`
+
+	// Units are the allocated memories. Packets for any other unit id
+	// are rejected, unless AutoCreate is set.
+	Units []UnitConfig `yaml:"units,omitempty"`
+
+	// AutoCreate allocates a full 64k memory for an unknown unit id on
+	// its first packet, instead of rejecting it.
+	AutoCreate bool `yaml:"auto_create,omitempty"`
+}
+
+// UnitConfig is one memory: the number of addresses of each area,
+// from 0. An area of size 0 rejects every write.
+type UnitConfig struct {
+	ID       uint16 `yaml:"id"`
+	Coils    int    `yaml:"coils,omitempty"`
+	Discrete int    `yaml:"discrete,omitempty"`
+	Holding  int    `yaml:"holding,omitempty"`
+	Input    int    `yaml:"input,omitempty"`
+}
+
+// LoadConfig reads a receiver config.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks sizes and duplicate unit ids.
+func (c *Config) Validate() error {
+	if c.TimeoutMs < 0 {
+		return errors.New("timeout_ms: must be >= 0")
+	}
+	seen := map[uint16]bool{}
+	for i, u := range c.Units {
+		if seen[u.ID] {
+			return fmt.Errorf("units[%d].id: %d defined twice", i, u.ID)
+		}
+		seen[u.ID] = true
+		for _, n := range []int{u.Coils, u.Discrete, u.Holding, u.Input} {
+			if n < 0 || n > 65536 {
+				return fmt.Errorf("units[%d]: area size %d outside 0-65536", i, n)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+// internal/ingestserver/server.go
+package ingestserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/logging"
+	"github.com/tamzrod/modbus-replicator/internal/sim"
+)
+
+// Raw Ingest v1 constants (docs/raw_ingest_v_1_spec.md).
+const (
+	headerSize = 10
+
+	magic0    byte = 0x52 // 'R'
+	magic1    byte = 0x49 // 'I'
+	versionV1 byte = 0x01
+
+	respOK       byte = 0x00
+	respRejected byte = 0x01
+)
+
+// PayloadSize derives the payload length from Area and Count: v1 has
+// no length field. Areas 1-2 are bit-packed, 3-4 are 16-bit registers.
+func PayloadSize(area byte, count uint16) (int, error) {
+	switch area {
+	case 1, 2:
+		return (int(count) + 7) / 8, nil
+	case 3, 4:
+		return int(count) * 2, nil
+	}
+	return 0, fmt.Errorf("area 0x%02x", area)
+}
+
+// Packet is one decoded Raw Ingest v1 packet.
+type Packet struct {
+	Area    byte
+	UnitID  uint16
+	Address uint16
+	Count   uint16
+	Payload []byte
+}
+
+// Stats counts packets by outcome. Snapshot via Server.Stats.
+type Stats struct {
+	Accepted int
+	Rejected int
+}
+
+// Server is a Raw Ingest v1 receiver: a stand-in for the MMA.
+//
+// One connection carries one packet. The packet is checked whole
+// (header, derived payload size, unit, bounds) before any byte is
+// written; the reply is a single status byte, then the connection
+// closes. Nothing is partially applied.
+type Server struct {
+	cfg Config
+	log *slog.Logger
+
+	modbus *sim.Server // serves the same memories over Modbus TCP
+
+	mu     sync.Mutex
+	units  map[uint16]*sim.Memory
+	stats  Stats
+	reject bool
+}
+
+// New allocates the configured memories. log nil discards.
+func New(cfg Config, log *slog.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.TimeoutMs == 0 {
+		cfg.TimeoutMs = DefaultTimeout
+	}
+
+	log = logging.OrDiscard(log)
+	s := &Server{
+		cfg:    cfg,
+		log:    log,
+		modbus: sim.NewGateway(log.With("server", "modbus")),
+		units:  map[uint16]*sim.Memory{},
+	}
+	for _, u := range cfg.Units {
+		s.add(u.ID, sim.NewMemorySize(u.Coils, u.Discrete, u.Holding, u.Input))
+	}
+	return s, nil
+}
+
+// add registers m for id; callers hold mu or own s exclusively.
+func (s *Server) add(id uint16, m *sim.Memory) {
+	s.units[id] = m
+	if id <= 255 {
+		s.modbus.SetUnitMemory(uint8(id), m)
+	}
+}
+
+// Memory returns the memory of unitID, nil when it has none.
+func (s *Server) Memory(unitID uint16) *sim.Memory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.units[unitID]
+}
+
+// Reset drops everything received, as an MMA restart would: configured
+// memories come back zeroed, auto-created ones disappear.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.units {
+		delete(s.units, id)
+		if id <= 255 {
+			s.modbus.SetUnitMemory(uint8(id), nil)
+		}
+	}
+	for _, u := range s.cfg.Units {
+		s.add(u.ID, sim.NewMemorySize(u.Coils, u.Discrete, u.Holding, u.Input))
+	}
+}
+
+// Stats returns a snapshot of the counters.
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// SetRejectAll makes every packet fail with the Rejected status, as an
+// MMA with no memory would. Tests use it for target-side failures.
+func (s *Server) SetRejectAll(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = on
+}
+
+// Serve listens on addr (Config.Listen, then DefaultListen, when empty)
+// until ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = s.cfg.Listen
+	}
+	if addr == "" {
+		addr = DefaultListen
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeListener(ctx, ln)
+}
+
+// ServeListener accepts Raw Ingest packets on ln until ctx is cancelled,
+// then closes ln and returns nil.
+func (s *Server) ServeListener(ctx context.Context, ln net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	stop := context.AfterFunc(ctx, func() { _ = ln.Close() })
+	defer stop()
+
+	s.log.Info("ingest sink listening", "addr", ln.Addr().String())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// ServeModbus serves the memories over Modbus TCP on addr
+// (Config.ModbusListen when empty) until ctx is cancelled.
+func (s *Server) ServeModbus(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = s.cfg.ModbusListen
+	}
+	if addr == "" {
+		return errors.New("ingestserver: no modbus listen address")
+	}
+	return s.modbus.Serve(ctx, addr)
+}
+
+// ServeModbusListener is ServeModbus on an open listener.
+func (s *Server) ServeModbusListener(ctx context.Context, ln net.Listener) error {
+	return s.modbus.ServeListener(ctx, ln)
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Duration(s.cfg.TimeoutMs) * time.Millisecond))
+
+	resp := respOK
+	p, err := readPacket(conn)
+	if err == nil {
+		err = s.apply(p)
+	}
+
+	s.mu.Lock()
+	if err != nil {
+		resp = respRejected
+		s.stats.Rejected++
+	} else {
+		s.stats.Accepted++
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.log.Warn("packet rejected", "remote", conn.RemoteAddr().String(), "err", err)
+	} else {
+		s.log.Debug("packet", "unit", p.UnitID, "area", p.Area, "address", p.Address, "count", p.Count)
+	}
+	_, _ = conn.Write([]byte{resp})
+}
+
+// readPacket reads exactly one v1 packet: the header, then the payload
+// size it implies. Bytes beyond that are never read.
+func readPacket(r io.Reader) (Packet, error) {
+	var h [headerSize]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return Packet{}, fmt.Errorf("header: %w", err)
+	}
+	if h[0] != magic0 || h[1] != magic1 {
+		return Packet{}, fmt.Errorf("bad magic % x", h[0:2])
+	}
+	if h[2] != versionV1 {
+		return Packet{}, fmt.Errorf("unsupported version 0x%02x", h[2])
+	}
+
+	p := Packet{
+		Area:    h[3],
+		UnitID:  binary.BigEndian.Uint16(h[4:6]),
+		Address: binary.BigEndian.Uint16(h[6:8]),
+		Count:   binary.BigEndian.Uint16(h[8:10]),
+	}
+	n, err := PayloadSize(p.Area, p.Count)
+	if err != nil {
+		return p, fmt.Errorf("unknown %w", err)
+	}
+	if p.Count == 0 {
+		return p, errors.New("count 0")
+	}
+
+	p.Payload = make([]byte, n)
+	if _, err := io.ReadFull(r, p.Payload); err != nil {
+		return p, fmt.Errorf("payload (%d bytes for count %d): %w", n, p.Count, err)
+	}
+	return p, nil
+}
+
+// apply checks unit and bounds, then writes p verbatim.
+func (s *Server) apply(p Packet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reject {
+		return errors.New("rejecting all packets")
+	}
+	m := s.units[p.UnitID]
+	if m == nil {
+		if !s.cfg.AutoCreate {
+			return fmt.Errorf("unit %d has no memory", p.UnitID)
+		}
+		m = sim.NewMemory()
+		s.add(p.UnitID, m)
+	}
+
+	area := sim.Area(p.Area)
+	if !m.Contains(area, p.Address, p.Count) {
+		return fmt.Errorf("unit %d %s %d+%d outside 0-%d", p.UnitID, area, p.Address, p.Count, m.Size(area)-1)
+	}
+	return m.Set(area, p.Address, decode(p)...)
+}
+
+// decode unpacks the payload: bits LSB first, registers big-endian.
+func decode(p Packet) []uint16 {
+	out := make([]uint16, p.Count)
+	for i := range out {
+		if p.Area == 1 || p.Area == 2 {
+			out[i] = uint16(p.Payload[i/8]>>(i%8)) & 1
+		} else {
+			out[i] = binary.BigEndian.Uint16(p.Payload[2*i:])
+		}
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+// internal/ingestserver/server_test.go
+package ingestserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+	"github.com/tamzrod/modbus-replicator/internal/sim"
+	"github.com/tamzrod/modbus-replicator/internal/writer/ingest"
+)
+
+func start(t *testing.T, cfg Config) (*Server, string, string) {
+	t.Helper()
+	s, err := New(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var done []chan error
+	serve := func(f func(context.Context, net.Listener) error) string {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch := make(chan error, 1)
+		go func() { ch <- f(ctx, ln) }()
+		done = append(done, ch)
+		return ln.Addr().String()
+	}
+	ingestAddr := serve(s.ServeListener)
+	modbusAddr := serve(s.ServeModbusListener)
+	t.Cleanup(func() {
+		cancel()
+		for _, ch := range done {
+			if err := <-ch; err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	return s, ingestAddr, modbusAddr
+}
+
+func client(t *testing.T, addr string) *ingest.EndpointClient {
+	t.Helper()
+	c, err := ingest.NewEndpointClient(ingest.Config{Endpoint: addr, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// send writes raw bytes on a fresh connection and returns the status byte.
+func send(t *testing.T, addr string, pkt []byte) byte {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(pkt); err != nil {
+		t.Fatal(err)
+	}
+	if tc, ok := conn.(*net.TCPConn); ok {
+		_ = tc.CloseWrite() // a short packet must not hang the server
+	}
+	var b [1]byte
+	if _, err := io.ReadFull(conn, b[:]); err != nil {
+		t.Fatal(err)
+	}
+	return b[0]
+}
+
+func TestPayloadSize(t *testing.T) {
+	for _, c := range []struct {
+		area  byte
+		count uint16
+		want  int
+	}{
+		{1, 1, 1}, {1, 8, 1}, {2, 9, 2}, {1, 2000, 250},
+		{3, 1, 2}, {4, 125, 250},
+	} {
+		if got, err := PayloadSize(c.area, c.count); err != nil || got != c.want {
+			t.Errorf("area %d count %d: %d %v", c.area, c.count, got, err)
+		}
+	}
+	if _, err := PayloadSize(5, 1); err == nil {
+		t.Error("area 5 accepted")
+	}
+}
+
+func TestServer_WriterClientRoundTrip(t *testing.T) {
+	s, addr, mbAddr := start(t, Config{Units: []UnitConfig{{ID: 7, Coils: 16, Holding: 100}}})
+	c := client(t, addr)
+
+	if err := c.WriteRegisters(3, 7, 10, []uint16{0x1234, 250, 500}); err != nil {
+		t.Fatal(err)
+	}
+	bits := []bool{true, false, true, true, false, false, false, false, true}
+	if err := c.WriteBits(1, 7, 3, bits); err != nil {
+		t.Fatal(err)
+	}
+
+	m := s.Memory(7)
+	if got := m.Get(sim.HoldingRegisters, 10, 3); !slices.Equal(got, []uint16{0x1234, 250, 500}) {
+		t.Fatalf("registers: %v", got)
+	}
+	if got := m.Get(sim.Coils, 3, 9); !slices.Equal(got, []uint16{1, 0, 1, 1, 0, 0, 0, 0, 1}) {
+		t.Fatalf("bits: %v", got)
+	}
+
+	// The same memory over Modbus TCP, unit id = ingest unit id.
+	mb, err := pmodbus.New(pmodbus.Config{Endpoint: mbAddr, UnitID: 7, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mb.Close()
+	if regs, err := mb.ReadHoldingRegisters(10, 3); err != nil || regs[0] != 0x1234 {
+		t.Fatalf("modbus: %v %v", regs, err)
+	}
+	if got, err := mb.ReadCoils(3, 9); err != nil || !slices.Equal(got, bits) {
+		t.Fatalf("modbus coils: %v %v", got, err)
+	}
+	var ex pmodbus.ModbusException
+	if _, err := mb.ReadHoldingRegisters(99, 2); !errors.As(err, &ex) || ex.Exception != 2 {
+		t.Fatalf("modbus past size: %v", err)
+	}
+
+	if st := s.Stats(); st.Accepted != 2 || st.Rejected != 0 {
+		t.Fatalf("stats: %+v", st)
+	}
+}
+
+func TestServer_Rejects(t *testing.T) {
+	s, addr, _ := start(t, Config{Units: []UnitConfig{{ID: 1, Coils: 8, Holding: 10}}})
+
+	header := func(magic string, version, area byte, unit, addr, count uint16) []byte {
+		return []byte{magic[0], magic[1], version, area,
+			byte(unit >> 8), byte(unit), byte(addr >> 8), byte(addr), byte(count >> 8), byte(count)}
+	}
+	regs := func(n int) []byte { return make([]byte, 2*n) }
+
+	cases := map[string][]byte{
+		"short header":   []byte("RI\x01\x03"),
+		"bad magic":      append(header("XX", 1, 3, 1, 0, 1), regs(1)...),
+		"version 2":      append(header("RI", 2, 3, 1, 0, 1), regs(1)...),
+		"area 0":         append(header("RI", 1, 0, 1, 0, 1), regs(1)...),
+		"area 5":         append(header("RI", 1, 5, 1, 0, 1), regs(1)...),
+		"count 0":        header("RI", 1, 3, 1, 0, 0),
+		"short payload":  append(header("RI", 1, 3, 1, 0, 3), regs(2)...),
+		"short bits":     header("RI", 1, 1, 1, 0, 1),
+		"unknown unit":   append(header("RI", 1, 3, 2, 0, 1), regs(1)...),
+		"past end":       append(header("RI", 1, 3, 1, 9, 2), regs(2)...),
+		"bits past end":  append(header("RI", 1, 1, 1, 4, 5), 0xFF),
+		"area not sized": append(header("RI", 1, 4, 1, 0, 1), regs(1)...),
+	}
+	for name, pkt := range cases {
+		if got := send(t, addr, pkt); got != respRejected {
+			t.Errorf("%s: status 0x%02x", name, got)
+		}
+	}
+
+	// Nothing of a rejected packet is applied.
+	if got := s.Memory(1).Get(sim.HoldingRegisters, 0, 10); slices.ContainsFunc(got, func(v uint16) bool { return v != 0 }) {
+		t.Fatalf("memory touched: %v", got)
+	}
+
+	// Count alone decides the payload size: trailing bytes are ignored.
+	pkt := append(header("RI", 1, 3, 1, 9, 1), 0x01, 0x02, 0xEE, 0xEE)
+	if got := send(t, addr, pkt); got != respOK {
+		t.Fatalf("exact packet: 0x%02x", got)
+	}
+	if got := s.Memory(1).Get(sim.HoldingRegisters, 9, 1)[0]; got != 0x0102 {
+		t.Fatalf("register 9 = %#x", got)
+	}
+	s.Reset()
+	if m := s.Memory(1); m == nil || m.Size(sim.HoldingRegisters) != 10 || m.Get(sim.HoldingRegisters, 9, 1)[0] != 0 {
+		t.Fatal("Reset: configured memory not zeroed")
+	}
+
+	s.SetRejectAll(true)
+	err := client(t, addr).WriteRegisters(3, 1, 0, []uint16{1})
+	if err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Fatalf("reject all: %v", err)
+	}
+	if st := s.Stats(); st.Rejected != len(cases)+1 || st.Accepted != 1 {
+		t.Fatalf("stats: %+v", st)
+	}
+}
+
+func TestServer_AutoCreate(t *testing.T) {
+	s, addr, mbAddr := start(t, Config{AutoCreate: true})
+
+	mb, err := pmodbus.New(pmodbus.Config{Endpoint: mbAddr, UnitID: 3, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mb.Close()
+	var ex pmodbus.ModbusException
+	if _, err := mb.ReadHoldingRegisters(0, 1); !errors.As(err, &ex) || ex.Exception != 0x0B {
+		t.Fatalf("before first packet: %v", err)
+	}
+
+	if err := client(t, addr).WriteRegisters(4, 3, 65535, []uint16{42}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Memory(3).Get(sim.InputRegisters, 65535, 1); got[0] != 42 {
+		t.Fatalf("got %v", got)
+	}
+	if regs, err := mb.ReadInputRegisters(65535, 1); err != nil || regs[0] != 42 {
+		t.Fatalf("modbus: %v %v", regs, err)
+	}
+
+	s.Reset()
+	if s.Memory(3) != nil {
+		t.Fatal("auto-created memory survived Reset")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig("testdata/sink.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Units) != 3 || cfg.ModbusListen == "" {
+		t.Fatalf("%+v", cfg)
+	}
+
+	bad := Config{Units: []UnitConfig{{ID: 1}, {ID: 1}}}
+	if err := bad.Validate(); err == nil || !strings.Contains(err.Error(), "twice") {
+		t.Fatalf("duplicate id: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+// internal/logging/levels.go
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+// UnitKey is the attribute that selects a per-unit level.
+const UnitKey = "unit"
+
+// Levels is the shared, reloadable level table: one global level plus
+// optional per-unit overrides.
+type Levels struct {
+	mu     sync.RWMutex
+	global slog.Level
+	units  map[string]slog.Level
+}
+
+// Apply replaces the table from configuration. On error nothing changes.
+func (l *Levels) Apply(c config.LoggingConfig) error {
+	global, err := ParseLevel(c.Level)
+	if err != nil {
+		return err
+	}
+
+	units := make(map[string]slog.Level, len(c.Units))
+	for id, s := range c.Units {
+		lv, err := ParseLevel(s)
+		if err != nil {
+			return err
+		}
+		units[id] = lv
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.global = global
+	l.units = units
+	return nil
+}
+
+func (l *Levels) levelFor(unit string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if unit != "" {
+		if lv, ok := l.units[unit]; ok {
+			return lv
+		}
+	}
+	return l.global
+}
+
+// levelHandler filters records by the level of the unit the logger is
+// bound to (via With(UnitKey, id)), falling back to the global level.
+type levelHandler struct {
+	next   slog.Handler
+	levels *Levels
+	unit   string
+}
+
+func newLevelHandler(next slog.Handler, levels *Levels) *levelHandler {
+	return &levelHandler{next: next, levels: levels}
+}
+
+func (h *levelHandler) Enabled(_ context.Context, lv slog.Level) bool {
+	return lv >= h.levels.levelFor(h.unit)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	unit := h.unit
+	for _, a := range attrs {
+		if a.Key == UnitKey {
+			unit = a.Value.String()
+		}
+	}
+	return &levelHandler{next: h.next.WithAttrs(attrs), levels: h.levels, unit: unit}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), levels: h.levels, unit: h.unit}
+}
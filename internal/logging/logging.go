@@ -0,0 +1,82 @@
+// internal/logging/logging.go
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+// DefaultSuppressWindow is used when logging.suppress_window_ms is 0.
+const DefaultSuppressWindow = time.Minute
+
+// Setup builds the process logger from configuration.
+//
+// The handler chain is: per-unit level filter → repeat suppression →
+// text/JSON output. The returned Levels can be re-applied on reload;
+// format and suppression window are fixed at startup.
+func Setup(w io.Writer, c config.LoggingConfig) (*slog.Logger, *Levels, error) {
+	levels := &Levels{}
+	if err := levels.Apply(c); err != nil {
+		return nil, nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug} // filtering happens in levelHandler
+
+	var out slog.Handler
+	switch c.Format {
+	case "", "text":
+		out = slog.NewTextHandler(w, opts)
+	case "json":
+		out = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, nil, fmt.Errorf("logging: format %q not supported (text, json)", c.Format)
+	}
+
+	window := DefaultSuppressWindow
+	if c.SuppressWindowMs > 0 {
+		window = time.Duration(c.SuppressWindowMs) * time.Millisecond
+	}
+
+	h := newLevelHandler(newSuppressHandler(out, window, time.Now), levels)
+	return slog.New(h), levels, nil
+}
+
+// ParseLevel maps a configured level name to a slog level.
+// Empty means info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("logging: level %q not supported (debug, info, warn, error)", s)
+}
+
+// Unit returns the logger for one unit pipeline.
+// Every entry carries the unit and source attributes.
+func Unit(l *slog.Logger, u config.UnitConfig) *slog.Logger {
+	return l.With(UnitKey, u.ID, "source", u.Source.Endpoint)
+}
+
+// Discard returns a logger that drops everything (tests, defaults).
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// OrDiscard returns l, or a discarding logger when l is nil.
+func OrDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return Discard()
+	}
+	return l
+}
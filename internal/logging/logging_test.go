@@ -0,0 +1,163 @@
+// internal/logging/logging_test.go
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+func TestSetup_JSONCarriesUnitContext(t *testing.T) {
+	var buf bytes.Buffer
+	log, _, err := Setup(&buf, config.LoggingConfig{Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := config.UnitConfig{ID: "inv-1"}
+	u.Source.Endpoint = "10.0.0.5:502"
+	Unit(log, u).With("target", "mma:9000").Warn("target write failed")
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("not json: %q", buf.String())
+	}
+	for k, want := range map[string]string{
+		"level":  "WARN",
+		"msg":    "target write failed",
+		"unit":   "inv-1",
+		"source": "10.0.0.5:502",
+		"target": "mma:9000",
+	} {
+		if rec[k] != want {
+			t.Fatalf("%s: got %v want %q", k, rec[k], want)
+		}
+	}
+}
+
+func TestLevels_PerUnitOverrideAndReload(t *testing.T) {
+	var buf bytes.Buffer
+	log, levels, err := Setup(&buf, config.LoggingConfig{
+		Level: "warn",
+		Units: map[string]string{"noisy": "debug"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.With(UnitKey, "quiet").Info("hidden")
+	log.With(UnitKey, "noisy").Debug("shown")
+	log.Info("hidden")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	// Reload: existing loggers follow the new table.
+	buf.Reset()
+	quiet := log.With(UnitKey, "quiet")
+	if err := levels.Apply(config.LoggingConfig{Level: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	quiet.Info("now shown")
+	if !strings.Contains(buf.String(), "now shown") {
+		t.Fatalf("reload not applied: %q", buf.String())
+	}
+
+	// A bad table is rejected and leaves the previous one in place.
+	if err := levels.Apply(config.LoggingConfig{Level: "loud"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if levels.levelFor("quiet") != slog.LevelInfo {
+		t.Fatal("levels changed by rejected apply")
+	}
+}
+
+func TestSuppress_RepeatsCollapsedIntoSummary(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Unix(0, 0)
+	h := newSuppressHandler(slog.NewTextHandler(&buf, nil), time.Minute, func() time.Time { return now })
+	log := slog.New(h).With(UnitKey, "inv-1")
+
+	for i := 0; i < 5; i++ {
+		log.Warn("poll failed", "err", "timeout")
+	}
+	log.Warn("poll failed", "err", "refused") // different attrs: not a repeat
+	log.Info("poll failed", "err", "timeout") // below WARN: never suppressed
+
+	if got := strings.Count(buf.String(), "poll failed"); got != 3 {
+		t.Fatalf("inside window: got %d lines\n%s", got, buf.String())
+	}
+
+	buf.Reset()
+	now = now.Add(time.Minute)
+	log.Warn("poll failed", "err", "timeout")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("after window: got %d lines\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], "suppressed repeated log entries") ||
+		!strings.Contains(lines[0], "count=4") ||
+		!strings.Contains(lines[0], "unit=inv-1") {
+		t.Fatalf("bad summary: %q", lines[0])
+	}
+}
+
+func TestSuppress_SummaryWrittenWhenWindowEnds(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Unix(0, 0)
+	h := newSuppressHandler(slog.NewTextHandler(&buf, nil), time.Minute, func() time.Time { return now })
+	var timers []func()
+	h.state.after = func(d time.Duration, f func()) {
+		if d != time.Minute {
+			t.Errorf("timer armed for %v, want the rest of the window", d)
+		}
+		timers = append(timers, f)
+	}
+	log := slog.New(h).With(UnitKey, "inv-1")
+
+	for i := 0; i < 3; i++ {
+		log.Warn("poll failed", "err", "timeout")
+	}
+	if len(timers) != 1 {
+		t.Fatalf("armed %d timers, want 1", len(timers))
+	}
+
+	// The outage ends: no further repeat, the window runs out.
+	buf.Reset()
+	now = now.Add(time.Minute)
+	timers[0]()
+
+	out := buf.String()
+	if !strings.Contains(out, "suppressed repeated log entries") ||
+		!strings.Contains(out, "count=2") ||
+		!strings.Contains(out, "unit=inv-1") {
+		t.Fatalf("bad summary: %q", out)
+	}
+
+	// A later occurrence starts afresh, with no second summary.
+	buf.Reset()
+	log.Warn("poll failed", "err", "timeout")
+	if strings.Contains(buf.String(), "suppressed") || strings.Count(buf.String(), "poll failed") != 1 {
+		t.Fatalf("after flush:\n%s", buf.String())
+	}
+}
+
+func TestSetup_RejectsBadConfig(t *testing.T) {
+	for _, c := range []config.LoggingConfig{
+		{Level: "trace"},
+		{Format: "xml"},
+		{Units: map[string]string{"a": "verbose"}},
+	} {
+		if _, _, err := Setup(&bytes.Buffer{}, c); err == nil {
+			t.Fatalf("expected error for %+v", c)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// internal/logging/suppress.go
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// suppressHandler rate-limits identical warnings and errors.
+//
+// During an outage a unit fails every poll cycle and would log the same
+// line at the poll rate. The first occurrence is written; identical ones
+// inside the window are counted and dropped. When the window ends a
+// "suppressed repeated log entries" summary carries the count, whether or
+// not the line comes back. Levels below WARN are never suppressed.
+type suppressHandler struct {
+	next  slog.Handler
+	state *suppressState
+
+	// bound renders the attributes fixed by With, part of the identity key.
+	bound string
+}
+
+type suppressState struct {
+	mu     sync.Mutex
+	window time.Duration
+	now    func() time.Time
+	after  func(d time.Duration, f func()) // time.AfterFunc; faked in tests
+	seen   map[string]*suppressEntry
+}
+
+type suppressEntry struct {
+	since      time.Time
+	suppressed int
+}
+
+// maxSuppressEntries bounds memory; older entries are purged beyond it.
+const maxSuppressEntries = 4096
+
+func newSuppressHandler(next slog.Handler, window time.Duration, now func() time.Time) *suppressHandler {
+	return &suppressHandler{
+		next: next,
+		state: &suppressState{
+			window: window,
+			now:    now,
+			after:  func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+			seen:   make(map[string]*suppressEntry),
+		},
+	}
+}
+
+func (h *suppressHandler) Enabled(ctx context.Context, lv slog.Level) bool {
+	return h.next.Enabled(ctx, lv)
+}
+
+func (h *suppressHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		return h.next.Handle(ctx, r)
+	}
+
+	key := h.bound + "|" + r.Message + "|" + renderAttrs(r)
+
+	lv, msg, pc := r.Level, r.Message, r.PC
+	expired := func(count int) {
+		_ = h.summary(context.Background(), h.state.now(), lv, msg, pc, count)
+	}
+
+	summary, pass := h.state.admit(key, expired)
+	if summary > 0 {
+		if err := h.summary(ctx, r.Time, lv, msg, pc, summary); err != nil {
+			return err
+		}
+	}
+	if !pass {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *suppressHandler) summary(ctx context.Context, t time.Time, lv slog.Level, msg string, pc uintptr, count int) error {
+	s := slog.NewRecord(t, lv, "suppressed repeated log entries", pc)
+	s.AddAttrs(slog.String("message", msg), slog.Int("count", count))
+	return h.next.Handle(ctx, s)
+}
+
+// admit reports whether the record identified by key passes, and how many
+// identical records were suppressed before it (to be summarised).
+//
+// The first suppressed repeat arms a timer for the end of the window; it
+// hands the count to expired unless a later record summarised it first.
+func (s *suppressState) admit(key string, expired func(count int)) (summary int, pass bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	e, ok := s.seen[key]
+	if ok && now.Sub(e.since) < s.window {
+		e.suppressed++
+		if e.suppressed == 1 {
+			s.after(e.since.Add(s.window).Sub(now), func() { s.expire(key, e, expired) })
+		}
+		return 0, false
+	}
+
+	if ok {
+		summary = e.suppressed
+		e.suppressed = 0 // summarised here, not by its timer
+	}
+	if len(s.seen) >= maxSuppressEntries {
+		s.purge(now)
+	}
+	s.seen[key] = &suppressEntry{since: now}
+	return summary, true
+}
+
+func (s *suppressState) expire(key string, e *suppressEntry, expired func(count int)) {
+	s.mu.Lock()
+	count := e.suppressed
+	e.suppressed = 0
+	if s.seen[key] == e {
+		delete(s.seen, key)
+	}
+	s.mu.Unlock()
+
+	if count > 0 {
+		expired(count)
+	}
+}
+
+func (s *suppressState) purge(now time.Time) {
+	for k, e := range s.seen {
+		if now.Sub(e.since) >= s.window {
+			delete(s.seen, k)
+		}
+	}
+}
+
+func (h *suppressHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.bound)
+	for _, a := range attrs {
+		b.WriteString(a.String())
+		b.WriteByte(' ')
+	}
+	return &suppressHandler{next: h.next.WithAttrs(attrs), state: h.state, bound: b.String()}
+}
+
+func (h *suppressHandler) WithGroup(name string) slog.Handler {
+	return &suppressHandler{next: h.next.WithGroup(name), state: h.state, bound: h.bound + name + "."}
+}
+
+func renderAttrs(r slog.Record) string {
+	var b strings.Builder
+	r.Attrs(func(a slog.Attr) bool {
+		b.WriteString(a.String())
+		b.WriteByte(' ')
+		return true
+	})
+	return b.String()
+}
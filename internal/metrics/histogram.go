@@ -0,0 +1,32 @@
+// internal/metrics/histogram.go
+package metrics
+
+import "time"
+
+// LatencyBuckets are the fixed upper bounds (seconds) for latency histograms.
+// Fixed on purpose: every histogram shares one shape, so a Histogram is a
+// plain value that can be copied across goroutines without aliasing.
+var LatencyBuckets = [...]float64{
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// Histogram is a cumulative latency histogram over LatencyBuckets.
+// Counts[i] is the number of observations <= LatencyBuckets[i];
+// Count is the +Inf bucket.
+type Histogram struct {
+	Counts [len(LatencyBuckets)]uint64
+	Count  uint64
+	Sum    float64 // seconds
+}
+
+// Observe records one duration.
+func (h *Histogram) Observe(d time.Duration) {
+	v := d.Seconds()
+	for i, ub := range LatencyBuckets {
+		if v <= ub {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += v
+}
@@ -0,0 +1,139 @@
+// internal/metrics/openmetrics.go
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the OpenMetrics text exposition media type.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Type is an OpenMetrics metric family type.
+type Type string
+
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+// Labels is one sample's label set. Rendering sorts by name.
+type Labels map[string]string
+
+// Family is one metric family and its samples.
+//
+// Counter families are named without the _total suffix; the encoder
+// appends it to every sample as OpenMetrics requires.
+type Family struct {
+	Name string
+	Help string
+	Type Type
+
+	samples    []sample
+	histograms []histSample
+}
+
+type sample struct {
+	labels Labels
+	value  float64
+}
+
+type histSample struct {
+	labels Labels
+	h      Histogram
+}
+
+// Add appends a counter or gauge sample.
+func (f *Family) Add(labels Labels, v float64) {
+	f.samples = append(f.samples, sample{labels: labels, value: v})
+}
+
+// AddHistogram appends a histogram sample.
+func (f *Family) AddHistogram(labels Labels, h Histogram) {
+	f.histograms = append(f.histograms, histSample{labels: labels, h: h})
+}
+
+// Write renders families in OpenMetrics text format, terminated by # EOF.
+func Write(w io.Writer, families []*Family) error {
+	bw := bufio.NewWriter(w)
+
+	for _, f := range families {
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Type)
+		if f.Help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		}
+
+		switch f.Type {
+		case TypeCounter:
+			for _, s := range f.samples {
+				writeSample(bw, f.Name+"_total", s.labels, "", "", s.value)
+			}
+		case TypeGauge:
+			for _, s := range f.samples {
+				writeSample(bw, f.Name, s.labels, "", "", s.value)
+			}
+		case TypeHistogram:
+			for _, hs := range f.histograms {
+				for i, ub := range LatencyBuckets {
+					writeSample(bw, f.Name+"_bucket", hs.labels, "le", formatFloat(ub), float64(hs.h.Counts[i]))
+				}
+				writeSample(bw, f.Name+"_bucket", hs.labels, "le", "+Inf", float64(hs.h.Count))
+				writeSample(bw, f.Name+"_count", hs.labels, "", "", float64(hs.h.Count))
+				writeSample(bw, f.Name+"_sum", hs.labels, "", "", hs.h.Sum)
+			}
+		}
+	}
+
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+func writeSample(w *bufio.Writer, name string, labels Labels, extraKey, extraVal string, v float64) {
+	w.WriteString(name)
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 0 || extraKey != "" {
+		w.WriteByte('{')
+		first := true
+		for _, k := range keys {
+			if !first {
+				w.WriteByte(',')
+			}
+			first = false
+			fmt.Fprintf(w, "%s=\"%s\"", k, escapeLabel(labels[k]))
+		}
+		if extraKey != "" {
+			if !first {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraKey, extraVal)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
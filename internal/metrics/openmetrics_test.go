@@ -0,0 +1,49 @@
+// internal/metrics/openmetrics_test.go
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite_CounterGaugeHistogram(t *testing.T) {
+	c := &Family{Name: "x_requests", Type: TypeCounter, Help: "Requests."}
+	c.Add(Labels{"unit": "a", "source": "10.0.0.1:502"}, 3)
+
+	g := &Family{Name: "x_health", Type: TypeGauge}
+	g.Add(Labels{"unit": `we"ird`}, 1)
+
+	var h Histogram
+	h.Observe(2 * time.Millisecond)
+	h.Observe(3 * time.Second)
+	hf := &Family{Name: "x_duration_seconds", Type: TypeHistogram}
+	hf.AddHistogram(Labels{"unit": "a"}, h)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, []*Family{c, g, hf}); err != nil {
+		t.Fatalf("Write() err=%v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE x_requests counter\n# HELP x_requests Requests.\n",
+		`x_requests_total{source="10.0.0.1:502",unit="a"} 3` + "\n",
+		`x_health{unit="we\"ird"} 1` + "\n",
+		`x_duration_seconds_bucket{unit="a",le="0.001"} 0` + "\n",
+		`x_duration_seconds_bucket{unit="a",le="0.0025"} 1` + "\n",
+		`x_duration_seconds_bucket{unit="a",le="5"} 2` + "\n",
+		`x_duration_seconds_bucket{unit="a",le="+Inf"} 2` + "\n",
+		`x_duration_seconds_count{unit="a"} 2` + "\n",
+		`x_duration_seconds_sum{unit="a"} 3.002` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	if !strings.HasSuffix(out, "# EOF\n") {
+		t.Fatalf("output not terminated by # EOF:\n%s", out)
+	}
+}
@@ -0,0 +1,314 @@
+// internal/mqtt/broker.go
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Broker is a small in-process MQTT 3.1.1 broker: enough to stand in
+// for a real one in tests and on a bench. It keeps retained messages,
+// publishes wills, and delivers to subscribers (network or in-process)
+// at QoS 0. There is no persistence and no authentication.
+type Broker struct {
+	log *slog.Logger
+
+	mu       sync.Mutex
+	conns    map[*brokerConn]struct{}
+	retained map[string]Message
+	watchers map[*watcher]struct{}
+	hold     bool
+	connects int
+}
+
+type brokerConn struct {
+	nc       net.Conn
+	clientID string
+	will     *Message
+	subs     []string
+
+	wmu sync.Mutex
+}
+
+type watcher struct {
+	filter string
+	ch     chan Message
+}
+
+// NewBroker returns an empty broker. log may be nil.
+func NewBroker(log *slog.Logger) *Broker {
+	if log == nil {
+		log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Broker{
+		log:      log,
+		conns:    make(map[*brokerConn]struct{}),
+		retained: make(map[string]Message),
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// Serve listens on addr until ctx is done.
+func (b *Broker) Serve(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return b.ServeListener(ctx, ln)
+}
+
+// ServeListener serves ln until ctx is done, then closes every client
+// connection (their wills are published).
+func (b *Broker) ServeListener(ctx context.Context, ln net.Listener) error {
+	stop := context.AfterFunc(ctx, func() {
+		ln.Close()
+		b.DropClients()
+	})
+	defer stop()
+
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go b.handle(nc)
+	}
+}
+
+// Subscribe delivers every message matching filter (+ and # allowed)
+// to the returned channel, starting with matching retained messages.
+// Messages are dropped when the channel is full. cancel ends it.
+func (b *Broker) Subscribe(filter string) (<-chan Message, func()) {
+	w := &watcher{filter: filter, ch: make(chan Message, 4096)}
+	b.mu.Lock()
+	b.watchers[w] = struct{}{}
+	for _, m := range b.retained {
+		if Match(filter, m.Topic) {
+			w.ch <- m
+		}
+	}
+	b.mu.Unlock()
+	return w.ch, func() {
+		b.mu.Lock()
+		delete(b.watchers, w)
+		b.mu.Unlock()
+	}
+}
+
+// DropClients closes every client connection without a DISCONNECT, as
+// a network failure would: their wills are published.
+func (b *Broker) DropClients() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.conns {
+		c.nc.Close()
+	}
+}
+
+// Hold, while true, swallows every PUBLISH from clients: nothing is
+// delivered or acknowledged.
+func (b *Broker) Hold(on bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hold = on
+}
+
+// Connects counts accepted CONNECTs.
+func (b *Broker) Connects() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.connects
+}
+
+// Retained returns the retained message on topic.
+func (b *Broker) Retained(topic string) (Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, ok := b.retained[topic]
+	return m, ok
+}
+
+func (b *Broker) handle(nc net.Conn) {
+	defer nc.Close()
+	br := bufio.NewReader(nc)
+
+	_ = nc.SetReadDeadline(time.Now().Add(10 * time.Second))
+	p, err := readPacket(br)
+	if err != nil || p.typ != typeConnect {
+		return
+	}
+	hello, err := parseConnect(p)
+	if err != nil {
+		_, _ = nc.Write(packet{typ: typeConnack, body: []byte{0, 1}}.encode())
+		return
+	}
+	_ = nc.SetReadDeadline(time.Time{})
+
+	c := &brokerConn{nc: nc, clientID: hello.clientID, will: hello.will}
+	b.mu.Lock()
+	// A second connection with the same client id takes over.
+	for old := range b.conns {
+		if old.clientID == c.clientID && c.clientID != "" {
+			old.nc.Close()
+		}
+	}
+	b.conns[c] = struct{}{}
+	b.connects++
+	b.mu.Unlock()
+
+	if err := c.send(packet{typ: typeConnack, body: []byte{0, 0}}); err != nil {
+		b.drop(c, true)
+		return
+	}
+	b.log.Debug("client connected", "client_id", c.clientID)
+
+	keepAlive := time.Duration(hello.keepAlive) * time.Second
+	for {
+		if keepAlive > 0 {
+			_ = nc.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		}
+		p, err := readPacket(br)
+		if err != nil {
+			b.drop(c, true)
+			return
+		}
+		switch p.typ {
+		case typePublish:
+			m, id, err := parsePublish(p)
+			if err != nil {
+				b.drop(c, true)
+				return
+			}
+			b.mu.Lock()
+			hold := b.hold
+			b.mu.Unlock()
+			if hold {
+				continue
+			}
+			b.publish(m)
+			if m.QoS == 1 {
+				_ = c.send(idPacket(typePuback, id))
+			}
+		case typeSubscribe:
+			r := reader{b: p.body}
+			id := r.uint16()
+			var filters []string
+			for len(r.b) > 0 && r.err == nil {
+				filters = append(filters, r.string())
+				r.byte() // requested qos; everything is delivered at 0
+			}
+			if r.err != nil {
+				b.drop(c, true)
+				return
+			}
+			b.mu.Lock()
+			c.subs = append(c.subs, filters...)
+			var retained []Message
+			for _, m := range b.retained {
+				for _, f := range filters {
+					if Match(f, m.Topic) {
+						retained = append(retained, m)
+						break
+					}
+				}
+			}
+			b.mu.Unlock()
+			ack := binary.BigEndian.AppendUint16(nil, id)
+			ack = append(ack, make([]byte, len(filters))...) // granted qos 0
+			_ = c.send(packet{typ: typeSuback, body: ack})
+			for _, m := range retained {
+				_ = c.send(publishPacket(Message{Topic: m.Topic, Payload: m.Payload, Retain: true}, 0, false))
+			}
+		case typePingreq:
+			_ = c.send(packet{typ: typePingresp})
+		case typeDisconnect:
+			b.drop(c, false)
+			return
+		}
+	}
+}
+
+func (c *brokerConn) send(p packet) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_ = c.nc.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := c.nc.Write(p.encode())
+	return err
+}
+
+// drop forgets c; lost connections publish their will.
+func (b *Broker) drop(c *brokerConn, lost bool) {
+	b.mu.Lock()
+	_, ok := b.conns[c]
+	delete(b.conns, c)
+	b.mu.Unlock()
+
+	if ok && lost && c.will != nil {
+		b.publish(*c.will)
+	}
+	if ok {
+		b.log.Debug("client disconnected", "client_id", c.clientID, "lost", lost)
+	}
+}
+
+// publish retains and fans m out.
+func (b *Broker) publish(m Message) {
+	b.mu.Lock()
+	if m.Retain {
+		if len(m.Payload) == 0 {
+			delete(b.retained, m.Topic)
+		} else {
+			b.retained[m.Topic] = m
+		}
+	}
+	var to []*brokerConn
+	for c := range b.conns {
+		for _, f := range c.subs {
+			if Match(f, m.Topic) {
+				to = append(to, c)
+				break
+			}
+		}
+	}
+	for w := range b.watchers {
+		if Match(w.filter, m.Topic) {
+			select {
+			case w.ch <- m:
+			default:
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	out := Message{Topic: m.Topic, Payload: m.Payload}
+	for _, c := range to {
+		_ = c.send(publishPacket(out, 0, false))
+	}
+}
+
+// Match reports whether topic matches filter (+ one level, # the rest).
+func Match(filter, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, part := range f {
+		switch {
+		case part == "#":
+			return true
+		case i >= len(t):
+			return false
+		case part != "+" && part != t[i]:
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
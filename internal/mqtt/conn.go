@@ -0,0 +1,267 @@
+// internal/mqtt/conn.go
+package mqtt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Config is one client connection.
+type Config struct {
+	Broker   string // host:port
+	ClientID string
+	Username string
+	Password string
+
+	// KeepAlive is announced to the broker; a PINGREQ goes out every
+	// half of it, and a broker silent for 1.5× is considered gone.
+	// 0 means 30s.
+	KeepAlive time.Duration
+
+	// Timeout bounds the dial, the CONNACK and every PUBACK.
+	// 0 means 5s.
+	Timeout time.Duration
+
+	// Will is published by the broker if the connection is lost
+	// without a DISCONNECT.
+	Will *Message
+}
+
+// ErrClosed is returned once the connection is gone.
+var ErrClosed = errors.New("mqtt: connection closed")
+
+// Conn is one clean MQTT 3.1.1 session. It does not reconnect: when it
+// fails, Done is closed and a new Conn must be dialed.
+type Conn struct {
+	nc      net.Conn
+	timeout time.Duration
+
+	wmu sync.Mutex // one packet per Write, in order
+
+	mu       sync.Mutex
+	nextID   uint16
+	acks     map[uint16]chan struct{}
+	lastRead time.Time
+	err      error
+
+	done chan struct{}
+}
+
+// Dial connects and completes the CONNECT handshake.
+func Dial(cfg Config) (*Conn, error) {
+	if cfg.KeepAlive <= 0 {
+		cfg.KeepAlive = 30 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	nc, err := net.DialTimeout("tcp", cfg.Broker, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(nc)
+	_ = nc.SetDeadline(time.Now().Add(cfg.Timeout))
+	hello := connect{
+		clientID:  cfg.ClientID,
+		username:  cfg.Username,
+		password:  cfg.Password,
+		keepAlive: uint16(min(cfg.KeepAlive/time.Second, 65535)),
+		will:      cfg.Will,
+	}
+	if _, err := nc.Write(hello.packet().encode()); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	p, err := readPacket(br)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("mqtt: connack: %w", err)
+	}
+	if p.typ != typeConnack || len(p.body) != 2 {
+		nc.Close()
+		return nil, errors.New("mqtt: expected CONNACK")
+	}
+	if rc := p.body[1]; rc != 0 {
+		nc.Close()
+		return nil, fmt.Errorf("mqtt: connection refused: %s", refusal(rc))
+	}
+	_ = nc.SetDeadline(time.Time{})
+
+	c := &Conn{
+		nc:       nc,
+		timeout:  cfg.Timeout,
+		acks:     make(map[uint16]chan struct{}),
+		lastRead: time.Now(),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop(br)
+	go c.keepAlive(cfg.KeepAlive)
+	return c, nil
+}
+
+func refusal(rc byte) string {
+	switch rc {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("return code %d", rc)
+}
+
+// Done is closed when the connection has failed or was closed.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+// Err is why the connection ended (nil while it is up).
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Publish sends m. With QoS 1 it waits for the PUBACK; dup marks a
+// resend of a message that may have arrived already.
+func (c *Conn) Publish(m Message, dup bool) error {
+	if m.QoS > 1 {
+		return fmt.Errorf("mqtt: qos %d not supported", m.QoS)
+	}
+	if m.QoS == 0 {
+		return c.write(publishPacket(m, 0, false))
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+	ack := make(chan struct{})
+	c.acks[id] = ack
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.acks, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(publishPacket(m, id, dup)); err != nil {
+		return err
+	}
+	t := time.NewTimer(c.timeout)
+	defer t.Stop()
+	select {
+	case <-ack:
+		return nil
+	case <-c.done:
+		return c.Err()
+	case <-t.C:
+		// Nothing is known about the stream any more.
+		c.fail(errors.New("mqtt: puback timeout"))
+		return c.Err()
+	}
+}
+
+// Close sends DISCONNECT (so the will is discarded) and closes.
+func (c *Conn) Close() error {
+	_ = c.write(packet{typ: typeDisconnect})
+	c.fail(ErrClosed)
+	return nil
+}
+
+func (c *Conn) write(p packet) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.Err()
+	default:
+	}
+	_ = c.nc.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.nc.Write(p.encode()); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+// fail records the first error and tears the connection down.
+func (c *Conn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.nc.Close()
+	close(c.done)
+}
+
+func (c *Conn) readLoop(br *bufio.Reader) {
+	for {
+		p, err := readPacket(br)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		c.mu.Lock()
+		c.lastRead = time.Now()
+		c.mu.Unlock()
+
+		switch p.typ {
+		case typePuback:
+			if len(p.body) != 2 {
+				continue
+			}
+			id := uint16(p.body[0])<<8 | uint16(p.body[1])
+			c.mu.Lock()
+			if ack, ok := c.acks[id]; ok {
+				close(ack)
+				delete(c.acks, id)
+			}
+			c.mu.Unlock()
+		case typePublish:
+			// Nothing is subscribed; acknowledge and drop.
+			if _, id, err := parsePublish(p); err == nil && p.flags>>1&0x03 == 1 {
+				_ = c.write(idPacket(typePuback, id))
+			}
+		}
+	}
+}
+
+func (c *Conn) keepAlive(every time.Duration) {
+	t := time.NewTicker(every / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-t.C:
+			c.mu.Lock()
+			silent := time.Since(c.lastRead)
+			c.mu.Unlock()
+			if silent > every*3/2 {
+				c.fail(errors.New("mqtt: broker keepalive timeout"))
+				return
+			}
+			_ = c.write(packet{typ: typePingreq})
+		}
+	}
+}
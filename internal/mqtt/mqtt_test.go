@@ -0,0 +1,191 @@
+// internal/mqtt/mqtt_test.go
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func startBroker(t *testing.T) (*Broker, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBroker(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = b.ServeListener(ctx, ln)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return b, ln.Addr().String()
+}
+
+func next(t *testing.T, ch <-chan Message) Message {
+	t.Helper()
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message")
+		return Message{}
+	}
+}
+
+func TestConn_PublishQoS0AndQoS1(t *testing.T) {
+	b, addr := startBroker(t)
+	got, cancel := b.Subscribe("plant/+/temp")
+	defer cancel()
+
+	c, err := Dial(Config{Broker: addr, ClientID: "a", Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Publish(Message{Topic: "plant/1/temp", Payload: []byte("20")}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Publish(Message{Topic: "plant/2/temp", Payload: []byte("21"), QoS: 1, Retain: true}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Publish(Message{Topic: "plant/2/pressure", Payload: []byte("x")}, false); err != nil {
+		t.Fatal(err)
+	}
+	if m := next(t, got); m.Topic != "plant/1/temp" || string(m.Payload) != "20" {
+		t.Fatalf("got %+v", m)
+	}
+	if m := next(t, got); m.Topic != "plant/2/temp" || m.QoS != 1 || !m.Retain {
+		t.Fatalf("got %+v", m)
+	}
+	if m, ok := b.Retained("plant/2/temp"); !ok || string(m.Payload) != "21" {
+		t.Fatalf("retained %+v", m)
+	}
+
+	// A late subscriber gets the retained message first.
+	late, cancel2 := b.Subscribe("plant/#")
+	defer cancel2()
+	if m := next(t, late); m.Topic != "plant/2/temp" {
+		t.Fatalf("late subscriber got %+v", m)
+	}
+}
+
+func TestConn_WillOnlyOnLostConnection(t *testing.T) {
+	b, addr := startBroker(t)
+	wills, cancel := b.Subscribe("state")
+	defer cancel()
+
+	will := &Message{Topic: "state", Payload: []byte("lost"), QoS: 1}
+	c, err := Dial(Config{Broker: addr, ClientID: "a", Will: will})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	c, err = Dial(Config{Broker: addr, ClientID: "a", Will: will})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.DropClients()
+	if m := next(t, wills); string(m.Payload) != "lost" {
+		t.Fatalf("got %+v", m)
+	}
+	select {
+	case m := <-wills:
+		t.Fatalf("will of the clean disconnect was published: %+v", m)
+	default:
+	}
+
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not notice the drop")
+	}
+	if err := c.Publish(Message{Topic: "x", QoS: 1}, false); err == nil {
+		t.Fatal("published on a dead connection")
+	}
+}
+
+func TestConn_PubackTimeoutEndsConnection(t *testing.T) {
+	b, addr := startBroker(t)
+	b.Hold(true)
+
+	c, err := Dial(Config{Broker: addr, ClientID: "a", Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Publish(Message{Topic: "x", QoS: 1}, false); err == nil {
+		t.Fatal("no error without a PUBACK")
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Fatal("connection kept after a lost PUBACK")
+	}
+}
+
+// Network subscribers get deliveries, and pings are answered.
+func TestBroker_NetworkSubscriber(t *testing.T) {
+	b, addr := startBroker(t)
+	b.publish(Message{Topic: "a/b", Payload: []byte("old"), Retain: true})
+
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	br := bufio.NewReader(nc)
+	_ = nc.SetDeadline(time.Now().Add(2 * time.Second))
+
+	expect := func(typ byte) packet {
+		t.Helper()
+		p, err := readPacket(br)
+		if err != nil || p.typ != typ {
+			t.Fatalf("got %+v, %v; want type %d", p, err, typ)
+		}
+		return p
+	}
+	nc.Write(connect{clientID: "sub", keepAlive: 10}.packet().encode())
+	expect(typeConnack)
+	nc.Write(packet{typ: typeSubscribe, flags: flagsSubscribe, body: append(appendString([]byte{0, 7}, "a/#"), 0)}.encode())
+	if p := expect(typeSuback); p.body[1] != 7 {
+		t.Fatalf("suback id %v", p.body)
+	}
+	if m, _, _ := parsePublish(expect(typePublish)); string(m.Payload) != "old" || !m.Retain {
+		t.Fatalf("retained %+v", m)
+	}
+
+	b.publish(Message{Topic: "a/c", Payload: []byte("new"), QoS: 1})
+	if m, _, _ := parsePublish(expect(typePublish)); m.Topic != "a/c" || m.QoS != 0 {
+		t.Fatalf("delivery %+v", m)
+	}
+	nc.Write(packet{typ: typePingreq}.encode())
+	expect(typePingresp)
+}
+
+func TestMatch(t *testing.T) {
+	for _, c := range []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a/b/c", true},
+		{"#", "a", true},
+		{"a/b/c", "a/b", false},
+		{"+/b", "x/c", false},
+	} {
+		if got := Match(c.filter, c.topic); got != c.want {
+			t.Errorf("Match(%q, %q) = %v", c.filter, c.topic, got)
+		}
+	}
+}
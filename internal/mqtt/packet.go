@@ -0,0 +1,247 @@
+// internal/mqtt/packet.go
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types (the high nibble of the first byte).
+const (
+	typeConnect    = 1
+	typeConnack    = 2
+	typePublish    = 3
+	typePuback     = 4
+	typeSubscribe  = 8
+	typeSuback     = 9
+	typePingreq    = 12
+	typePingresp   = 13
+	typeDisconnect = 14
+)
+
+const (
+	protocolLevel = 4 // 3.1.1
+
+	flagsSubscribe = 0x02
+	publishDup     = 0x08
+	publishRetain  = 0x01
+
+	connectUsername = 0x80
+	connectPassword = 0x40
+	connectWillRet  = 0x20
+	connectWill     = 0x04
+	connectClean    = 0x02
+)
+
+// Message is one application message.
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte // 0 or 1
+	Retain  bool
+}
+
+// packet is one control packet: fixed header and the rest.
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+// readPacket reads one control packet.
+func readPacket(r *bufio.Reader) (packet, error) {
+	b0, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	var n, shift int
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		n |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return packet{}, errors.New("mqtt: malformed remaining length")
+		}
+		shift += 7
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{typ: b0 >> 4, flags: b0 & 0x0F, body: body}, nil
+}
+
+// encode returns the packet ready for a single Write.
+func (p packet) encode() []byte {
+	b := make([]byte, 0, len(p.body)+5)
+	b = append(b, p.typ<<4|p.flags)
+	n := len(p.body)
+	for {
+		d := byte(n & 0x7F)
+		n >>= 7
+		if n > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, p.body...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// reader walks a packet body.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) uint16() uint16 {
+	if len(r.b) < 2 {
+		r.err = errors.New("mqtt: short packet")
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *reader) byte() byte {
+	if len(r.b) < 1 {
+		r.err = errors.New("mqtt: short packet")
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := int(r.uint16())
+	if r.err != nil || len(r.b) < n {
+		r.err = errors.New("mqtt: short packet")
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) string() string { return string(r.bytes()) }
+
+// ---- CONNECT ----
+
+type connect struct {
+	clientID  string
+	username  string
+	password  string
+	keepAlive uint16 // seconds
+	will      *Message
+}
+
+func (c connect) packet() packet {
+	var flags byte = connectClean
+	if c.will != nil {
+		flags |= connectWill | c.will.QoS<<3
+		if c.will.Retain {
+			flags |= connectWillRet
+		}
+	}
+	if c.username != "" {
+		flags |= connectUsername
+	}
+	if c.password != "" {
+		flags |= connectPassword
+	}
+
+	b := appendString(nil, "MQTT")
+	b = append(b, protocolLevel, flags)
+	b = binary.BigEndian.AppendUint16(b, c.keepAlive)
+	b = appendString(b, c.clientID)
+	if c.will != nil {
+		b = appendString(b, c.will.Topic)
+		b = appendString(b, string(c.will.Payload))
+	}
+	if c.username != "" {
+		b = appendString(b, c.username)
+	}
+	if c.password != "" {
+		b = appendString(b, c.password)
+	}
+	return packet{typ: typeConnect, body: b}
+}
+
+func parseConnect(p packet) (connect, error) {
+	r := reader{b: p.body}
+	if r.string() != "MQTT" || r.byte() != protocolLevel {
+		return connect{}, errors.New("mqtt: unsupported protocol")
+	}
+	flags := r.byte()
+	c := connect{keepAlive: r.uint16(), clientID: r.string()}
+	if flags&connectWill != 0 {
+		c.will = &Message{
+			Topic:   r.string(),
+			Payload: append([]byte(nil), r.bytes()...),
+			QoS:     flags >> 3 & 0x03,
+			Retain:  flags&connectWillRet != 0,
+		}
+	}
+	if flags&connectUsername != 0 {
+		c.username = r.string()
+	}
+	if flags&connectPassword != 0 {
+		c.password = r.string()
+	}
+	return c, r.err
+}
+
+// ---- PUBLISH ----
+
+func publishPacket(m Message, id uint16, dup bool) packet {
+	flags := m.QoS << 1
+	if m.Retain {
+		flags |= publishRetain
+	}
+	if dup {
+		flags |= publishDup
+	}
+	b := appendString(make([]byte, 0, len(m.Topic)+len(m.Payload)+4), m.Topic)
+	if m.QoS > 0 {
+		b = binary.BigEndian.AppendUint16(b, id)
+	}
+	return packet{typ: typePublish, flags: flags, body: append(b, m.Payload...)}
+}
+
+func parsePublish(p packet) (Message, uint16, error) {
+	r := reader{b: p.body}
+	m := Message{Topic: r.string(), QoS: p.flags >> 1 & 0x03, Retain: p.flags&publishRetain != 0}
+	var id uint16
+	if m.QoS > 0 {
+		id = r.uint16()
+	}
+	if r.err != nil {
+		return Message{}, 0, r.err
+	}
+	if m.QoS > 1 {
+		return Message{}, 0, fmt.Errorf("mqtt: qos %d not supported", m.QoS)
+	}
+	m.Payload = append([]byte(nil), r.b...)
+	return m, id, nil
+}
+
+func idPacket(typ byte, id uint16) packet {
+	return packet{typ: typ, body: binary.BigEndian.AppendUint16(nil, id)}
+}
@@ -0,0 +1,148 @@
+// internal/mqttpub/json.go
+package mqttpub
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/mqtt"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+// JSON topics, under TopicPrefix:
+//
+//	{prefix}/state                          "online" / "offline" (retained, will)
+//	{prefix}/{unit}/{area}/{address}        one message per read block
+//	{prefix}/{unit}/status                  health (retained)
+//
+// area is coil, discrete, holding or input.
+
+const (
+	stateOnline  = "online"
+	stateOffline = "offline"
+)
+
+type jsonFormat struct {
+	p *Publisher
+}
+
+func newJSON(p *Publisher) *jsonFormat { return &jsonFormat{p: p} }
+
+func (f *jsonFormat) stateTopic() string { return f.p.opt.TopicPrefix + "/state" }
+
+func (f *jsonFormat) will() *mqtt.Message {
+	return &mqtt.Message{Topic: f.stateTopic(), Payload: []byte(stateOffline), QoS: f.p.opt.QoS, Retain: true}
+}
+
+func (f *jsonFormat) connected() {
+	f.p.prepend(mqtt.Message{Topic: f.stateTopic(), Payload: []byte(stateOnline), QoS: f.p.opt.QoS, Retain: true})
+}
+
+func (f *jsonFormat) goodbye() []mqtt.Message { return []mqtt.Message{*f.will()} }
+
+func (f *jsonFormat) unit(id string) Unit {
+	return &jsonUnit{f: f, base: f.p.opt.TopicPrefix + "/" + id, id: id}
+}
+
+type jsonUnit struct {
+	f    *jsonFormat
+	base string
+	id   string
+
+	mu   sync.Mutex
+	last *jsonStatus
+}
+
+type jsonBlock struct {
+	Unit     string `json:"unit"`
+	At       string `json:"at"`
+	FC       uint8  `json:"fc"`
+	Address  uint16 `json:"address"`
+	Quantity uint16 `json:"quantity"`
+	Values   any    `json:"values"`
+}
+
+type jsonStatus struct {
+	Health         string `json:"health"`
+	HealthCode     uint16 `json:"health_code"`
+	LastErrorCode  uint16 `json:"last_error_code"`
+	SecondsInError uint16 `json:"seconds_in_error"`
+}
+
+// Write publishes every block of a successful poll. Failed polls are
+// reported through the status topic only.
+func (u *jsonUnit) Write(res poller.PollResult) error {
+	if res.Err != nil {
+		return nil
+	}
+	opt := u.f.p.opt
+	msgs := make([]mqtt.Message, 0, len(res.Blocks))
+	for _, b := range res.Blocks {
+		blk := jsonBlock{
+			Unit:     u.id,
+			At:       res.At.UTC().Format(time.RFC3339Nano),
+			FC:       b.FC,
+			Address:  b.Address,
+			Quantity: b.Quantity,
+			Values:   b.Registers,
+		}
+		if b.FC == 1 || b.FC == 2 {
+			blk.Values = b.Bits
+		}
+		payload, err := json.Marshal(blk)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, mqtt.Message{
+			Topic:   u.base + "/" + areaName(b.FC) + "/" + strconv.Itoa(int(b.Address)),
+			Payload: payload,
+			QoS:     opt.QoS,
+			Retain:  opt.Retain,
+		})
+	}
+	return u.f.p.enqueue(msgs...)
+}
+
+// WriteStatus publishes the unit's health when it changed.
+func (u *jsonUnit) WriteStatus(s status.Snapshot) error {
+	st := jsonStatus{
+		Health:         status.HealthName(s.Health),
+		HealthCode:     s.Health,
+		LastErrorCode:  s.LastErrorCode,
+		SecondsInError: s.SecondsInError,
+	}
+	u.mu.Lock()
+	same := u.last != nil && *u.last == st
+	u.last = &st
+	u.mu.Unlock()
+	if same {
+		return nil
+	}
+
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return u.f.p.enqueue(mqtt.Message{Topic: u.base + "/status", Payload: payload, QoS: u.f.p.opt.QoS, Retain: true})
+}
+
+// Close is a no-op: the last status (the shutdown health) stays retained.
+func (u *jsonUnit) Close() error { return nil }
+
+// areaName is the topic / metric name of an FC's memory area.
+func areaName(fc uint8) string {
+	switch fc {
+	case 1:
+		return "coil"
+	case 2:
+		return "discrete"
+	case 3:
+		return "holding"
+	case 4:
+		return "input"
+	}
+	return "fc" + strconv.Itoa(int(fc))
+}
@@ -0,0 +1,283 @@
+// internal/mqttpub/mqttpub_test.go
+package mqttpub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/mqtt"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+func startBroker(t *testing.T) (*mqtt.Broker, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := mqtt.NewBroker(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = b.ServeListener(ctx, ln)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return b, ln.Addr().String()
+}
+
+func next(t *testing.T, ch <-chan mqtt.Message) mqtt.Message {
+	t.Helper()
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(3 * time.Second):
+		t.Fatal("no message")
+		return mqtt.Message{}
+	}
+}
+
+func options(addr string) Options {
+	return Options{
+		Broker:      addr,
+		ClientID:    "rep",
+		KeepAlive:   30 * time.Second,
+		Timeout:     time.Second,
+		Reconnect:   10 * time.Millisecond,
+		QueueSize:   100,
+		TopicPrefix: "site",
+		GroupID:     "plant",
+		EdgeNodeID:  "rep",
+	}
+}
+
+var at = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func poll(regs ...uint16) poller.PollResult {
+	return poller.PollResult{
+		UnitID: "inv-1",
+		At:     at,
+		Blocks: []poller.BlockResult{
+			{FC: 1, Address: 0, Quantity: 2, Bits: []bool{true, false}},
+			{FC: 3, Address: 10, Quantity: uint16(len(regs)), Registers: regs},
+		},
+	}
+}
+
+func TestJSON_TopicsPayloadsAndState(t *testing.T) {
+	b, addr := startBroker(t)
+	got, cancel := b.Subscribe("site/#")
+	defer cancel()
+
+	p := New(options(addr), nil)
+	u := p.Unit("inv-1")
+
+	if m := next(t, got); m.Topic != "site/state" || string(m.Payload) != "online" || !m.Retain {
+		t.Fatalf("first message = %s %q retain=%v", m.Topic, m.Payload, m.Retain)
+	}
+
+	if err := u.Write(poll(7, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Write(poller.PollResult{UnitID: "inv-1", At: at, Err: errors.New("timeout")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`site/inv-1/coil/0 {"unit":"inv-1","at":"2026-03-01T12:00:00Z","fc":1,"address":0,"quantity":2,"values":[true,false]}`,
+		`site/inv-1/holding/10 {"unit":"inv-1","at":"2026-03-01T12:00:00Z","fc":3,"address":10,"quantity":2,"values":[7,8]}`,
+	} {
+		m := next(t, got)
+		if s := m.Topic + " " + string(m.Payload); s != want {
+			t.Fatalf("got  %s\nwant %s", s, want)
+		}
+	}
+
+	snap := status.Snapshot{Health: status.HealthError, LastErrorCode: 0x0B, SecondsInError: 3}
+	_ = u.WriteStatus(snap)
+	_ = u.WriteStatus(snap) // unchanged: not republished
+	m := next(t, got)
+	var st jsonStatus
+	if err := json.Unmarshal(m.Payload, &st); err != nil {
+		t.Fatal(err)
+	}
+	if m.Topic != "site/inv-1/status" || !m.Retain || st != (jsonStatus{"error", status.HealthError, 0x0B, 3}) {
+		t.Fatalf("status = %s %s retain=%v", m.Topic, m.Payload, m.Retain)
+	}
+
+	_ = u.Close()
+	_ = p.Close()
+	if m := next(t, got); m.Topic != "site/state" || string(m.Payload) != "offline" {
+		t.Fatalf("after the status: %s %q (status republished?)", m.Topic, m.Payload)
+	}
+	if m, _ := b.Retained("site/state"); string(m.Payload) != "offline" {
+		t.Fatalf("retained state = %q", m.Payload)
+	}
+}
+
+func TestJSON_QoS1ResentAfterReconnect(t *testing.T) {
+	b, addr := startBroker(t)
+	got, cancel := b.Subscribe("site/inv-1/#")
+	defer cancel()
+
+	opt := options(addr)
+	opt.QoS = 1
+	p := New(opt, nil)
+	defer p.Close()
+	u := p.Unit("inv-1")
+
+	waitFor(t, func() bool { return b.Connects() == 1 })
+	b.Hold(true) // swallowed: no PUBACK
+	_ = u.Write(poll(1))
+	time.Sleep(100 * time.Millisecond)
+	b.Hold(false)
+	b.DropClients()
+
+	m := next(t, got)
+	if m.Topic != "site/inv-1/coil/0" {
+		t.Fatalf("got %s", m.Topic)
+	}
+	next(t, got) // holding/10
+	if n := b.Connects(); n < 2 {
+		t.Fatalf("connects = %d, want a reconnect", n)
+	}
+}
+
+func TestPublisher_QueueDropsOldest(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing listens: everything queues
+
+	opt := options(addr)
+	opt.QueueSize = 3
+	opt.Reconnect = time.Hour
+	p := New(opt, nil)
+	u := p.Unit("inv-1")
+
+	if err := u.Write(poll(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Write(poll(2)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("err = %v, want ErrQueueFull", err)
+	}
+	if q, d := p.Queued(), p.Dropped(); q != 3 || d != 1 {
+		t.Fatalf("queued %d dropped %d, want 3 and 1", q, d)
+	}
+	_ = p.Close()
+}
+
+func TestSparkplug_BirthDataDeathAndRebirth(t *testing.T) {
+	b, addr := startBroker(t)
+	got, cancel := b.Subscribe("spBv1.0/plant/#")
+	defer cancel()
+
+	opt := options(addr)
+	opt.Sparkplug = true
+	p := New(opt, nil)
+	u := p.Unit("inv-1")
+
+	type msg struct {
+		topic string
+		p     Payload
+	}
+	read := func() msg {
+		t.Helper()
+		m := next(t, got)
+		pl, err := DecodePayload(m.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg{m.Topic, pl}
+	}
+	seq := func(m msg) int {
+		if m.p.Seq == nil {
+			return -1
+		}
+		return int(*m.p.Seq)
+	}
+	metrics := func(m msg) map[string]uint64 {
+		out := make(map[string]uint64)
+		for _, x := range m.p.Metrics {
+			out[x.Name] = x.Value
+		}
+		return out
+	}
+
+	m := read()
+	if m.topic != "spBv1.0/plant/NBIRTH/rep" || seq(m) != 0 || metrics(m)[metricBdSeq] != 0 {
+		t.Fatalf("NBIRTH: %s seq %d %v", m.topic, seq(m), metrics(m))
+	}
+
+	_ = u.Write(poll(7, 8))
+	m = read()
+	want := map[string]uint64{"coil/0": 1, "coil/1": 0, "holding/10": 7, "holding/11": 8}
+	if m.topic != "spBv1.0/plant/DBIRTH/rep/inv-1" || seq(m) != 1 || len(metrics(m)) != 4 {
+		t.Fatalf("DBIRTH: %s seq %d %v", m.topic, seq(m), metrics(m))
+	}
+	for k, v := range want {
+		if metrics(m)[k] != v {
+			t.Fatalf("DBIRTH %s = %d, want %d", k, metrics(m)[k], v)
+		}
+	}
+	if m.p.Metrics[0].Datatype != TypeBoolean || m.p.Metrics[2].Datatype != TypeUInt16 {
+		t.Fatalf("datatypes %d %d", m.p.Metrics[0].Datatype, m.p.Metrics[2].Datatype)
+	}
+
+	_ = u.Write(poll(7, 9))
+	m = read()
+	if m.topic != "spBv1.0/plant/DDATA/rep/inv-1" || seq(m) != 2 || len(m.p.Metrics) != 1 || metrics(m)["holding/11"] != 9 {
+		t.Fatalf("DDATA: %s seq %d %v", m.topic, seq(m), metrics(m))
+	}
+
+	_ = u.WriteStatus(status.Snapshot{Health: status.HealthError})
+	if m = read(); m.topic != "spBv1.0/plant/DDEATH/rep/inv-1" || seq(m) != 3 {
+		t.Fatalf("DDEATH: %s seq %d", m.topic, seq(m))
+	}
+	_ = u.Write(poll(7, 9))
+	if m = read(); m.topic != "spBv1.0/plant/DBIRTH/rep/inv-1" || seq(m) != 4 {
+		t.Fatalf("rebirth: %s seq %d", m.topic, seq(m))
+	}
+
+	// A lost session: the broker sends NDEATH (the will); the next
+	// session is a new bdSeq, sequence 0, and the live device is reborn.
+	b.DropClients()
+	if m = read(); m.topic != "spBv1.0/plant/NDEATH/rep" || seq(m) != -1 || metrics(m)[metricBdSeq] != 0 {
+		t.Fatalf("NDEATH: %s seq %d %v", m.topic, seq(m), metrics(m))
+	}
+	if m = read(); m.topic != "spBv1.0/plant/NBIRTH/rep" || seq(m) != 0 || metrics(m)[metricBdSeq] != 1 {
+		t.Fatalf("NBIRTH after reconnect: %s seq %d %v", m.topic, seq(m), metrics(m))
+	}
+	if m = read(); m.topic != "spBv1.0/plant/DBIRTH/rep/inv-1" || seq(m) != 1 || metrics(m)["holding/11"] != 9 {
+		t.Fatalf("DBIRTH after reconnect: %s seq %d %v", m.topic, seq(m), metrics(m))
+	}
+
+	_ = u.Close()
+	_ = p.Close()
+	if m = read(); m.topic != "spBv1.0/plant/DDEATH/rep/inv-1" || seq(m) != 2 {
+		t.Fatalf("close: %s seq %d", m.topic, seq(m))
+	}
+	if m = read(); m.topic != "spBv1.0/plant/NDEATH/rep" || metrics(m)[metricBdSeq] != 1 {
+		t.Fatalf("goodbye: %s %v", m.topic, metrics(m))
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,246 @@
+// internal/mqttpub/node.go
+package mqttpub
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/mqtt"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+// The replicator is one Sparkplug B edge node; every unit is a device.
+//
+//	spBv1.0/{group}/NBIRTH|NDEATH/{node}
+//	spBv1.0/{group}/DBIRTH|DDATA|DDEATH/{node}/{unit}
+//
+// NDEATH is the session's will and carries the bdSeq of the NBIRTH that
+// follows it. A device is born on its first good poll (all metrics),
+// reports changed metrics in DDATA and dies when its health leaves ok.
+// Metrics are named {area}/{address}: bits are Boolean, registers UInt16.
+//
+// A reconnect starts over: whatever was queued is discarded for a new
+// NBIRTH and a DBIRTH of every live device, so hosts never see a
+// sequence gap.
+
+const (
+	metricBdSeq   = "bdSeq"
+	metricRebirth = "Node Control/Rebirth"
+)
+
+type sparkplugFormat struct {
+	p *Publisher
+
+	// mu orders sequence numbers with the queue: hold it across enqueue.
+	mu      sync.Mutex
+	bdSeq   uint64 // of the session being dialed / current
+	seq     uint64
+	death   mqtt.Message
+	devices map[string]*device
+	dialed  bool
+}
+
+func newSparkplug(p *Publisher) *sparkplugFormat {
+	return &sparkplugFormat{p: p, devices: make(map[string]*device)}
+}
+
+func (f *sparkplugFormat) topic(typ string, dev string) string {
+	t := "spBv1.0/" + f.p.opt.GroupID + "/" + typ + "/" + f.p.opt.EdgeNodeID
+	if dev != "" {
+		t += "/" + dev
+	}
+	return t
+}
+
+func msToUint(t time.Time) uint64 { return uint64(t.UnixMilli()) }
+
+// nextSeq returns the sequence number of the next message (0–255).
+func (f *sparkplugFormat) nextSeq() *uint64 {
+	s := f.seq
+	f.seq = (f.seq + 1) % 256
+	return &s
+}
+
+func (f *sparkplugFormat) will() *mqtt.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dialed {
+		f.bdSeq = (f.bdSeq + 1) % 256
+	}
+	f.dialed = true
+	f.death = mqtt.Message{
+		Topic: f.topic("NDEATH", ""),
+		Payload: Payload{
+			Timestamp: msToUint(time.Now()),
+			Metrics:   []Metric{{Name: metricBdSeq, Datatype: TypeUInt64, Value: f.bdSeq}},
+		}.Encode(),
+		QoS: 1,
+	}
+	m := f.death
+	return &m
+}
+
+func (f *sparkplugFormat) connected() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq = 0
+	now := msToUint(time.Now())
+	msgs := []mqtt.Message{{
+		Topic: f.topic("NBIRTH", ""),
+		Payload: Payload{
+			Timestamp: now,
+			Metrics: []Metric{
+				{Name: metricBdSeq, Datatype: TypeUInt64, Value: f.bdSeq},
+				{Name: metricRebirth, Datatype: TypeBoolean, Value: 0},
+			},
+			Seq: f.nextSeq(),
+		}.Encode(),
+		QoS: f.p.opt.QoS,
+	}}
+	ids := make([]string, 0, len(f.devices))
+	for id, d := range f.devices {
+		if d.born {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		msgs = append(msgs, f.devices[id].birth())
+	}
+	f.p.replaceQueue(msgs...)
+}
+
+func (f *sparkplugFormat) goodbye() []mqtt.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return []mqtt.Message{f.death}
+}
+
+func (f *sparkplugFormat) unit(id string) Unit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := &device{f: f, id: id, values: make(map[string]Metric)}
+	// A reload builds the new unit before closing the old one; the old
+	// one's DDEATH still goes out, the registration is the new one's.
+	f.devices[id] = d
+	return d
+}
+
+type device struct {
+	f  *sparkplugFormat
+	id string
+
+	// guarded by f.mu
+	born   bool
+	names  []string // birth order
+	values map[string]Metric
+	at     time.Time
+}
+
+// birth is a DBIRTH of every known metric. Caller holds f.mu.
+func (d *device) birth() mqtt.Message {
+	metrics := make([]Metric, 0, len(d.names))
+	for _, n := range d.names {
+		metrics = append(metrics, d.values[n])
+	}
+	return d.message("DBIRTH", metrics)
+}
+
+func (d *device) message(typ string, metrics []Metric) mqtt.Message {
+	return mqtt.Message{
+		Topic: d.f.topic(typ, d.id),
+		Payload: Payload{
+			Timestamp: msToUint(d.at),
+			Metrics:   metrics,
+			Seq:       d.f.nextSeq(),
+		}.Encode(),
+		QoS: d.f.p.opt.QoS,
+	}
+}
+
+// update stores the poll's values and returns the metrics that changed.
+func (d *device) update(res poller.PollResult) []Metric {
+	d.at = res.At
+	ts := msToUint(res.At)
+	var changed []Metric
+	set := func(name string, typ uint32, v uint64) {
+		old, ok := d.values[name]
+		if !ok {
+			d.names = append(d.names, name)
+		}
+		m := Metric{Name: name, Timestamp: ts, Datatype: typ, Value: v}
+		d.values[name] = m
+		if !ok || old.Value != v {
+			changed = append(changed, m)
+		}
+	}
+	for _, b := range res.Blocks {
+		area := areaName(b.FC) + "/"
+		for i, bit := range b.Bits {
+			var v uint64
+			if bit {
+				v = 1
+			}
+			set(area+strconv.Itoa(int(b.Address)+i), TypeBoolean, v)
+		}
+		for i, r := range b.Registers {
+			set(area+strconv.Itoa(int(b.Address)+i), TypeUInt16, uint64(r))
+		}
+	}
+	return changed
+}
+
+// Write births the device on its first good poll, then sends changes.
+func (d *device) Write(res poller.PollResult) error {
+	if res.Err != nil {
+		return nil
+	}
+	f := d.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changed := d.update(res)
+	switch {
+	case !d.born:
+		d.born = true
+		return f.p.enqueue(d.birth())
+	case len(changed) > 0:
+		return f.p.enqueue(d.message("DDATA", changed))
+	}
+	return nil
+}
+
+// WriteStatus kills the device when its health leaves ok; the next good
+// poll births it again.
+func (d *device) WriteStatus(s status.Snapshot) error {
+	if s.Health == status.HealthOK {
+		return nil
+	}
+	f := d.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return d.die()
+}
+
+// Close sends DDEATH for a live device and forgets it.
+func (d *device) Close() error {
+	f := d.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.devices[d.id] == d {
+		delete(f.devices, d.id)
+	}
+	return d.die()
+}
+
+// die is DDEATH. Caller holds f.mu.
+func (d *device) die() error {
+	if !d.born {
+		return nil
+	}
+	d.born = false
+	d.at = time.Now()
+	return d.f.p.enqueue(d.message("DDEATH", nil))
+}
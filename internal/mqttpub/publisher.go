@@ -0,0 +1,316 @@
+// internal/mqttpub/publisher.go
+package mqttpub
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/logging"
+	"github.com/tamzrod/modbus-replicator/internal/mqtt"
+	"github.com/tamzrod/modbus-replicator/internal/writer"
+)
+
+// Options are the publisher settings; see config.MQTTConfig.
+type Options struct {
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+
+	QoS    byte
+	Retain bool // JSON data topics only
+
+	KeepAlive time.Duration
+	Timeout   time.Duration
+	Reconnect time.Duration // minimum time between connect attempts
+	QueueSize int
+
+	// Sparkplug selects Sparkplug B instead of JSON topics.
+	Sparkplug   bool
+	TopicPrefix string // JSON
+	GroupID     string // Sparkplug
+	EdgeNodeID  string // Sparkplug
+}
+
+// FromConfig converts the validated (defaulted) config section.
+func FromConfig(c config.MQTTConfig) Options {
+	return Options{
+		Broker:      c.Broker,
+		ClientID:    c.ClientID,
+		Username:    c.Username,
+		Password:    c.Password,
+		QoS:         byte(c.QoS),
+		Retain:      c.Retain,
+		KeepAlive:   time.Duration(c.KeepAliveMs) * time.Millisecond,
+		Timeout:     time.Duration(c.TimeoutMs) * time.Millisecond,
+		Reconnect:   time.Duration(c.ReconnectMs) * time.Millisecond,
+		QueueSize:   c.QueueSize,
+		Sparkplug:   c.Format == "sparkplug_b",
+		TopicPrefix: c.TopicPrefix,
+		GroupID:     c.GroupID,
+		EdgeNodeID:  c.EdgeNodeID,
+	}
+}
+
+// ErrQueueFull is returned by a write that pushed the oldest queued
+// messages out. The write itself is queued.
+var ErrQueueFull = errors.New("mqtt: queue full, oldest messages dropped")
+
+// Unit publishes one unit: data from Write, health from WriteStatus.
+// It goes into runner.Config.Outputs and StatusWriters; Close when the
+// unit is torn down.
+type Unit interface {
+	writer.Writer
+	writer.StatusWriter
+	Close() error
+}
+
+// format is what differs between JSON and Sparkplug B.
+type format interface {
+	// will is the last will of the next session.
+	will() *mqtt.Message
+	// connected queues whatever a new session starts with.
+	connected()
+	// goodbye is published before a clean DISCONNECT.
+	goodbye() []mqtt.Message
+	unit(id string) Unit
+}
+
+// Publisher owns one broker session shared by every unit. Units queue
+// messages without blocking their poll loop; one goroutine dials,
+// reconnects (at most every Reconnect) and sends the queue in order.
+//
+// QoS 1 messages stay queued until acknowledged and are resent with DUP
+// after a reconnect. When the queue is full the oldest are dropped.
+type Publisher struct {
+	opt Options
+	log *slog.Logger
+	fmt format
+
+	mu      sync.Mutex
+	queue   []item
+	nextID  uint64
+	dropped uint64
+	closing bool
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+type item struct {
+	id   uint64
+	msg  mqtt.Message
+	sent bool // handed to a connection before: resend as DUP
+}
+
+// New starts the publisher. Nothing is dialed synchronously; broker
+// availability is runtime state. log may be nil.
+func New(opt Options, log *slog.Logger) *Publisher {
+	p := &Publisher{
+		opt:  opt,
+		log:  logging.OrDiscard(log).With("mqtt", opt.Broker),
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	if opt.Sparkplug {
+		p.fmt = newSparkplug(p)
+	} else {
+		p.fmt = newJSON(p)
+	}
+	go p.run()
+	return p
+}
+
+// Unit returns the publisher of unit id.
+func (p *Publisher) Unit(id string) Unit { return p.fmt.unit(id) }
+
+// Queued is the number of messages waiting for the broker.
+func (p *Publisher) Queued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queue)
+}
+
+// Dropped is the number of messages lost to a full queue.
+func (p *Publisher) Dropped() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dropped
+}
+
+// enqueue appends msgs, dropping the oldest beyond QueueSize.
+func (p *Publisher) enqueue(msgs ...mqtt.Message) error {
+	p.mu.Lock()
+	for _, m := range msgs {
+		p.nextID++
+		p.queue = append(p.queue, item{id: p.nextID, msg: m})
+	}
+	over := len(p.queue) - p.opt.QueueSize
+	if p.opt.QueueSize > 0 && over > 0 {
+		p.queue = append(p.queue[:0], p.queue[over:]...)
+		p.dropped += uint64(over)
+	}
+	p.mu.Unlock()
+
+	p.poke()
+	if p.opt.QueueSize > 0 && over > 0 {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// replaceQueue discards everything queued for msgs (Sparkplug: a new
+// session starts from births; old sequence numbers are meaningless).
+func (p *Publisher) replaceQueue(msgs ...mqtt.Message) {
+	p.mu.Lock()
+	p.queue = p.queue[:0]
+	p.mu.Unlock()
+	_ = p.enqueue(msgs...)
+}
+
+// prepend queues msgs ahead of everything else.
+func (p *Publisher) prepend(msgs ...mqtt.Message) {
+	p.mu.Lock()
+	head := make([]item, 0, len(msgs)+len(p.queue))
+	for _, m := range msgs {
+		p.nextID++
+		head = append(head, item{id: p.nextID, msg: m})
+	}
+	p.queue = append(head, p.queue...)
+	p.mu.Unlock()
+	p.poke()
+}
+
+func (p *Publisher) poke() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Publisher) head() (item, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 {
+		return item{}, false
+	}
+	return p.queue[0], true
+}
+
+// settle removes the head if it is still id; otherwise marks it sent.
+func (p *Publisher) settle(id uint64, delivered bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 || p.queue[0].id != id {
+		return
+	}
+	if delivered || p.queue[0].msg.QoS == 0 {
+		p.queue = p.queue[1:]
+		return
+	}
+	p.queue[0].sent = true
+}
+
+// Close flushes the queue (for at most Timeout once connected), says
+// goodbye and disconnects. Call it after every unit has written its
+// final status. An unreachable broker gets one more connect attempt.
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	p.closing = true
+	p.mu.Unlock()
+	p.poke()
+	<-p.done
+	return nil
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	var conn *mqtt.Conn
+	var lastDial, flushBy time.Time
+	down, lastTry := false, false
+
+	for {
+		p.mu.Lock()
+		closing, empty := p.closing, len(p.queue) == 0
+		p.mu.Unlock()
+
+		if closing && conn != nil {
+			if flushBy.IsZero() {
+				flushBy = time.Now().Add(p.opt.Timeout)
+			}
+			if empty || time.Now().After(flushBy) {
+				for _, m := range p.fmt.goodbye() {
+					_ = conn.Publish(m, false)
+				}
+				conn.Close()
+				p.log.Info("mqtt disconnected")
+				return
+			}
+		}
+
+		if conn == nil {
+			switch {
+			case closing && lastTry:
+				return
+			case closing:
+				lastTry = true
+			case !lastDial.IsZero() && time.Since(lastDial) < p.opt.Reconnect:
+				// Sleep out the backoff; a Close cuts it short.
+				t := time.NewTimer(p.opt.Reconnect - time.Since(lastDial))
+				select {
+				case <-t.C:
+				case <-p.wake:
+					t.Stop()
+					continue
+				}
+			}
+			lastDial = time.Now()
+			c, err := mqtt.Dial(mqtt.Config{
+				Broker:    p.opt.Broker,
+				ClientID:  p.opt.ClientID,
+				Username:  p.opt.Username,
+				Password:  p.opt.Password,
+				KeepAlive: p.opt.KeepAlive,
+				Timeout:   p.opt.Timeout,
+				Will:      p.fmt.will(),
+			})
+			if err != nil {
+				if !down {
+					p.log.Warn("mqtt connect failed", "err", err)
+				}
+				down = true
+				continue
+			}
+			conn, down = c, false
+			p.log.Info("mqtt connected")
+			p.fmt.connected()
+			continue
+		}
+
+		it, ok := p.head()
+		if !ok {
+			select {
+			case <-p.wake:
+			case <-conn.Done():
+				p.log.Warn("mqtt connection lost", "err", conn.Err())
+				conn, down = nil, true
+			}
+			continue
+		}
+
+		err := conn.Publish(it.msg, it.sent)
+		p.settle(it.id, err == nil)
+		if err != nil {
+			if errors.Is(err, mqtt.ErrClosed) && conn.Err() != nil {
+				err = conn.Err()
+			}
+			p.log.Warn("mqtt connection lost", "err", err)
+			conn.Close()
+			conn, down = nil, true
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// internal/mqttpub/sparkplug.go
+package mqttpub
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Sparkplug B payloads are protobuf (org.eclipse.tahu.protobuf.Payload).
+// Only the fields the replicator uses are encoded:
+//
+//	Payload { uint64 timestamp = 1; repeated Metric metrics = 2; uint64 seq = 3; }
+//	Metric  { string name = 1; uint64 timestamp = 3; uint32 datatype = 4;
+//	          bool is_null = 7; uint32 int_value = 10; uint64 long_value = 11;
+//	          bool boolean_value = 14; }
+
+// Sparkplug B data types used here.
+const (
+	TypeUInt16  = 6
+	TypeUInt64  = 8
+	TypeBoolean = 11
+)
+
+// Metric is one Sparkplug metric.
+type Metric struct {
+	Name      string
+	Timestamp uint64 // ms since epoch; 0 omits it
+	Datatype  uint32
+	Value     uint64 // booleans are 0/1
+	IsNull    bool
+}
+
+// Payload is one Sparkplug B payload. Seq is omitted from NDEATH.
+type Payload struct {
+	Timestamp uint64
+	Metrics   []Metric
+	Seq       *uint64
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func appendTag(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wire))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// Encode returns the protobuf encoding of p.
+func (p Payload) Encode() []byte {
+	b := appendVarintField(nil, 1, p.Timestamp)
+	for _, m := range p.Metrics {
+		b = appendBytesField(b, 2, m.encode())
+	}
+	if p.Seq != nil {
+		b = appendVarintField(b, 3, *p.Seq)
+	}
+	return b
+}
+
+func (m Metric) encode() []byte {
+	b := appendBytesField(nil, 1, []byte(m.Name))
+	if m.Timestamp != 0 {
+		b = appendVarintField(b, 3, m.Timestamp)
+	}
+	b = appendVarintField(b, 4, uint64(m.Datatype))
+	switch {
+	case m.IsNull:
+		b = appendVarintField(b, 7, 1)
+	case m.Datatype == TypeBoolean:
+		b = appendVarintField(b, 14, m.Value)
+	case m.Datatype == TypeUInt64:
+		b = appendVarintField(b, 11, m.Value)
+	default:
+		b = appendVarintField(b, 10, m.Value)
+	}
+	return b
+}
+
+var errProto = errors.New("sparkplug: malformed payload")
+
+// DecodePayload parses a payload written by Encode (other fields are
+// skipped).
+func DecodePayload(b []byte) (Payload, error) {
+	var p Payload
+	err := fields(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			p.Timestamp = v
+		case 2:
+			m, err := decodeMetric(data)
+			if err != nil {
+				return err
+			}
+			p.Metrics = append(p.Metrics, m)
+		case 3:
+			seq := v
+			p.Seq = &seq
+		}
+		return nil
+	})
+	return p, err
+}
+
+func decodeMetric(b []byte) (Metric, error) {
+	var m Metric
+	err := fields(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			m.Name = string(data)
+		case 3:
+			m.Timestamp = v
+		case 4:
+			m.Datatype = uint32(v)
+		case 7:
+			m.IsNull = v != 0
+		case 10, 11, 14:
+			m.Value = v
+		}
+		return nil
+	})
+	return m, err
+}
+
+// fields walks protobuf fields: varints in v, length-delimited in data.
+func fields(b []byte, fn func(field int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 || tag>>3 > math.MaxInt32 {
+			return errProto
+		}
+		b = b[n:]
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return errProto
+			}
+			b = b[n:]
+			if err := fn(field, v, nil); err != nil {
+				return err
+			}
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errProto
+			}
+			data := b[n : n+int(l)]
+			b = b[n+int(l):]
+			if err := fn(field, 0, data); err != nil {
+				return err
+			}
+		case 1: // fixed64
+			if len(b) < 8 {
+				return errProto
+			}
+			b = b[8:]
+		case 5: // fixed32
+			if len(b) < 4 {
+				return errProto
+			}
+			b = b[4:]
+		default:
+			return errProto
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@
 package poller
 
 import (
+	"log/slog"
 	"time"
 
 	cfg "github.com/tamzrod/modbus-replicator/internal/config"
@@ -10,14 +11,23 @@ import (
 
 // Build constructs a Poller without touching the network.
 // No dialing at startup. Device availability is runtime state.
-func Build(u cfg.UnitConfig) (*Poller, func() error, error) {
+// log may be nil.
+func Build(u cfg.UnitConfig, log *slog.Logger) (*Poller, func() error, error) {
+	return BuildWith(u, log, nil)
+}
 
-	factory := func() (Client, error) {
-		return pmodbus.New(pmodbus.Config{
-			Endpoint: u.Source.Endpoint,
-			UnitID:   u.Source.UnitID,
-			Timeout:  time.Duration(u.Source.TimeoutMs) * time.Millisecond,
-		})
+// BuildWith is Build with the client factory supplied by the caller
+// (e.g. a replayed recording). factory nil dials u.Source over Modbus TCP.
+func BuildWith(u cfg.UnitConfig, log *slog.Logger, factory func() (Client, error)) (*Poller, func() error, error) {
+
+	if factory == nil {
+		factory = func() (Client, error) {
+			return pmodbus.New(pmodbus.Config{
+				Endpoint: u.Source.Endpoint,
+				UnitID:   u.Source.UnitID,
+				Timeout:  time.Duration(u.Source.TimeoutMs) * time.Millisecond,
+			})
+		}
 	}
 
 	reads := make([]ReadBlock, 0, len(u.Reads))
@@ -34,6 +44,7 @@ func Build(u cfg.UnitConfig) (*Poller, func() error, error) {
 			UnitID:   u.ID,
 			Interval: time.Duration(u.Poll.IntervalMs) * time.Millisecond,
 			Reads:    reads,
+			Logger:   log,
 		},
 		nil,      // no initial client
 		factory,  // lazy connection
@@ -0,0 +1,150 @@
+// internal/poller/e2e_test.go
+package poller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	cfg "github.com/tamzrod/modbus-replicator/internal/config"
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+	"github.com/tamzrod/modbus-replicator/internal/sim"
+)
+
+// End-to-end: Build's real Modbus TCP client against the simulator on
+// loopback. Covers the connection policy in PollOnce.
+
+func startSim(t *testing.T) (*sim.Server, string) {
+	t.Helper()
+	s, err := sim.New(sim.Config{UnitIDs: []uint8{1}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Memory().Set(sim.HoldingRegisters, 0, 11, 12, 13, 14)
+	_ = s.Memory().Set(sim.Coils, 0, 1, 1)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.ServeListener(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return s, ln.Addr().String()
+}
+
+func buildE2E(t *testing.T, addr string) *Poller {
+	t.Helper()
+	p, _, err := Build(cfg.UnitConfig{
+		ID:     "sim",
+		Source: cfg.SourceConfig{Endpoint: addr, UnitID: 1, TimeoutMs: 100},
+		Reads: []cfg.ReadConfig{
+			{FC: 1, Address: 0, Quantity: 2},
+			{FC: 3, Address: 0, Quantity: 4},
+		},
+		Poll: cfg.PollConfig{IntervalMs: 1000},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func mustPoll(t *testing.T, p *Poller) PollResult {
+	t.Helper()
+	res := p.PollOnce()
+	if res.Err != nil {
+		t.Fatalf("poll: %v", res.Err)
+	}
+	if got := res.Blocks[1].Registers; len(got) != 4 || got[0] != 11 || got[3] != 14 {
+		t.Fatalf("registers: %v", got)
+	}
+	return res
+}
+
+func TestE2E_PollsAndKeepsConnection(t *testing.T) {
+	s, addr := startSim(t)
+	p := buildE2E(t, addr)
+
+	for range 3 {
+		mustPoll(t, p)
+	}
+	if c := p.Counters(); c.ReconnectsTotal != 1 || c.ResponsesValidTotal != 3 {
+		t.Fatalf("counters: %+v", c)
+	}
+	if st := s.Stats(); st.Connections != 1 || st.Requests != 6 {
+		t.Fatalf("sim stats: %+v", st)
+	}
+}
+
+func TestE2E_ExceptionKeepsConnection(t *testing.T) {
+	s, addr := startSim(t)
+	p := buildE2E(t, addr)
+	mustPoll(t, p)
+
+	_ = s.AddFault(sim.Fault{Kind: sim.FaultException, FC: 3, Code: 6, Count: 1})
+	res := p.PollOnce()
+	var ex pmodbus.ModbusException
+	if !errors.As(res.Err, &ex) || ex.Exception != 6 {
+		t.Fatalf("want exception 6, got %v", res.Err)
+	}
+
+	mustPoll(t, p)
+	if st := s.Stats(); st.Connections != 1 {
+		t.Fatalf("exception must not reconnect: %+v", st)
+	}
+}
+
+// Each fault kills or desyncs the connection: the failing poll discards
+// the client and the next one reconnects and reads correct data.
+func TestE2E_ReconnectAfterFault(t *testing.T) {
+	for _, kind := range []string{sim.FaultDrop, sim.FaultHalfClose, sim.FaultTIDMismatch} {
+		t.Run(kind, func(t *testing.T) {
+			s, addr := startSim(t)
+			p := buildE2E(t, addr)
+			mustPoll(t, p)
+
+			_ = s.AddFault(sim.Fault{Kind: kind, Count: 1})
+			if res := p.PollOnce(); res.Err == nil {
+				t.Fatal("expected the faulted poll to fail")
+			}
+			mustPoll(t, p)
+			mustPoll(t, p)
+
+			if c := p.Counters(); c.ReconnectsTotal != 2 || c.ConsecutiveFailCurr != 0 {
+				t.Fatalf("counters: %+v", c)
+			}
+		})
+	}
+}
+
+// A reply arriving after the timeout is read by the next request on the
+// same connection. The transaction id check catches it; the poller must
+// then reconnect rather than stay one response behind forever.
+func TestE2E_LateReplyResyncs(t *testing.T) {
+	s, addr := startSim(t)
+	p := buildE2E(t, addr)
+	mustPoll(t, p)
+
+	_ = s.AddFault(sim.Fault{Kind: sim.FaultDelay, FC: 1, DelayMs: 150, Count: 1})
+	res := p.PollOnce()
+	var ne net.Error
+	if !errors.As(res.Err, &ne) || !ne.Timeout() {
+		t.Fatalf("want timeout, got %v", res.Err)
+	}
+	time.Sleep(100 * time.Millisecond) // late reply now sits in the socket
+
+	// The next poll reads the stale reply and fails; the one after is
+	// back in sync.
+	if res := p.PollOnce(); res.Err == nil {
+		t.Fatal("stale reply accepted")
+	}
+	mustPoll(t, p)
+	mustPoll(t, p)
+}
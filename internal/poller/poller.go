@@ -3,8 +3,11 @@ package poller
 
 import (
 	"errors"
+	"io"
+	"log/slog"
 	"net"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,6 +25,10 @@ type Config struct {
 	UnitID   string
 	Interval time.Duration
 	Reads    []ReadBlock
+
+	// Logger receives connection events and poll failures.
+	// nil discards.
+	Logger *slog.Logger
 }
 
 // Poller reads from a field device via a Client.
@@ -34,7 +41,9 @@ type Poller struct {
 	client  Client
 	factory func() (Client, error)
 
-	// Transport lifetime instrumentation (passive only)
+	// Transport lifetime instrumentation (passive only).
+	// Guarded by mu: Counters() is read from the runner goroutine.
+	mu       sync.Mutex
 	counters TransportCounters
 }
 
@@ -52,6 +61,10 @@ func New(cfg Config, client Client, factory func() (Client, error)) (*Poller, er
 		return nil, errors.New("poller: at least one read block required")
 	}
 
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
 	return &Poller{
 		cfg:     cfg,
 		client:  client,
@@ -67,9 +80,17 @@ func New(cfg Config, client Client, factory func() (Client, error)) (*Poller, er
 // - if client is nil, try to create once via factory
 // - on a "dead connection" error, discard client (so next tick can recreate)
 func (p *Poller) PollOnce() PollResult {
+	res := p.pollOnce()
+	res.Elapsed = time.Since(res.At)
+	return res
+}
+
+func (p *Poller) pollOnce() PollResult {
 
 	// Increment request attempt (one per poll cycle)
+	p.mu.Lock()
 	p.counters.RequestsTotal++
+	p.mu.Unlock()
 
 	res := PollResult{
 		UnitID: p.cfg.UnitID,
@@ -90,6 +111,13 @@ func (p *Poller) PollOnce() PollResult {
 			return res
 		}
 		p.client = c
+
+		p.mu.Lock()
+		p.counters.ReconnectsTotal++
+		n := p.counters.ReconnectsTotal
+		p.mu.Unlock()
+
+		p.cfg.Logger.Info("source connected", "connects", n)
 	}
 
 	var blocks []BlockResult
@@ -162,6 +190,9 @@ func (p *Poller) PollOnce() PollResult {
 
 // recordSuccess updates counters for a successful poll cycle.
 func (p *Poller) recordSuccess() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.counters.ResponsesValidTotal++
 	p.counters.ConsecutiveFailCurr = 0
 }
@@ -172,6 +203,9 @@ func (p *Poller) recordFailure(err error) {
 		return
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	// Classify timeout separately
 	var ne net.Error
 	if errors.As(err, &ne) && ne.Timeout() {
@@ -189,6 +223,8 @@ func (p *Poller) recordFailure(err error) {
 
 // Counters returns a snapshot copy of the transport counters.
 func (p *Poller) Counters() TransportCounters {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.counters
 }
 
@@ -211,6 +247,7 @@ func (p *Poller) maybeInvalidateClient(err error) {
 	}
 
 	p.client = nil
+	p.cfg.Logger.Warn("source connection dropped", "err", err)
 }
 
 // isDeadConnErr is a conservative classifier for transport-death errors.
@@ -245,5 +282,12 @@ func isDeadConnErr(err error) bool {
 		return true
 	}
 
+	// A reply for an earlier request (e.g. one that timed out) is still
+	// queued: the stream is out of step and every later read would get
+	// the previous answer.
+	if strings.Contains(s, "transaction id mismatch") {
+		return true
+	}
+
 	return false
 }
\ No newline at end of file
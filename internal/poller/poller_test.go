@@ -83,3 +83,37 @@ func TestPollOnce_Failure(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestPollOnce_ReconnectCountedAndElapsedSet(t *testing.T) {
+	cfg := Config{
+		UnitID:   "u1",
+		Interval: 1 * time.Second,
+		Reads:    []ReadBlock{{FC: 3, Address: 0, Quantity: 1}},
+	}
+
+	dials := 0
+	factory := func() (Client, error) {
+		dials++
+		return &fakeClient{}, nil
+	}
+
+	p, err := New(cfg, nil, factory)
+	if err != nil {
+		t.Fatalf("New() err=%v", err)
+	}
+
+	res := p.PollOnce()
+	if res.Err != nil {
+		t.Fatalf("PollOnce err=%v", res.Err)
+	}
+	if res.Elapsed < 0 {
+		t.Fatalf("negative elapsed: %v", res.Elapsed)
+	}
+
+	// Client is reused on the next cycle: no second connect.
+	p.PollOnce()
+
+	if c := p.Counters(); c.ReconnectsTotal != 1 || dials != 1 {
+		t.Fatalf("reconnects=%d dials=%d want 1/1", c.ReconnectsTotal, dials)
+	}
+}
@@ -3,12 +3,12 @@ package poller
 
 import (
 	"context"
-	"log"
 	"time"
 )
 
 func (p *Poller) Run(ctx context.Context, out chan<- PollResult) {
-	log.Println("poller: started")
+	log := p.cfg.Logger
+	log.Debug("poller started", "interval", p.cfg.Interval)
 
 	ticker := time.NewTicker(p.cfg.Interval)
 	defer ticker.Stop()
@@ -16,7 +16,7 @@ func (p *Poller) Run(ctx context.Context, out chan<- PollResult) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("poller: context done")
+			log.Debug("poller stopped")
 			return
 
 		case <-ticker.C:
@@ -25,9 +25,18 @@ func (p *Poller) Run(ctx context.Context, out chan<- PollResult) {
 			// NOTE:
 			// Per-tick success logging intentionally removed.
 			// Errors are surfaced via status memory and downstream handling.
-			// Silence on success prevents log flooding at scale.
+			// Silence on success prevents log flooding at scale;
+			// repeated failures are collapsed by the logging handler.
+			if res.Err != nil {
+				log.Warn("poll failed", "err", res.Err)
+			}
 
-			out <- res
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				log.Debug("poller stopped")
+				return
+			}
 		}
 	}
 }
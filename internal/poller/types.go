@@ -33,6 +33,10 @@ type PollResult struct {
 
 	Blocks []BlockResult
 	Err    error // non-nil means the poll cycle failed
+
+	// Elapsed is the wall time spent in the cycle (connect + reads).
+	// Observability only.
+	Elapsed time.Duration
 }
 
 // TransportCounters holds lifetime transport instrumentation
@@ -54,4 +58,8 @@ type TransportCounters struct {
 
 	ConsecutiveFailCurr uint16
 	ConsecutiveFailMax  uint16
+
+	// ReconnectsTotal counts client (re)connections made via the factory,
+	// including the first lazy connect. Not part of the status block.
+	ReconnectsTotal uint32
 }
\ No newline at end of file
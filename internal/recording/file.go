@@ -0,0 +1,181 @@
+// internal/recording/file.go
+package recording
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+)
+
+// Ext is the file extension of recordings; Path names them.
+const Ext = ".rec"
+
+// Path is the recording of unit inside dir.
+func Path(dir, unit string) string {
+	return filepath.Join(dir, unit+Ext)
+}
+
+// Recorder appends poll results to one unit's recording. Each record is
+// written with a single write, so a crash can at worst tear the last
+// record, which the next Open drops.
+type Recorder struct {
+	mu sync.Mutex
+	f  *os.File
+	c  codec
+}
+
+// Open opens (or creates) the recording at path for appending. An
+// existing file must belong to unit; its records are read once to
+// resume delta encoding, and a torn tail is truncated away.
+func Open(path, unit string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := resume(f, unit)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rec, nil
+}
+
+func resume(f *os.File, unit string) (*Recorder, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() == 0 {
+		if _, err := f.Write(appendHeader(nil, unit)); err != nil {
+			return nil, err
+		}
+		return &Recorder{f: f}, nil
+	}
+
+	cr := &countingReader{r: f}
+	r, err := NewReader(cr)
+	if err != nil {
+		return nil, err
+	}
+	if r.Unit() != unit {
+		return nil, fmt.Errorf("recording of unit %q, not %q", r.Unit(), unit)
+	}
+	good := cr.n - int64(r.br.Buffered())
+	// Keep everything before the end or the first damaged record.
+	for {
+		if _, err := r.Next(); err != nil {
+			break
+		}
+		good = cr.n - int64(r.br.Buffered())
+	}
+	if err := f.Truncate(good); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &Recorder{f: f, c: r.c}, nil
+}
+
+// Record appends res. It implements runner.Recorder.
+func (r *Recorder) Record(res poller.PollResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body := r.c.encode(res)
+	frame := binary.AppendUvarint(make([]byte, 0, len(body)+9), uint64(len(body)))
+	frame = append(frame, body...)
+	frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(body))
+	_, err := r.f.Write(frame)
+	return err
+}
+
+// Close closes the file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+func appendHeader(b []byte, unit string) []byte {
+	b = append(b, magic...)
+	b = append(b, version)
+	return appendString(b, unit)
+}
+
+// Reader reads a recording front to back.
+type Reader struct {
+	br   *bufio.Reader
+	unit string
+	c    codec
+}
+
+// NewReader checks the header of r.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var h [len(magic) + 1]byte
+	if _, err := io.ReadFull(br, h[:]); err != nil || string(h[:len(magic)]) != magic {
+		return nil, errors.New("recording: not a poll recording")
+	}
+	if h[len(magic)] != version {
+		return nil, fmt.Errorf("recording: version %d not supported", h[len(magic)])
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil || n > 1024 {
+		return nil, errors.New("recording: bad header")
+	}
+	unit := make([]byte, n)
+	if _, err := io.ReadFull(br, unit); err != nil {
+		return nil, errors.New("recording: bad header")
+	}
+	return &Reader{br: br, unit: string(unit)}, nil
+}
+
+// Unit is the unit id the recording was made for.
+func (r *Reader) Unit() string { return r.unit }
+
+// Next returns the next result, with UnitID set. It returns io.EOF at
+// the clean end, io.ErrUnexpectedEOF on a torn final record and
+// ErrCorrupt on a damaged one.
+func (r *Reader) Next() (poller.PollResult, error) {
+	n, err := binary.ReadUvarint(r.br)
+	if errors.Is(err, io.EOF) {
+		return poller.PollResult{}, io.EOF
+	}
+	if err != nil {
+		return poller.PollResult{}, io.ErrUnexpectedEOF
+	}
+	if n > maxRecord {
+		return poller.PollResult{}, ErrCorrupt
+	}
+	frame := make([]byte, n+4)
+	if _, err := io.ReadFull(r.br, frame); err != nil {
+		return poller.PollResult{}, io.ErrUnexpectedEOF
+	}
+	body := frame[:n]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(frame[n:]) {
+		return poller.PollResult{}, ErrCorrupt
+	}
+	res, err := r.c.decode(body)
+	res.UnitID = r.unit
+	return res, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
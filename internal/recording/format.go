@@ -0,0 +1,283 @@
+// internal/recording/format.go
+package recording
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+)
+
+// File layout (all integers varint unless noted):
+//
+//	header:  "RPOL" version(byte) len(unit id) unit id
+//	record:  len(body) body crc32(body, 4 bytes big-endian)
+//	body:    Δat(µs, signed) elapsed(µs) raw_error_code
+//	         err_kind(byte) [err]
+//	         n_blocks { fc(byte) address quantity data_kind(byte) [data] }
+//
+// Δat is relative to the previous record (the first to the Unix epoch).
+// A block whose data equals the same block of the previous record is
+// stored as data_kind=repeat without data: a steady device costs a few
+// bytes per block per poll. Bits are packed LSB first, registers
+// big-endian, exactly as on the wire.
+const (
+	magic   = "RPOL"
+	version = 1
+
+	errNone      = 0
+	errException = 1 // fc(byte) code(byte)
+	errTimeout   = 2 // len message
+	errOther     = 3 // len message
+
+	dataLiteral = 0
+	dataRepeat  = 1
+
+	// maxRecord bounds one record body: 4 areas' worth of max-size
+	// blocks would still be far below it.
+	maxRecord = 1 << 20
+)
+
+// ErrCorrupt marks a record that fails its checksum or does not decode.
+// A torn final record (crash while appending) is reported as
+// io.ErrUnexpectedEOF instead.
+var ErrCorrupt = errors.New("recording: corrupt record")
+
+// codec carries the previous record, which deltas refer to. Encoder
+// and decoder advance it identically.
+type codec struct {
+	prevAt time.Time
+	prev   []poller.BlockResult
+}
+
+func (c *codec) encode(res poller.PollResult) []byte {
+	delta := res.At.UnixMicro()
+	if !c.prevAt.IsZero() {
+		delta = res.At.Sub(c.prevAt).Microseconds()
+	}
+	b := binary.AppendVarint(nil, delta)
+	b = binary.AppendUvarint(b, uint64(max(0, res.Elapsed.Microseconds())))
+	b = binary.AppendUvarint(b, uint64(res.RawErrorCode))
+
+	var ex pmodbus.ModbusException
+	var te interface{ Timeout() bool }
+	switch {
+	case res.Err == nil:
+		b = append(b, errNone)
+	case errors.As(res.Err, &ex):
+		b = append(b, errException, ex.Function, ex.Exception)
+	case errors.As(res.Err, &te) && te.Timeout():
+		b = appendString(append(b, errTimeout), res.Err.Error())
+	default:
+		b = appendString(append(b, errOther), res.Err.Error())
+	}
+
+	b = binary.AppendUvarint(b, uint64(len(res.Blocks)))
+	for i, blk := range res.Blocks {
+		b = append(b, blk.FC)
+		b = binary.AppendUvarint(b, uint64(blk.Address))
+		b = binary.AppendUvarint(b, uint64(blk.Quantity))
+		if i < len(c.prev) && sameBlock(c.prev[i], blk) {
+			b = append(b, dataRepeat)
+			continue
+		}
+		b = append(b, dataLiteral)
+		b = appendData(b, blk)
+	}
+
+	c.prevAt, c.prev = res.At, res.Blocks
+	return b
+}
+
+func (c *codec) decode(body []byte) (poller.PollResult, error) {
+	d := decoder{b: body}
+	var res poller.PollResult
+
+	delta := d.varint()
+	if c.prevAt.IsZero() {
+		res.At = time.UnixMicro(delta)
+	} else {
+		res.At = c.prevAt.Add(time.Duration(delta) * time.Microsecond)
+	}
+	res.Elapsed = time.Duration(d.uvarint()) * time.Microsecond
+	res.RawErrorCode = uint16(d.uvarint())
+
+	switch d.byte() {
+	case errNone:
+	case errException:
+		res.Err = pmodbus.ModbusException{Function: d.byte(), Exception: d.byte()}
+	case errTimeout:
+		res.Err = timeoutError(d.string())
+	case errOther:
+		res.Err = errors.New(d.string())
+	default:
+		d.fail()
+	}
+
+	n := d.uvarint()
+	if n > uint64(len(body)) {
+		d.fail()
+	}
+	for i := 0; i < int(n) && d.err == nil; i++ {
+		blk := poller.BlockResult{FC: d.byte(), Address: uint16(d.uvarint()), Quantity: uint16(d.uvarint())}
+		switch d.byte() {
+		case dataRepeat:
+			if i >= len(c.prev) || !sameGeometry(c.prev[i], blk) {
+				d.fail()
+				break
+			}
+			blk.Bits, blk.Registers = c.prev[i].Bits, c.prev[i].Registers
+		case dataLiteral:
+			d.data(&blk)
+		default:
+			d.fail()
+		}
+		res.Blocks = append(res.Blocks, blk)
+	}
+	if d.err != nil || len(d.b) != 0 {
+		return poller.PollResult{}, ErrCorrupt
+	}
+
+	c.prevAt, c.prev = res.At, res.Blocks
+	return res, nil
+}
+
+func isBits(fc uint8) bool { return fc == 1 || fc == 2 }
+
+func sameGeometry(a, b poller.BlockResult) bool {
+	return a.FC == b.FC && a.Address == b.Address && a.Quantity == b.Quantity
+}
+
+func sameBlock(a, b poller.BlockResult) bool {
+	if !sameGeometry(a, b) || len(a.Bits) != len(b.Bits) || len(a.Registers) != len(b.Registers) {
+		return false
+	}
+	for i := range a.Bits {
+		if a.Bits[i] != b.Bits[i] {
+			return false
+		}
+	}
+	for i := range a.Registers {
+		if a.Registers[i] != b.Registers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendData(b []byte, blk poller.BlockResult) []byte {
+	if isBits(blk.FC) {
+		packed := make([]byte, (int(blk.Quantity)+7)/8)
+		for i, v := range blk.Bits {
+			if v && i < int(blk.Quantity) {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append(b, packed...)
+	}
+	for i := 0; i < int(blk.Quantity); i++ {
+		var v uint16
+		if i < len(blk.Registers) {
+			v = blk.Registers[i]
+		}
+		b = binary.BigEndian.AppendUint16(b, v)
+	}
+	return b
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+}
+
+// decoder reads a record body; the first failure sticks.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = ErrCorrupt
+	}
+	d.b = nil
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil || n < 0 || n > len(d.b) {
+		d.fail()
+		return nil
+	}
+	out := d.b[:n]
+	d.b = d.b[n:]
+	return out
+}
+
+func (d *decoder) byte() byte {
+	if b := d.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		d.fail()
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+func (d *decoder) data(blk *poller.BlockResult) {
+	q := int(blk.Quantity)
+	if isBits(blk.FC) {
+		packed := d.take((q + 7) / 8)
+		if packed == nil && q > 0 {
+			return
+		}
+		blk.Bits = make([]bool, q)
+		for i := range blk.Bits {
+			blk.Bits[i] = packed[i/8]&(1<<(i%8)) != 0
+		}
+		return
+	}
+	raw := d.take(2 * q)
+	if raw == nil && q > 0 {
+		return
+	}
+	blk.Registers = make([]uint16, q)
+	for i := range blk.Registers {
+		blk.Registers[i] = binary.BigEndian.Uint16(raw[2*i:])
+	}
+}
+
+// timeoutError is a replayed timeout: it keeps the message and still
+// classifies as a timeout (net.Error), so counters and reconnect policy
+// see what they saw live.
+type timeoutError string
+
+func (e timeoutError) Error() string   { return string(e) }
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
@@ -0,0 +1,200 @@
+// internal/recording/recording_test.go
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func result(at time.Duration, regs ...uint16) poller.PollResult {
+	return poller.PollResult{
+		UnitID:  "meter",
+		At:      t0.Add(at),
+		Elapsed: 3 * time.Millisecond,
+		Blocks: []poller.BlockResult{
+			{FC: 1, Address: 0, Quantity: 10, Bits: []bool{true, false, false, true, true, false, false, false, false, true}},
+			{FC: 3, Address: 100, Quantity: uint16(len(regs)), Registers: regs},
+		},
+	}
+}
+
+func failed(at time.Duration, err error) poller.PollResult {
+	return poller.PollResult{UnitID: "meter", At: t0.Add(at), Elapsed: 40 * time.Millisecond, Err: err}
+}
+
+func record(t *testing.T, path string, results ...poller.PollResult) {
+	t.Helper()
+	rec, err := Open(path, "meter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if err := rec.Record(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readAll(t *testing.T, path string) ([]poller.PollResult, error) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []poller.PollResult
+	for {
+		res, err := r.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, res)
+	}
+}
+
+func TestRecording_RoundTrip(t *testing.T) {
+	path := Path(t.TempDir(), "meter")
+	in := []poller.PollResult{
+		result(0, 1, 2, 3),
+		result(time.Second, 1, 2, 3),
+		failed(2*time.Second, pmodbus.ModbusException{Function: 3, Exception: 2}),
+		failed(3*time.Second, timeoutError("i/o timeout")),
+		failed(4*time.Second, errors.New("connection refused")),
+		result(5*time.Second, 1, 2, 4),
+	}
+	in[1].RawErrorCode = 7
+	record(t, path, in...)
+
+	out, err := readAll(t, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("read %d results, want %d", len(out), len(in))
+	}
+	for i := range in {
+		got, want := out[i], in[i]
+		if !got.At.Equal(want.At) || got.Elapsed != want.Elapsed || got.RawErrorCode != want.RawErrorCode ||
+			got.UnitID != want.UnitID || !reflect.DeepEqual(got.Blocks, want.Blocks) {
+			t.Fatalf("result %d:\ngot  %+v\nwant %+v", i, got, want)
+		}
+		if (got.Err == nil) != (want.Err == nil) || (got.Err != nil && got.Err.Error() != want.Err.Error()) {
+			t.Fatalf("result %d: err %v, want %v", i, got.Err, want.Err)
+		}
+	}
+
+	// Error kinds survive, so status codes are reproduced.
+	var ex pmodbus.ModbusException
+	if !errors.As(out[2].Err, &ex) || ex.Code() != in[2].Err.(pmodbus.ModbusException).Code() {
+		t.Fatalf("exception not preserved: %#v", out[2].Err)
+	}
+	var te interface{ Timeout() bool }
+	if !errors.As(out[3].Err, &te) || !te.Timeout() {
+		t.Fatalf("timeout not preserved: %#v", out[3].Err)
+	}
+}
+
+func TestRecording_SteadyDeviceIsCompact(t *testing.T) {
+	dir := t.TempDir()
+	regs := make([]uint16, 125)
+	for i := range regs {
+		regs[i] = uint16(i * 31)
+	}
+
+	var results []poller.PollResult
+	for i := 0; i < 1000; i++ {
+		results = append(results, result(time.Duration(i)*time.Second, regs...))
+	}
+	record(t, Path(dir, "meter"), results...)
+
+	st, err := os.Stat(Path(dir, "meter"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One literal record, then ~20 bytes per unchanged poll.
+	if st.Size() > 300+1000*24 {
+		t.Fatalf("1000 unchanged polls took %d bytes", st.Size())
+	}
+}
+
+func TestRecording_AppendsAcrossOpens(t *testing.T) {
+	path := Path(t.TempDir(), "meter")
+	record(t, path, result(0, 1), result(time.Second, 1))
+	record(t, path, result(2*time.Second, 1), result(3*time.Second, 2))
+
+	out, err := readAll(t, path)
+	if err != nil || len(out) != 4 {
+		t.Fatalf("read %d results, err %v", len(out), err)
+	}
+	if !out[2].At.Equal(t0.Add(2*time.Second)) || out[2].Blocks[1].Registers[0] != 1 || out[3].Blocks[1].Registers[0] != 2 {
+		t.Fatalf("appended results: %+v", out[2:])
+	}
+
+	if _, err := Open(path, "other"); err == nil {
+		t.Fatal("opened another unit's recording")
+	}
+}
+
+func TestRecording_TornTailIsDropped(t *testing.T) {
+	path := Path(t.TempDir(), "meter")
+	record(t, path, result(0, 1), result(time.Second, 2))
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b[:len(b)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := readAll(t, path); len(out) != 1 || err != io.ErrUnexpectedEOF {
+		t.Fatalf("torn file: %d results, err %v", len(out), err)
+	}
+
+	// Reopening cuts the torn record and appends cleanly after it.
+	record(t, path, result(2*time.Second, 3))
+	out, err := readAll(t, path)
+	if err != nil || len(out) != 2 || out[1].Blocks[1].Registers[0] != 3 {
+		t.Fatalf("after reopen: %+v, err %v", out, err)
+	}
+}
+
+func TestRecording_CorruptRecord(t *testing.T) {
+	path := Path(t.TempDir(), "meter")
+	record(t, path, result(0, 1), result(time.Second, 2), result(2*time.Second, 3))
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-6] ^= 0xFF // inside the last body
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := readAll(t, path); len(out) != 2 || !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("%d results, err %v", len(out), err)
+	}
+
+	if _, err := NewReader(bytes.NewReader([]byte("not a recording"))); err == nil {
+		t.Fatal("accepted a foreign file")
+	}
+}
@@ -0,0 +1,162 @@
+// internal/recording/replay.go
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+)
+
+// ErrEndOfRecording is returned by every read once the recording has
+// been played out.
+var ErrEndOfRecording = errors.New("replay: end of recording")
+
+// Replay is a poller.Client that answers from a recording instead of a
+// device. The recording's clock starts with the first read and runs at
+// speed times real time; each read answers from the latest recorded
+// result due by then, with its block data or its error. The last result
+// stays current for one recorded interval, then reads fail with
+// ErrEndOfRecording.
+//
+// Replay deliberately has no Close: the poller closes clients it thinks
+// are dead, and a replayed connection error must not end the replay.
+// Release closes the file.
+type Replay struct {
+	f     io.Closer
+	r     *Reader
+	speed float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	started bool
+	start   time.Time // wall time of the first read
+	first   time.Time // recorded time of the first result
+	cur     *poller.PollResult
+	next    *poller.PollResult
+	gap     time.Duration // between the last two results read
+	readErr error         // why the reader stopped
+}
+
+// OpenReplay opens the recording at path. speed 1 is the original
+// pace, 60 plays an hour per minute; <= 0 means 1.
+func OpenReplay(path string, speed float64) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewReplay(r, f, speed, nil), nil
+}
+
+// NewReplay plays r. closer (may be nil) is closed by Release; now nil
+// means time.Now.
+func NewReplay(r *Reader, closer io.Closer, speed float64, now func() time.Time) *Replay {
+	if speed <= 0 {
+		speed = 1
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &Replay{f: closer, r: r, speed: speed, now: now}
+}
+
+// Unit is the unit id the recording was made for.
+func (p *Replay) Unit() string { return p.r.Unit() }
+
+// Release closes the recording.
+func (p *Replay) Release() error {
+	if p.f == nil {
+		return nil
+	}
+	return p.f.Close()
+}
+
+// current returns the result due now.
+func (p *Replay) current() (*poller.PollResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.started {
+		p.started = true
+		p.start = p.now()
+		p.fetch()
+		if p.next != nil {
+			p.first = p.next.At
+		}
+	}
+
+	due := p.first.Add(time.Duration(float64(p.now().Sub(p.start)) * p.speed))
+	for p.next != nil && !p.next.At.After(due) {
+		if p.cur != nil {
+			p.gap = p.next.At.Sub(p.cur.At)
+		}
+		p.cur = p.next
+		p.fetch()
+	}
+
+	if p.cur == nil || (p.next == nil && due.Sub(p.cur.At) >= max(p.gap, time.Second)) {
+		if errors.Is(p.readErr, io.EOF) {
+			return nil, ErrEndOfRecording
+		}
+		return nil, fmt.Errorf("%w (%v)", ErrEndOfRecording, p.readErr)
+	}
+	return p.cur, nil
+}
+
+func (p *Replay) fetch() {
+	p.next = nil
+	res, err := p.r.Next()
+	if err != nil {
+		p.readErr = err
+		return
+	}
+	p.next = &res
+}
+
+// block finds the recorded block with the requested geometry.
+func (p *Replay) block(fc uint8, addr, qty uint16) (poller.BlockResult, error) {
+	res, err := p.current()
+	if err != nil {
+		return poller.BlockResult{}, err
+	}
+	if res.Err != nil {
+		return poller.BlockResult{}, res.Err
+	}
+	for _, b := range res.Blocks {
+		if b.FC == fc && b.Address == addr && b.Quantity == qty {
+			return b, nil
+		}
+	}
+	return poller.BlockResult{}, fmt.Errorf("replay: fc=%d addr=%d qty=%d not in the recording", fc, addr, qty)
+}
+
+// ---- poller.Client ----
+
+func (p *Replay) ReadCoils(addr, qty uint16) ([]bool, error) {
+	b, err := p.block(1, addr, qty)
+	return append([]bool(nil), b.Bits...), err
+}
+
+func (p *Replay) ReadDiscreteInputs(addr, qty uint16) ([]bool, error) {
+	b, err := p.block(2, addr, qty)
+	return append([]bool(nil), b.Bits...), err
+}
+
+func (p *Replay) ReadHoldingRegisters(addr, qty uint16) ([]uint16, error) {
+	b, err := p.block(3, addr, qty)
+	return append([]uint16(nil), b.Registers...), err
+}
+
+func (p *Replay) ReadInputRegisters(addr, qty uint16) ([]uint16, error) {
+	b, err := p.block(4, addr, qty)
+	return append([]uint16(nil), b.Registers...), err
+}
@@ -0,0 +1,149 @@
+// internal/recording/replay_test.go
+package recording
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+)
+
+// fakeClock is advanced by the test.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func openReplay(t *testing.T, path string, speed float64, clk *fakeClock) *Replay {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp := NewReplay(r, f, speed, clk.Now)
+	t.Cleanup(func() { rp.Release() })
+	return rp
+}
+
+func holding(t *testing.T, rp *Replay) (uint16, error) {
+	t.Helper()
+	regs, err := rp.ReadHoldingRegisters(100, 1)
+	if err != nil {
+		return 0, err
+	}
+	return regs[0], nil
+}
+
+func TestReplay_Pacing(t *testing.T) {
+	path := Path(t.TempDir(), "meter")
+	record(t, path, result(0, 10), result(10*time.Second, 20), result(20*time.Second, 30))
+
+	for _, speed := range []float64{1, 10} {
+		clk := &fakeClock{now: time.Unix(1000, 0)}
+		rp := openReplay(t, path, speed, clk)
+		step := time.Duration(float64(time.Second) / speed)
+
+		// Recorded second → expected value.
+		for _, c := range []struct {
+			at   int
+			want uint16
+		}{{0, 10}, {9, 10}, {10, 20}, {15, 20}, {20, 30}, {29, 30}} {
+			clk.now = time.Unix(1000, 0).Add(time.Duration(c.at) * step)
+			if got, err := holding(t, rp); err != nil || got != c.want {
+				t.Fatalf("speed %v, t=%ds: got %d, %v; want %d", speed, c.at, got, err, c.want)
+			}
+		}
+
+		// The last result lasts one recorded interval.
+		clk.now = time.Unix(1000, 0).Add(30 * step)
+		if _, err := holding(t, rp); !errors.Is(err, ErrEndOfRecording) {
+			t.Fatalf("speed %v: after the end: %v", speed, err)
+		}
+	}
+}
+
+func TestReplay_ReproducesFailures(t *testing.T) {
+	path := Path(t.TempDir(), "meter")
+	record(t, path,
+		result(0, 1),
+		failed(time.Second, pmodbus.ModbusException{Function: 3, Exception: 4}),
+		failed(2*time.Second, timeoutError("read tcp: i/o timeout")),
+		result(3*time.Second, 2),
+	)
+
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	rp := openReplay(t, path, 1, clk)
+
+	if got, err := holding(t, rp); err != nil || got != 1 {
+		t.Fatalf("t=0: %d, %v", got, err)
+	}
+
+	clk.advance(time.Second)
+	var ex pmodbus.ModbusException
+	if _, err := rp.ReadCoils(0, 10); !errors.As(err, &ex) || ex.Exception != 4 {
+		t.Fatalf("t=1: %v", err)
+	}
+
+	clk.advance(time.Second)
+	var te interface{ Timeout() bool }
+	if _, err := holding(t, rp); !errors.As(err, &te) || !te.Timeout() {
+		t.Fatalf("t=2: %v", err)
+	}
+
+	clk.advance(time.Second)
+	bits, err := rp.ReadCoils(0, 10)
+	if err != nil || len(bits) != 10 || !bits[0] {
+		t.Fatalf("t=3: %v, %v", bits, err)
+	}
+	if _, err := rp.ReadInputRegisters(100, 1); err == nil {
+		t.Fatal("read a block that was never recorded")
+	}
+}
+
+// A replay drives the real poller: every (re)connect gets the same
+// replay, and the results carry the recorded data and errors.
+func TestReplay_DrivesPoller(t *testing.T) {
+	path := Path(t.TempDir(), "meter")
+	record(t, path,
+		result(0, 5),
+		failed(time.Second, errors.New("read tcp: connection reset by peer")),
+		result(2*time.Second, 6),
+	)
+
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	rp := openReplay(t, path, 1, clk)
+	p, err := poller.New(poller.Config{
+		UnitID:   "meter",
+		Interval: time.Second,
+		Reads:    []poller.ReadBlock{{FC: 1, Address: 0, Quantity: 10}, {FC: 3, Address: 100, Quantity: 1}},
+	}, nil, func() (poller.Client, error) { return rp, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		res := p.PollOnce()
+		switch {
+		case res.Err != nil:
+			got = append(got, "err")
+		default:
+			got = append(got, string(rune('0'+res.Blocks[1].Registers[0])))
+		}
+		clk.advance(time.Second)
+	}
+	if want := []string{"5", "err", "6", "err"}; !slices.Equal(got, want) {
+		t.Fatalf("polled %v, want %v", got, want)
+	}
+	if c := p.Counters(); c.ReconnectsTotal != 2 {
+		t.Fatalf("reconnects %d, want 2", c.ReconnectsTotal)
+	}
+}
@@ -0,0 +1,31 @@
+// internal/runner/clock.go
+package runner
+
+import "time"
+
+// Clock abstracts the runner's 1 Hz tick.
+// Production code uses SystemClock; tests inject a manual clock
+// so that seconds-in-error accounting is fully deterministic.
+type Clock interface {
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the subset of *time.Ticker the runner depends on.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the real clock backed by package time.
+type SystemClock struct{}
+
+func (SystemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{t: time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (s systemTicker) C() <-chan time.Time { return s.t.C }
+func (s systemTicker) Stop()               { s.t.Stop() }
@@ -0,0 +1,340 @@
+// internal/runner/runner.go
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/metrics"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+	"github.com/tamzrod/modbus-replicator/internal/writer"
+)
+
+// Source is what the runner needs from a poller.
+// *poller.Poller satisfies it.
+type Source interface {
+	Run(ctx context.Context, out chan<- poller.PollResult)
+	Counters() poller.TransportCounters
+}
+
+// Recorder keeps a copy of every poll result, before it is written.
+// *recording.Recorder satisfies it.
+type Recorder interface {
+	Record(res poller.PollResult) error
+}
+
+// Config wires one unit pipeline.
+type Config struct {
+	UnitID string
+
+	Source        Source
+	Writer        writer.Writer
+	StatusWriters []writer.StatusWriter
+
+	// DeliveryWriters receive the per-target delivery record of Writer
+	// (when it implements writer.OutcomeReporter) after every poll it
+	// delivered. They never see the status snapshot, and status never
+	// sees delivery.
+	DeliveryWriters []writer.DeliveryWriter
+
+	// Outputs are further sinks next to Writer (e.g. the historian).
+	// Each gets every result after Writer; a failure is logged and
+	// affects neither status nor the other outputs.
+	Outputs []writer.Writer
+
+	// Recorder, when set, receives every poll result. A failed record
+	// is logged and does not affect the data path.
+	Recorder Recorder
+
+	// Clock drives the 1 Hz seconds-in-error / seconds-since-good tick.
+	// nil means SystemClock.
+	Clock Clock
+
+	// ShutdownHealth, when set, is asserted to every status target
+	// after the loop exits, so targets can tell a deliberate stop
+	// from a crash. nil leaves the last snapshot in place.
+	ShutdownHealth *uint16
+
+	// Logger receives lifecycle and health transition entries.
+	// nil discards.
+	Logger *slog.Logger
+}
+
+// Runner owns the per-unit orchestration loop:
+// poll results in, data + status writes out.
+//
+// Lifecycle is explicit: New → Start → Stop.
+type Runner struct {
+	cfg Config
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	st state
+
+	// info is the published view for observers (HTTP API).
+	// The loop is the only writer; infoMu guards cross-goroutine reads.
+	infoMu sync.Mutex
+	info   Info
+}
+
+// Info is a point-in-time view of one unit for observability.
+// It never feeds back into polling, writing or status.
+type Info struct {
+	UnitID   string
+	Snapshot status.Snapshot
+	Counters poller.TransportCounters
+
+	// LastPoll is nil until the first poll result arrives.
+	LastPoll *poller.PollResult
+
+	// PollLatency accumulates PollResult.Elapsed over the unit's lifetime.
+	PollLatency metrics.Histogram
+
+	// Targets is the per-target data delivery record, when the writer
+	// implements writer.OutcomeReporter.
+	Targets []writer.TargetOutcome
+}
+
+// New validates cfg and returns a stopped runner.
+func New(cfg Config) (*Runner, error) {
+	if cfg.UnitID == "" {
+		return nil, errors.New("runner: unit id required")
+	}
+	if cfg.Source == nil {
+		return nil, errors.New("runner: source required")
+	}
+	if cfg.Writer == nil {
+		return nil, errors.New("runner: writer required")
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock{}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	st := newState()
+
+	return &Runner{
+		cfg:  cfg,
+		st:   st,
+		info: Info{UnitID: cfg.UnitID, Snapshot: st.snap},
+	}, nil
+}
+
+// Start launches the poller and the orchestration loop.
+// It returns immediately; a runner can be started only once.
+func (r *Runner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		return errors.New("runner: already started")
+	}
+	r.started = true
+
+	ctx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+
+	out := make(chan poller.PollResult)
+	done := make(chan struct{})
+	r.done = done
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		r.cfg.Source.Run(ctx, out)
+	}()
+	go func() {
+		defer wg.Done()
+		r.loop(ctx, out)
+		r.writeShutdownStatus()
+	}()
+	go func() {
+		wg.Wait()
+		r.cfg.Logger.Info("unit stopped")
+		close(done)
+	}()
+
+	r.cfg.Logger.Info("unit started")
+	return nil
+}
+
+// Stop cancels the runner and waits for the poller, any in-flight write
+// and the final shutdown status write to finish.
+//
+// ctx bounds the wait: if it expires first, Stop returns ctx.Err() and
+// the remaining work is abandoned to process exit.
+// Stop on a runner that was never started is a no-op.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) loop(ctx context.Context, out <-chan poller.PollResult) {
+	secTicker := r.cfg.Clock.NewTicker(time.Second)
+	defer secTicker.Stop()
+
+	// initial full assert
+	r.writeStatus()
+	r.writeDelivery()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case res := <-out:
+			if r.cfg.Recorder != nil {
+				if err := r.cfg.Recorder.Record(res); err != nil {
+					r.cfg.Logger.Warn("recording failed", "err", err)
+				}
+			}
+
+			// Per-target failures are logged by the writer itself.
+			_ = r.cfg.Writer.Write(res)
+			if res.Err == nil {
+				r.writeDelivery()
+			}
+			for _, o := range r.cfg.Outputs {
+				if err := o.Write(res); err != nil {
+					r.cfg.Logger.Warn("output failed", "err", err)
+				}
+			}
+
+			prev := r.st.snap.Health
+			counters := r.cfg.Source.Counters()
+			if r.st.observe(res, counters) {
+				r.writeStatus()
+			}
+			r.logTransition(prev, res.Err)
+			r.publish(&res, counters)
+
+		case now := <-secTicker.C():
+			if r.st.tick(now) {
+				r.writeStatus()
+				r.publish(nil, poller.TransportCounters{})
+			}
+		}
+	}
+}
+
+// logTransition records health changes only; steady state is silent.
+func (r *Runner) logTransition(prev uint16, err error) {
+	cur := r.st.snap.Health
+	if cur == prev {
+		return
+	}
+	if cur == status.HealthOK {
+		r.cfg.Logger.Info("health changed", "from", status.HealthName(prev), "to", status.HealthName(cur))
+		return
+	}
+	r.cfg.Logger.Warn("health changed",
+		"from", status.HealthName(prev),
+		"to", status.HealthName(cur),
+		"error_code", r.st.snap.LastErrorCode,
+		"err", err,
+	)
+}
+
+// SetShutdownHealth replaces the health asserted on Stop.
+// It lets a config reload change the shutdown status without
+// restarting the unit.
+func (r *Runner) SetShutdownHealth(h uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cfg.ShutdownHealth = &h
+}
+
+// writeShutdownStatus asserts the configured shutdown health once.
+func (r *Runner) writeShutdownStatus() {
+	r.mu.Lock()
+	h := r.cfg.ShutdownHealth
+	r.mu.Unlock()
+
+	if h == nil {
+		return
+	}
+	r.st.snap.Health = *h
+	r.writeStatus()
+	r.publish(nil, poller.TransportCounters{})
+}
+
+// publish refreshes the observer view.
+// res == nil keeps the previous poll result and counters.
+func (r *Runner) publish(res *poller.PollResult, c poller.TransportCounters) {
+	var targets []writer.TargetOutcome
+	if res != nil {
+		if rep, ok := r.cfg.Writer.(writer.OutcomeReporter); ok {
+			targets = rep.Outcomes()
+		}
+	}
+
+	r.infoMu.Lock()
+	defer r.infoMu.Unlock()
+
+	r.info.Snapshot = r.st.snap
+	if res != nil {
+		r.info.LastPoll = res
+		r.info.Counters = c
+		r.info.Targets = targets
+		r.info.PollLatency.Observe(res.Elapsed)
+	}
+}
+
+// Info returns the latest published view of the unit.
+func (r *Runner) Info() Info {
+	r.infoMu.Lock()
+	defer r.infoMu.Unlock()
+
+	out := r.info
+	out.Targets = append([]writer.TargetOutcome(nil), r.info.Targets...)
+	return out
+}
+
+// writeStatus fans the current snapshot out to every target.
+// Status write failures are owned by the status writer (re-assert path).
+func (r *Runner) writeStatus() {
+	snap := r.st.snap
+	for _, sw := range r.cfg.StatusWriters {
+		_ = sw.WriteStatus(snap)
+	}
+}
+
+// writeDelivery fans the writer's per-target delivery record out to
+// every delivery block. Failures are owned by the delivery writer.
+func (r *Runner) writeDelivery() {
+	if len(r.cfg.DeliveryWriters) == 0 {
+		return
+	}
+	rep, ok := r.cfg.Writer.(writer.OutcomeReporter)
+	if !ok {
+		return
+	}
+	d := writer.DeliveryOf(rep.Outcomes())
+	for _, dw := range r.cfg.DeliveryWriters {
+		_ = dw.WriteDelivery(d)
+	}
+}
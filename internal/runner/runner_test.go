@@ -0,0 +1,593 @@
+// internal/runner/runner_test.go
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+	"github.com/tamzrod/modbus-replicator/internal/writer"
+)
+
+// ------------------------------------------------------------------
+// Fakes
+// ------------------------------------------------------------------
+
+type codedErr struct{ code uint16 }
+
+func (e codedErr) Error() string { return fmt.Sprintf("coded error %d", e.code) }
+func (e codedErr) Code() uint16  { return e.code }
+
+// manualClock hands out a single manual ticker that the test fires.
+type manualClock struct {
+	ticker *manualTicker
+}
+
+type manualTicker struct {
+	c chan time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{ticker: &manualTicker{c: make(chan time.Time)}}
+}
+
+func (m *manualClock) NewTicker(time.Duration) Ticker { return m.ticker }
+func (t *manualTicker) C() <-chan time.Time           { return t.c }
+func (t *manualTicker) Stop()                         {}
+func (m *manualClock) fire()                          { m.ticker.c <- time.Unix(0, 0) }
+
+// fakeSource forwards results pushed by the test into the runner.
+type fakeSource struct {
+	in       chan poller.PollResult
+	counters poller.TransportCounters
+	exited   chan struct{}
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		in:     make(chan poller.PollResult),
+		exited: make(chan struct{}),
+	}
+}
+
+func (f *fakeSource) Run(ctx context.Context, out chan<- poller.PollResult) {
+	defer close(f.exited)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case res := <-f.in:
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (f *fakeSource) Counters() poller.TransportCounters { return f.counters }
+
+type nopWriter struct{}
+
+func (nopWriter) Write(poller.PollResult) error { return nil }
+
+// recordingStatusWriter publishes every snapshot it receives.
+type recordingStatusWriter struct {
+	got chan status.Snapshot
+}
+
+func (w *recordingStatusWriter) WriteStatus(s status.Snapshot) error {
+	w.got <- s
+	return nil
+}
+
+// ------------------------------------------------------------------
+// State machine: replay poll-outcome sequences against the spec
+// ------------------------------------------------------------------
+
+type step struct {
+	poll *error // nil => tick; non-nil => poll outcome (*poll may be nil for success)
+
+	wantHealth  uint16
+	wantCode    uint16
+	wantSeconds uint16
+	wantChanged bool
+}
+
+func ok() *error            { var e error; return &e }
+func fail(err error) *error { return &err }
+
+func TestState_Replay(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "boot state ticks while unknown",
+			steps: []step{
+				{poll: nil, wantHealth: status.HealthUnknown, wantSeconds: 1, wantChanged: true},
+				{poll: nil, wantHealth: status.HealthUnknown, wantSeconds: 2, wantChanged: true},
+			},
+		},
+		{
+			name: "success resets immediately without hysteresis",
+			steps: []step{
+				{poll: fail(codedErr{code: 2}), wantHealth: status.HealthError, wantCode: 2, wantChanged: true},
+				{poll: nil, wantHealth: status.HealthError, wantCode: 2, wantSeconds: 1, wantChanged: true},
+				{poll: nil, wantHealth: status.HealthError, wantCode: 2, wantSeconds: 2, wantChanged: true},
+				{poll: ok(), wantHealth: status.HealthOK, wantChanged: true},
+				{poll: nil, wantHealth: status.HealthOK, wantChanged: false},
+			},
+		},
+		{
+			name: "repeated identical failure does not change snapshot",
+			steps: []step{
+				{poll: fail(codedErr{code: 4}), wantHealth: status.HealthError, wantCode: 4, wantChanged: true},
+				{poll: fail(codedErr{code: 4}), wantHealth: status.HealthError, wantCode: 4, wantChanged: false},
+			},
+		},
+		{
+			name: "error code follows the latest failure",
+			steps: []step{
+				{poll: fail(codedErr{code: 4}), wantHealth: status.HealthError, wantCode: 4, wantChanged: true},
+				{poll: nil, wantHealth: status.HealthError, wantCode: 4, wantSeconds: 1, wantChanged: true},
+				{poll: fail(codedErr{code: 11}), wantHealth: status.HealthError, wantCode: 11, wantSeconds: 1, wantChanged: true},
+			},
+		},
+		{
+			name: "uncoded error falls back to 1",
+			steps: []step{
+				{poll: fail(errors.New("dial tcp: refused")), wantHealth: status.HealthError, wantCode: 1, wantChanged: true},
+			},
+		},
+		{
+			name: "wrapped coded error is unwrapped",
+			steps: []step{
+				{poll: fail(fmt.Errorf("read: %w", codedErr{code: 3})), wantHealth: status.HealthError, wantCode: 3, wantChanged: true},
+			},
+		},
+		{
+			name: "ok after ok is unchanged",
+			steps: []step{
+				{poll: ok(), wantHealth: status.HealthOK, wantChanged: true},
+				{poll: ok(), wantHealth: status.HealthOK, wantChanged: false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newState()
+
+			for i, s := range tt.steps {
+				var changed bool
+				if s.poll == nil {
+					changed = st.tick(time.Time{})
+				} else {
+					changed = st.observe(poller.PollResult{Err: *s.poll}, poller.TransportCounters{})
+				}
+
+				if changed != s.wantChanged {
+					t.Fatalf("step %d: changed=%v want=%v", i, changed, s.wantChanged)
+				}
+				if st.snap.Health != s.wantHealth {
+					t.Fatalf("step %d: health=%d want=%d", i, st.snap.Health, s.wantHealth)
+				}
+				if st.snap.LastErrorCode != s.wantCode {
+					t.Fatalf("step %d: code=%d want=%d", i, st.snap.LastErrorCode, s.wantCode)
+				}
+				if st.snap.SecondsInError != s.wantSeconds {
+					t.Fatalf("step %d: seconds=%d want=%d", i, st.snap.SecondsInError, s.wantSeconds)
+				}
+			}
+		})
+	}
+}
+
+func TestState_SecondsInErrorSaturates(t *testing.T) {
+	st := newState()
+	st.observe(poller.PollResult{Err: errors.New("down")}, poller.TransportCounters{})
+	st.snap.SecondsInError = 65534
+
+	if !st.tick(time.Time{}) {
+		t.Fatalf("expected change on tick to 65535")
+	}
+	if st.tick(time.Time{}) {
+		t.Fatalf("expected no change once saturated")
+	}
+	if st.snap.SecondsInError != 65535 {
+		t.Fatalf("seconds=%d want=65535", st.snap.SecondsInError)
+	}
+}
+
+func TestState_CountersInjected(t *testing.T) {
+	st := newState()
+	st.observe(poller.PollResult{}, poller.TransportCounters{})
+
+	c := poller.TransportCounters{
+		RequestsTotal:        10,
+		ResponsesValidTotal:  8,
+		TimeoutsTotal:        1,
+		TransportErrorsTotal: 1,
+		ConsecutiveFailCurr:  0,
+		ConsecutiveFailMax:   2,
+	}
+
+	if !st.observe(poller.PollResult{}, c) {
+		t.Fatalf("expected counter change to be reported")
+	}
+	if st.snap.RequestsTotal != 10 || st.snap.ResponsesValidTotal != 8 ||
+		st.snap.TimeoutsTotal != 1 || st.snap.TransportErrorsTotal != 1 ||
+		st.snap.ConsecutiveFailMax != 2 {
+		t.Fatalf("counters not copied: %+v", st.snap)
+	}
+	if st.observe(poller.PollResult{}, c) {
+		t.Fatalf("expected no change for identical counters")
+	}
+}
+
+func TestState_Diagnostics(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	st := newState()
+	if st.snap.SecondsSinceGood != 65535 || st.snap.LastSuccessEpoch != 0 {
+		t.Fatalf("initial diagnostics: %+v", st.snap)
+	}
+
+	// No good poll yet: seconds-since-good stays saturated.
+	st.observe(poller.PollResult{At: at, Err: errors.New("down"), Elapsed: 40 * time.Millisecond}, poller.TransportCounters{})
+	st.tick(at.Add(time.Second))
+	if st.snap.SecondsSinceGood != 65535 || st.snap.LastPollDurationMs != 40 {
+		t.Fatalf("before first good poll: %+v", st.snap)
+	}
+
+	if !st.observe(poller.PollResult{At: at.Add(2 * time.Second), Elapsed: 70 * time.Second}, poller.TransportCounters{}) {
+		t.Fatalf("expected diagnostics change to be reported")
+	}
+	if st.snap.SecondsSinceGood != 0 || st.snap.LastSuccessEpoch != uint32(at.Unix()+2) || st.snap.LastPollDurationMs != 65535 {
+		t.Fatalf("after good poll: %+v", st.snap)
+	}
+
+	// Healthy between polls: it still counts from the good poll.
+	if !st.tick(at.Add(4*time.Second + 500*time.Millisecond)) {
+		t.Fatalf("expected seconds-since-good to move while healthy")
+	}
+	if st.snap.SecondsSinceGood != 2 || st.snap.SecondsInError != 0 {
+		t.Fatalf("healthy between polls: %+v", st.snap)
+	}
+
+	st.observe(poller.PollResult{At: at.Add(5 * time.Second), Err: errors.New("down")}, poller.TransportCounters{})
+	st.tick(at.Add(6 * time.Second))
+	st.tick(at.Add(7 * time.Second))
+	if st.snap.SecondsSinceGood != 5 || st.snap.SecondsInError != 2 || st.snap.LastSuccessEpoch != uint32(at.Unix()+2) || st.snap.LastPollDurationMs != 0 {
+		t.Fatalf("in error: %+v", st.snap)
+	}
+
+	// A good poll resets it.
+	st.observe(poller.PollResult{At: at.Add(8 * time.Second)}, poller.TransportCounters{})
+	st.tick(at.Add(8*time.Second + 200*time.Millisecond))
+	if st.snap.SecondsSinceGood != 0 {
+		t.Fatalf("after recovery: %+v", st.snap)
+	}
+
+	// Saturates, e.g. after a long outage.
+	st.tick(at.Add(48 * time.Hour))
+	if st.snap.SecondsSinceGood != 65535 {
+		t.Fatalf("long outage: %+v", st.snap)
+	}
+}
+
+// ------------------------------------------------------------------
+// Runner lifecycle
+// ------------------------------------------------------------------
+
+func TestRunner_Lifecycle(t *testing.T) {
+	src := newFakeSource()
+	clk := newManualClock()
+	sw := &recordingStatusWriter{got: make(chan status.Snapshot, 16)}
+
+	r, err := New(Config{
+		UnitID:        "u1",
+		Source:        src,
+		Writer:        nopWriter{},
+		StatusWriters: []writer.StatusWriter{sw},
+		Clock:         clk,
+	})
+	if err != nil {
+		t.Fatalf("New() err=%v", err)
+	}
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() err=%v", err)
+	}
+	if err := r.Start(context.Background()); err == nil {
+		t.Fatalf("expected error on second Start")
+	}
+
+	expect := func(health, seconds uint16) {
+		t.Helper()
+		select {
+		case s := <-sw.got:
+			if s.Health != health || s.SecondsInError != seconds {
+				t.Fatalf("got health=%d seconds=%d want health=%d seconds=%d",
+					s.Health, s.SecondsInError, health, seconds)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for status write")
+		}
+	}
+
+	// initial full assert
+	expect(status.HealthUnknown, 0)
+
+	src.in <- poller.PollResult{UnitID: "u1", Err: codedErr{code: 2}}
+	expect(status.HealthError, 0)
+
+	clk.fire()
+	expect(status.HealthError, 1)
+
+	src.in <- poller.PollResult{UnitID: "u1"}
+	expect(status.HealthOK, 0)
+
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() err=%v", err)
+	}
+
+	select {
+	case <-src.exited:
+	default:
+		t.Fatalf("source still running after Stop")
+	}
+}
+
+func TestRunner_StopAssertsShutdownHealth(t *testing.T) {
+	src := newFakeSource()
+	sw := &recordingStatusWriter{got: make(chan status.Snapshot, 16)}
+	disabled := status.HealthDisabled
+
+	r, err := New(Config{
+		UnitID:         "u1",
+		Source:         src,
+		Writer:         nopWriter{},
+		StatusWriters:  []writer.StatusWriter{sw},
+		Clock:          newManualClock(),
+		ShutdownHealth: &disabled,
+	})
+	if err != nil {
+		t.Fatalf("New() err=%v", err)
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() err=%v", err)
+	}
+
+	<-sw.got // initial full assert
+	src.in <- poller.PollResult{UnitID: "u1"}
+	<-sw.got // OK
+
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() err=%v", err)
+	}
+
+	select {
+	case s := <-sw.got:
+		if s.Health != status.HealthDisabled {
+			t.Fatalf("final health=%d want=%d", s.Health, status.HealthDisabled)
+		}
+	default:
+		t.Fatalf("no shutdown status written")
+	}
+}
+
+// blockingWriter holds a data write in flight until released.
+type blockingWriter struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(poller.PollResult) error {
+	close(w.entered)
+	<-w.release
+	return nil
+}
+
+func TestRunner_StopDeadlineExceeded(t *testing.T) {
+	src := newFakeSource()
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	defer close(w.release)
+
+	r, err := New(Config{
+		UnitID: "u1",
+		Source: src,
+		Writer: w,
+		Clock:  newManualClock(),
+	})
+	if err != nil {
+		t.Fatalf("New() err=%v", err)
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() err=%v", err)
+	}
+
+	src.in <- poller.PollResult{UnitID: "u1"}
+	<-w.entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := r.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop() err=%v want DeadlineExceeded", err)
+	}
+}
+
+func TestRunner_InfoPublishedAfterPoll(t *testing.T) {
+	src := newFakeSource()
+	src.counters = poller.TransportCounters{RequestsTotal: 1, ResponsesValidTotal: 1}
+
+	r, err := New(Config{UnitID: "u1", Source: src, Writer: nopWriter{}, Clock: newManualClock()})
+	if err != nil {
+		t.Fatalf("New() err=%v", err)
+	}
+	if info := r.Info(); info.UnitID != "u1" || info.LastPoll != nil {
+		t.Fatalf("unexpected initial info: %+v", info)
+	}
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() err=%v", err)
+	}
+	defer r.Stop(context.Background())
+
+	src.in <- poller.PollResult{UnitID: "u1"}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		info := r.Info()
+		if info.LastPoll != nil {
+			if info.Snapshot.Health != status.HealthOK || info.Counters.RequestsTotal != 1 {
+				t.Fatalf("unexpected info: %+v", info)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("info not published")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// chanRecorder records into a channel and fails when told to.
+type chanRecorder struct {
+	got chan poller.PollResult
+	err error
+}
+
+func (c *chanRecorder) Record(res poller.PollResult) error {
+	c.got <- res
+	return c.err
+}
+
+// chanWriter publishes every result it is asked to write.
+type chanWriter struct{ got chan poller.PollResult }
+
+func (w chanWriter) Write(res poller.PollResult) error {
+	w.got <- res
+	return nil
+}
+
+func TestRunner_RecorderAndOutputsSeeEveryResult(t *testing.T) {
+	src := newFakeSource()
+	rec := &chanRecorder{got: make(chan poller.PollResult, 4), err: errors.New("disk full")}
+	w := chanWriter{got: make(chan poller.PollResult, 4)}
+
+	out := chanWriter{got: make(chan poller.PollResult, 4)}
+
+	r, err := New(Config{UnitID: "u1", Source: src, Writer: w, Outputs: []writer.Writer{out}, Recorder: rec, Clock: newManualClock()})
+	if err != nil {
+		t.Fatalf("New() err=%v", err)
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() err=%v", err)
+	}
+	defer r.Stop(context.Background())
+
+	// A failing recorder must not hold back the data path.
+	for i, in := range []poller.PollResult{{UnitID: "u1"}, {UnitID: "u1", Err: codedErr{code: 4}}} {
+		src.in <- in
+		for _, ch := range []chan poller.PollResult{rec.got, w.got, out.got} {
+			select {
+			case got := <-ch:
+				if got.Err != in.Err {
+					t.Fatalf("result %d: got err %v, want %v", i, got.Err, in.Err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("result %d: not recorded and written", i)
+			}
+		}
+	}
+}
+
+// outcomeWriter reports one target that took every write.
+type outcomeWriter struct{ n uint64 }
+
+func (w *outcomeWriter) Write(poller.PollResult) error { w.n++; return nil }
+func (w *outcomeWriter) Outcomes() []writer.TargetOutcome {
+	o := writer.TargetOutcome{TargetID: 7, Successes: w.n}
+	if w.n > 0 {
+		o.LastWriteAt = time.Unix(int64(w.n), 0)
+	}
+	return []writer.TargetOutcome{o}
+}
+
+// chanDeliveryWriter publishes every delivery record it receives.
+type chanDeliveryWriter struct{ got chan []status.TargetDelivery }
+
+func (w chanDeliveryWriter) WriteDelivery(d []status.TargetDelivery) error {
+	w.got <- d
+	return nil
+}
+
+// Delivery is asserted at start and after every poll the writer
+// delivered; a failed poll delivers nothing and writes no delivery.
+func TestRunner_DeliveryAfterDeliveredPolls(t *testing.T) {
+	src := newFakeSource()
+	dw := chanDeliveryWriter{got: make(chan []status.TargetDelivery, 4)}
+
+	r, err := New(Config{
+		UnitID:          "u1",
+		Source:          src,
+		Writer:          &outcomeWriter{},
+		DeliveryWriters: []writer.DeliveryWriter{dw},
+		Clock:           newManualClock(),
+	})
+	if err != nil {
+		t.Fatalf("New() err=%v", err)
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() err=%v", err)
+	}
+
+	next := func() []status.TargetDelivery {
+		t.Helper()
+		select {
+		case d := <-dw.got:
+			return d
+		case <-time.After(2 * time.Second):
+			t.Fatal("no delivery written")
+		}
+		return nil
+	}
+
+	if d := next(); len(d) != 1 || d[0].TargetID != 7 || d[0].LastOutcome != status.DeliveryNone {
+		t.Fatalf("initial delivery: %+v", d)
+	}
+
+	src.in <- poller.PollResult{UnitID: "u1", Err: codedErr{code: 4}}
+	src.in <- poller.PollResult{UnitID: "u1"}
+	if d := next(); d[0].LastOutcome != status.DeliveryOK {
+		t.Fatalf("after delivered poll: %+v", d)
+	}
+
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() err=%v", err)
+	}
+	select {
+	case d := <-dw.got:
+		t.Fatalf("unexpected delivery write: %+v", d)
+	default:
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(Config{Source: newFakeSource(), Writer: nopWriter{}}); err == nil {
+		t.Fatalf("expected error for missing unit id")
+	}
+	if _, err := New(Config{UnitID: "u1", Writer: nopWriter{}}); err == nil {
+		t.Fatalf("expected error for missing source")
+	}
+	if _, err := New(Config{UnitID: "u1", Source: newFakeSource()}); err == nil {
+		t.Fatalf("expected error for missing writer")
+	}
+}
@@ -0,0 +1,169 @@
+// internal/runner/state.go
+package runner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+// state is the per-unit status state machine.
+//
+// It is pure: no IO, no clock, no goroutines.
+// Given the same sequence of observe/tick calls it always produces
+// the same snapshots (docs/Runner_State_Specs, Determinism Guarantee).
+type state struct {
+	snap status.Snapshot
+
+	good     bool      // a poll has succeeded
+	lastGood time.Time // PollResult.At of the latest good poll
+}
+
+func newState() state {
+	return state{
+		snap: status.Snapshot{
+			Health:           status.HealthUnknown,
+			LastErrorCode:    0,
+			SecondsInError:   0,
+			SecondsSinceGood: 65535, // no good poll yet
+		},
+	}
+}
+
+// observe applies one poll outcome plus the poller's latest transport counters.
+// It reports whether the snapshot changed.
+func (s *state) observe(res poller.PollResult, c poller.TransportCounters) bool {
+	changed := false
+
+	// ----------------------------
+	// Health logic
+	// ----------------------------
+	if res.Err == nil {
+		if s.snap.Health != status.HealthOK {
+			s.snap.Health = status.HealthOK
+			changed = true
+		}
+		if s.snap.LastErrorCode != 0 {
+			s.snap.LastErrorCode = 0
+			changed = true
+		}
+		if s.snap.SecondsInError != 0 {
+			s.snap.SecondsInError = 0
+			changed = true
+		}
+	} else {
+		if s.snap.Health != status.HealthError {
+			s.snap.Health = status.HealthError
+			changed = true
+		}
+
+		code := errorCode(res.Err)
+		if s.snap.LastErrorCode != code {
+			s.snap.LastErrorCode = code
+			changed = true
+		}
+	}
+
+	// ----------------------------
+	// Diagnostics (passive)
+	// ----------------------------
+	if ms := saturate16(res.Elapsed / time.Millisecond); s.snap.LastPollDurationMs != ms {
+		s.snap.LastPollDurationMs = ms
+		changed = true
+	}
+	if res.Err == nil {
+		s.good, s.lastGood = true, res.At
+		if s.snap.SecondsSinceGood != 0 {
+			s.snap.SecondsSinceGood = 0
+			changed = true
+		}
+		if sec := res.At.Unix(); sec > 0 && s.snap.LastSuccessEpoch != uint32(sec) {
+			s.snap.LastSuccessEpoch = uint32(sec)
+			changed = true
+		}
+	}
+
+	// ----------------------------
+	// Transport counters injection (passive)
+	// ----------------------------
+	if s.snap.RequestsTotal != c.RequestsTotal {
+		s.snap.RequestsTotal = c.RequestsTotal
+		changed = true
+	}
+	if s.snap.ResponsesValidTotal != c.ResponsesValidTotal {
+		s.snap.ResponsesValidTotal = c.ResponsesValidTotal
+		changed = true
+	}
+	if s.snap.TimeoutsTotal != c.TimeoutsTotal {
+		s.snap.TimeoutsTotal = c.TimeoutsTotal
+		changed = true
+	}
+	if s.snap.TransportErrorsTotal != c.TransportErrorsTotal {
+		s.snap.TransportErrorsTotal = c.TransportErrorsTotal
+		changed = true
+	}
+	if s.snap.ConsecutiveFailCurr != c.ConsecutiveFailCurr {
+		s.snap.ConsecutiveFailCurr = c.ConsecutiveFailCurr
+		changed = true
+	}
+	if s.snap.ConsecutiveFailMax != c.ConsecutiveFailMax {
+		s.snap.ConsecutiveFailMax = c.ConsecutiveFailMax
+		changed = true
+	}
+
+	return changed
+}
+
+// tick accounts one elapsed second; now is the tick's time.
+// seconds-in-error grows while health != OK; seconds-since-good is
+// measured from the last good poll whatever the health, so a slow poll
+// interval shows too. Both saturate at 65535, and seconds-since-good
+// stays there until the first good poll.
+// It reports whether the snapshot changed.
+func (s *state) tick(now time.Time) bool {
+	changed := false
+	if s.snap.Health != status.HealthOK && s.snap.SecondsInError < 65535 {
+		s.snap.SecondsInError++
+		changed = true
+	}
+	if s.good {
+		if sec := saturate16(now.Sub(s.lastGood) / time.Second); s.snap.SecondsSinceGood != sec {
+			s.snap.SecondsSinceGood = sec
+			changed = true
+		}
+	}
+	return changed
+}
+
+func saturate16(n time.Duration) uint16 {
+	return uint16(min(max(n, 0), 65535))
+}
+
+// errorCode extracts the raw error code from a poll error.
+// No string mapping is performed; unknown errors map to 1.
+func errorCode(err error) uint16 {
+	if err == nil {
+		return 0
+	}
+
+	type coderA interface{ Code() uint16 }
+	type coderB interface{ ErrorCode() uint16 }
+	type coderC interface{ ModbusCode() uint16 }
+
+	var a coderA
+	if errors.As(err, &a) {
+		return a.Code()
+	}
+	var b coderB
+	if errors.As(err, &b) {
+		return b.ErrorCode()
+	}
+	var c coderC
+	if errors.As(err, &c) {
+		return c.ModbusCode()
+	}
+
+	return 1
+}
@@ -0,0 +1,355 @@
+// internal/scan/scan.go
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+)
+
+// Reader is the part of pmodbus.Client a scan needs.
+type Reader interface {
+	ReadCoils(addr, qty uint16) ([]bool, error)
+	ReadDiscreteInputs(addr, qty uint16) ([]bool, error)
+	ReadHoldingRegisters(addr, qty uint16) ([]uint16, error)
+	ReadInputRegisters(addr, qty uint16) ([]uint16, error)
+	Close() error
+}
+
+// Dialer opens a connection addressing one unit id.
+type Dialer func(unitID uint8) (Reader, error)
+
+// Modbus exception codes the scan acts on.
+const (
+	excIllegalFunction = 0x01 // FC not supported by the unit
+	excGatewayPath     = 0x0A // gateway: no path to the unit
+	excGatewayTarget   = 0x0B // gateway: unit did not respond
+)
+
+// Protocol limits per request (as enforced by config validation).
+const (
+	maxBits      = 2000
+	maxRegisters = 125
+)
+
+// Options bound one scan.
+type Options struct {
+	FCs   []uint8 // function codes to map (default 1-4)
+	Start uint16  // first address probed
+	End   uint16  // last address probed (inclusive)
+
+	// Step is the stride used to cross unreadable addresses (1-125).
+	// A readable run is still found exactly if it starts between two
+	// probes, but runs shorter than Step inside a gap can be missed.
+	Step uint16
+}
+
+func (o *Options) defaults() {
+	if len(o.FCs) == 0 {
+		o.FCs = []uint8{1, 2, 3, 4}
+	}
+	o.Step = max(1, min(o.Step, maxRegisters))
+	if o.End < o.Start {
+		o.End = o.Start
+	}
+}
+
+// Range is a readable address span, inclusive.
+type Range struct {
+	Start uint16 `json:"start"`
+	End   uint16 `json:"end"`
+}
+
+func (r Range) String() string { return fmt.Sprintf("%d-%d", r.Start, r.End) }
+
+// FCResult is the map of one function code.
+type FCResult struct {
+	FC        uint8   `json:"fc"`
+	Supported bool    `json:"supported"`
+	Ranges    []Range `json:"ranges"`
+}
+
+// UnitResult is everything learned about one responsive unit id.
+type UnitResult struct {
+	UnitID   uint8      `json:"unit_id"`
+	FCs      []FCResult `json:"fcs"`
+	Requests int        `json:"requests"`
+}
+
+// Summary is a one-line description, e.g. "fc1 unsupported; fc3 0-49, 100-199".
+func (r UnitResult) Summary() string {
+	var parts []string
+	for _, f := range r.FCs {
+		switch {
+		case !f.Supported:
+			parts = append(parts, fmt.Sprintf("fc%d unsupported", f.FC))
+		case len(f.Ranges) == 0:
+			parts = append(parts, fmt.Sprintf("fc%d nothing readable", f.FC))
+		default:
+			rs := make([]string, len(f.Ranges))
+			for i, rg := range f.Ranges {
+				rs[i] = rg.String()
+			}
+			parts = append(parts, fmt.Sprintf("fc%d %s", f.FC, strings.Join(rs, ", ")))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Reads turns the discovered ranges into read blocks, each within the
+// protocol limit of its function code.
+func (r UnitResult) Reads() []config.ReadConfig {
+	var out []config.ReadConfig
+	for _, f := range r.FCs {
+		limit := uint32(maxQty(f.FC))
+		for _, rg := range f.Ranges {
+			for a := uint32(rg.Start); a <= uint32(rg.End); a += limit {
+				q := min(limit, uint32(rg.End)-a+1)
+				out = append(out, config.ReadConfig{FC: f.FC, Address: uint16(a), Quantity: uint16(q)})
+			}
+		}
+	}
+	return out
+}
+
+// UnitConfig is a starting point for replicator.units: source and reads
+// filled in, targets left for the engineer.
+func (r UnitResult) UnitConfig(id, endpoint string, timeoutMs int) config.UnitConfig {
+	return config.UnitConfig{
+		ID: id,
+		Source: config.SourceConfig{
+			Endpoint:  endpoint,
+			UnitID:    r.UnitID,
+			TimeoutMs: timeoutMs,
+		},
+		Reads:   r.Reads(),
+		Targets: []config.TargetConfig{},
+		Poll:    config.PollConfig{IntervalMs: config.DefaultPollIntervalMs},
+	}
+}
+
+// ErrAbsent reports a unit id that did not answer.
+var ErrAbsent = errors.New("no response")
+
+// Unit probes one unit id and, when it answers, maps every requested
+// function code. Any Modbus reply counts as an answer, exceptions
+// included, except the gateway codes 0x0A/0x0B. Timeouts and transport
+// errors on the presence probe mean ErrAbsent.
+func Unit(ctx context.Context, dial Dialer, unitID uint8, opt Options) (UnitResult, error) {
+	opt.defaults()
+	s := &unitScan{dial: dial, unitID: unitID}
+	defer s.close()
+
+	res := UnitResult{UnitID: unitID}
+	c, err := dial(unitID)
+	if err != nil {
+		return res, fmt.Errorf("connect: %w", err)
+	}
+	s.conn = c
+
+	if err := s.present(opt); err != nil {
+		res.Requests = s.requests
+		return res, err
+	}
+
+	for _, fc := range opt.FCs {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		f, err := s.mapFC(ctx, fc, opt)
+		if err != nil {
+			return res, err
+		}
+		res.FCs = append(res.FCs, f)
+	}
+	res.Requests = s.requests
+	return res, nil
+}
+
+type unitScan struct {
+	dial     Dialer
+	unitID   uint8
+	conn     Reader
+	requests int
+}
+
+func (s *unitScan) close() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// outcome of one read
+type outcome int
+
+const (
+	readOK outcome = iota
+	readFailed
+	readUnsupported
+	readAbsent
+)
+
+// read issues one request. A non-Modbus error drops the connection: a
+// late reply would otherwise be taken for the next request's answer.
+func (s *unitScan) read(fc uint8, addr, qty uint16) (outcome, error) {
+	if s.conn == nil {
+		c, err := s.dial(s.unitID)
+		if err != nil {
+			return readFailed, err
+		}
+		s.conn = c
+	}
+	s.requests++
+
+	var err error
+	switch fc {
+	case 1:
+		_, err = s.conn.ReadCoils(addr, qty)
+	case 2:
+		_, err = s.conn.ReadDiscreteInputs(addr, qty)
+	case 3:
+		_, err = s.conn.ReadHoldingRegisters(addr, qty)
+	case 4:
+		_, err = s.conn.ReadInputRegisters(addr, qty)
+	default:
+		return readUnsupported, nil
+	}
+	if err == nil {
+		return readOK, nil
+	}
+
+	var exc pmodbus.ModbusException
+	if errors.As(err, &exc) {
+		switch exc.Exception {
+		case excIllegalFunction:
+			return readUnsupported, nil
+		case excGatewayPath, excGatewayTarget:
+			return readAbsent, nil
+		}
+		return readFailed, nil
+	}
+	s.close()
+	return readFailed, err
+}
+
+// present probes the first address, with FC 3 when it is scanned. Any
+// Modbus reply counts, exceptions included, except the gateway codes
+// 0x0A/0x0B.
+func (s *unitScan) present(opt Options) error {
+	fc := opt.FCs[0]
+	if slices.Contains(opt.FCs, 3) {
+		fc = 3
+	}
+	o, err := s.read(fc, opt.Start, 1)
+	if o == readAbsent || err != nil {
+		return ErrAbsent
+	}
+	return nil
+}
+
+// mapFC walks [Start, End] for one function code.
+func (s *unitScan) mapFC(ctx context.Context, fc uint8, opt Options) (FCResult, error) {
+	res := FCResult{FC: fc, Supported: true}
+	limit := uint32(maxQty(fc))
+	end := uint32(opt.End)
+
+	// gapStart is the first address after the last unreadable probe when
+	// the walk has been stepping; a run found later may start before the
+	// probe that found it.
+	gapStart, stepping := uint32(0), false
+
+	for addr := uint32(opt.Start); addr <= end; {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		n, o := s.longestFrom(fc, uint16(addr), uint16(min(limit, end-addr+1)))
+		if o == readUnsupported {
+			return FCResult{FC: fc}, nil
+		}
+		if n == 0 {
+			if !stepping {
+				gapStart, stepping = addr+1, true
+			}
+			addr += uint32(opt.Step)
+			continue
+		}
+
+		start := addr
+		if stepping && gapStart < addr {
+			start = s.earliestStart(fc, gapStart, addr)
+		}
+		stepping = false
+
+		res.Ranges = appendRange(res.Ranges, Range{Start: uint16(start), End: uint16(addr + uint32(n) - 1)})
+		addr += uint32(n)
+	}
+	return res, nil
+}
+
+// longestFrom returns the longest readable quantity from addr, up to
+// max. One request when the whole block reads; otherwise a binary search.
+func (s *unitScan) longestFrom(fc uint8, addr, limit uint16) (uint16, outcome) {
+	o, _ := s.read(fc, addr, limit)
+	if o != readFailed {
+		if o == readOK {
+			return limit, o
+		}
+		return 0, o
+	}
+	if limit == 1 {
+		return 0, readFailed
+	}
+	if o, _ := s.read(fc, addr, 1); o != readOK {
+		return 0, o
+	}
+
+	lo, hi := uint16(1), limit // lo reads, hi does not
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if o, _ := s.read(fc, addr, mid); o == readOK {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, readFailed
+}
+
+// earliestStart finds the lowest x in [lo, hi] such that [x, hi] reads,
+// assuming hi is readable and the run is contiguous.
+func (s *unitScan) earliestStart(fc uint8, lo, hi uint32) uint32 {
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if o, _ := s.read(fc, uint16(mid), uint16(hi-mid+1)); o == readOK {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return hi
+}
+
+// appendRange merges r into rs when contiguous with the last range.
+func appendRange(rs []Range, r Range) []Range {
+	if n := len(rs); n > 0 && uint32(rs[n-1].End)+1 >= uint32(r.Start) {
+		if r.End > rs[n-1].End {
+			rs[n-1].End = r.End
+		}
+		return rs
+	}
+	return append(rs, r)
+}
+
+func maxQty(fc uint8) uint16 {
+	if fc == 1 || fc == 2 {
+		return maxBits
+	}
+	return maxRegisters
+}
@@ -0,0 +1,143 @@
+// internal/scan/scan_test.go
+package scan
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+)
+
+// fakeDevice answers reads inside its readable ranges and raises
+// exception 02 outside them. An FC without an entry is unsupported.
+type fakeDevice struct {
+	readable map[uint8][]Range
+	requests int
+}
+
+func (d *fakeDevice) read(fc uint8, addr, qty uint16) error {
+	d.requests++
+	rs, ok := d.readable[fc]
+	if !ok {
+		return pmodbus.ModbusException{Function: fc, Exception: excIllegalFunction}
+	}
+	end := uint32(addr) + uint32(qty) - 1
+	for _, r := range rs {
+		if addr >= r.Start && end <= uint32(r.End) {
+			return nil
+		}
+	}
+	return pmodbus.ModbusException{Function: fc, Exception: 0x02}
+}
+
+func (d *fakeDevice) ReadCoils(a, q uint16) ([]bool, error) {
+	return make([]bool, q), d.read(1, a, q)
+}
+func (d *fakeDevice) ReadDiscreteInputs(a, q uint16) ([]bool, error) {
+	return make([]bool, q), d.read(2, a, q)
+}
+func (d *fakeDevice) ReadHoldingRegisters(a, q uint16) ([]uint16, error) {
+	return make([]uint16, q), d.read(3, a, q)
+}
+func (d *fakeDevice) ReadInputRegisters(a, q uint16) ([]uint16, error) {
+	return make([]uint16, q), d.read(4, a, q)
+}
+func (d *fakeDevice) Close() error { return nil }
+
+// silent never answers (no unit behind this id).
+type silent struct{ fakeDevice }
+
+func (silent) ReadHoldingRegisters(uint16, uint16) ([]uint16, error) {
+	return nil, errors.New("i/o timeout")
+}
+
+func dialer(units map[uint8]Reader) Dialer {
+	return func(id uint8) (Reader, error) {
+		if r, ok := units[id]; ok {
+			return r, nil
+		}
+		return &silent{}, nil
+	}
+}
+
+func TestUnit_MapsRangesExactly(t *testing.T) {
+	dev := &fakeDevice{readable: map[uint8][]Range{
+		3: {{0, 49}, {105, 330}},
+		4: {{1000, 1009}},
+		1: {{0, 15}},
+	}}
+
+	res, err := Unit(context.Background(), dialer(map[uint8]Reader{7: dev}), 7, Options{
+		FCs: []uint8{1, 2, 3, 4}, End: 1999, Step: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FCResult{
+		{FC: 1, Supported: true, Ranges: []Range{{0, 15}}},
+		{FC: 2},
+		{FC: 3, Supported: true, Ranges: []Range{{0, 49}, {105, 330}}},
+		{FC: 4, Supported: true, Ranges: []Range{{1000, 1009}}},
+	}
+	if !reflect.DeepEqual(res.FCs, want) {
+		t.Fatalf("got  %+v\nwant %+v", res.FCs, want)
+	}
+	if res.Requests != dev.requests {
+		t.Fatalf("requests = %d, device saw %d", res.Requests, dev.requests)
+	}
+	if got := res.Summary(); got != "fc1 0-15; fc2 unsupported; fc3 0-49, 105-330; fc4 1000-1009" {
+		t.Fatalf("summary %q", got)
+	}
+}
+
+func TestUnit_AbsentAndGateway(t *testing.T) {
+	gw := &fakeDevice{readable: map[uint8][]Range{}}
+	gateway := &gatewayOnly{gw}
+
+	d := dialer(map[uint8]Reader{2: gateway})
+	for _, id := range []uint8{1, 2} {
+		if _, err := Unit(context.Background(), d, id, Options{End: 10}); !errors.Is(err, ErrAbsent) {
+			t.Fatalf("unit %d: got %v, want ErrAbsent", id, err)
+		}
+	}
+}
+
+type gatewayOnly struct{ *fakeDevice }
+
+func (g *gatewayOnly) ReadHoldingRegisters(uint16, uint16) ([]uint16, error) {
+	return nil, pmodbus.ModbusException{Function: 3, Exception: excGatewayTarget}
+}
+
+func TestUnit_ConnectError(t *testing.T) {
+	dial := func(uint8) (Reader, error) { return nil, errors.New("connection refused") }
+	if _, err := Unit(context.Background(), dial, 1, Options{}); err == nil || errors.Is(err, ErrAbsent) {
+		t.Fatalf("got %v, want connect error", err)
+	}
+}
+
+func TestUnitResult_ReadsRespectProtocolLimits(t *testing.T) {
+	r := UnitResult{UnitID: 3, FCs: []FCResult{
+		{FC: 3, Supported: true, Ranges: []Range{{0, 299}}},
+		{FC: 2, Supported: true, Ranges: []Range{{10, 2509}}},
+	}}
+
+	want := []config.ReadConfig{
+		{FC: 3, Address: 0, Quantity: 125},
+		{FC: 3, Address: 125, Quantity: 125},
+		{FC: 3, Address: 250, Quantity: 50},
+		{FC: 2, Address: 10, Quantity: 2000},
+		{FC: 2, Address: 2010, Quantity: 500},
+	}
+	if got := r.Reads(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v", got)
+	}
+
+	u := r.UnitConfig("plc-3", "10.0.0.9:502", 500)
+	if u.Source.UnitID != 3 || len(u.Reads) != 5 || u.Poll.IntervalMs != config.DefaultPollIntervalMs {
+		t.Fatalf("unit %+v", u)
+	}
+}
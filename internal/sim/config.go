@@ -0,0 +1,88 @@
+// internal/sim/config.go
+package sim
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes one simulated device (or gateway).
+type Config struct {
+	// Listen is host:port for Serve. Default ":5020".
+	Listen string `yaml:"listen,omitempty"`
+
+	// UnitIDs answered. Empty answers every id, like a device that
+	// ignores the unit id.
+	UnitIDs []uint8 `yaml:"unit_ids,omitempty"`
+
+	// Gateway answers unlisted unit ids with exception 0x0B (target
+	// failed to respond) instead of staying silent.
+	Gateway bool `yaml:"gateway,omitempty"`
+
+	// Seed is a CSV file of area,address,value[,value...] lines,
+	// relative to the config file. Applied before Memory.
+	Seed string `yaml:"seed,omitempty"`
+
+	Memory  []SeedBlock `yaml:"memory,omitempty"`
+	Scripts []Script    `yaml:"scripts,omitempty"`
+	Faults  []Fault     `yaml:"faults,omitempty"`
+
+	// RandomSeed makes random walks reproducible. 0 picks one.
+	RandomSeed uint64 `yaml:"random_seed,omitempty"`
+}
+
+// SeedBlock sets consecutive values from Address on.
+type SeedBlock struct {
+	Area    string   `yaml:"area"`
+	Address uint16   `yaml:"address"`
+	Values  []uint16 `yaml:"values"`
+}
+
+// LoadConfig reads a simulator config. A relative Seed is resolved
+// against the config file's directory.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.Seed != "" && !filepath.IsAbs(cfg.Seed) {
+		cfg.Seed = filepath.Join(filepath.Dir(path), cfg.Seed)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the config without touching the network or files.
+func (c *Config) Validate() error {
+	for i, b := range c.Memory {
+		if _, err := ParseArea(b.Area); err != nil {
+			return fmt.Errorf("memory[%d].area: %w", i, err)
+		}
+		if int(b.Address)+len(b.Values) > 65536 {
+			return fmt.Errorf("memory[%d]: %d values from %d run past 65535", i, len(b.Values), b.Address)
+		}
+	}
+	for i := range c.Scripts {
+		if err := c.Scripts[i].validate(); err != nil {
+			return fmt.Errorf("scripts[%d].%w", i, err)
+		}
+	}
+	for i := range c.Faults {
+		if err := c.Faults[i].validate(); err != nil {
+			return fmt.Errorf("faults[%d].%w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// internal/sim/fault.go
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Fault kinds.
+const (
+	FaultException   = "exception"    // answer with exception Code
+	FaultDelay       = "delay"        // answer late by DelayMs (combines with the others)
+	FaultDrop        = "drop"         // close the connection without answering
+	FaultTIDMismatch = "tid_mismatch" // answer with the wrong transaction id
+	FaultHalfClose   = "half_close"   // send FIN, keep reading, never answer
+)
+
+// Fault injects a failure into matching requests.
+//
+// A request matches when UnitID (if set), FC (if non-zero) and the
+// address window [Address, Address+Quantity) (if Quantity is non-zero)
+// all match. Of the matching requests, the first After pass untouched,
+// then every Every-th one triggers the fault, at most Count times
+// (0: unlimited).
+type Fault struct {
+	Kind     string `yaml:"kind"`
+	UnitID   *uint8 `yaml:"unit_id,omitempty"`
+	FC       uint8  `yaml:"fc,omitempty"`
+	Address  uint16 `yaml:"address,omitempty"`
+	Quantity uint16 `yaml:"quantity,omitempty"`
+
+	Code    uint8 `yaml:"code,omitempty"`     // exception; default 0x04
+	DelayMs int   `yaml:"delay_ms,omitempty"` // delay
+
+	After int `yaml:"after,omitempty"`
+	Every int `yaml:"every,omitempty"`
+	Count int `yaml:"count,omitempty"`
+}
+
+func (f *Fault) validate() error {
+	switch f.Kind {
+	case FaultException:
+		if f.Code > 0x0B {
+			return fmt.Errorf("code: %d outside 1-11", f.Code)
+		}
+	case FaultDelay:
+		if f.DelayMs <= 0 {
+			return errors.New("delay_ms: must be > 0")
+		}
+	case FaultDrop, FaultTIDMismatch, FaultHalfClose:
+	default:
+		return fmt.Errorf("kind: %q (exception, delay, drop, tid_mismatch, half_close)", f.Kind)
+	}
+	if f.FC > 4 {
+		return fmt.Errorf("fc: %d (1-4 or omitted)", f.FC)
+	}
+	if f.After < 0 || f.Every < 0 || f.Count < 0 {
+		return errors.New("after, every, count: must be >= 0")
+	}
+	return nil
+}
+
+// faultState is a Fault plus its trigger counters.
+type faultState struct {
+	Fault
+	matched int
+	fired   int
+}
+
+func (f *faultState) matches(unitID, fc uint8, addr, qty uint16) bool {
+	if f.UnitID != nil && *f.UnitID != unitID {
+		return false
+	}
+	if f.FC != 0 && f.FC != fc {
+		return false
+	}
+	if f.Quantity != 0 {
+		lo, hi := int(f.Address), int(f.Address)+int(f.Quantity)
+		if int(addr)+int(qty) <= lo || int(addr) >= hi {
+			return false
+		}
+	}
+	return true
+}
+
+// fire counts a matching request and reports whether the fault triggers.
+func (f *faultState) fire() bool {
+	f.matched++
+	n := f.matched - f.After
+	if n <= 0 {
+		return false
+	}
+	if f.Every > 1 && (n-1)%f.Every != 0 {
+		return false
+	}
+	if f.Count > 0 && f.fired >= f.Count {
+		return false
+	}
+	f.fired++
+	return true
+}
+
+// action is what the server does with one request.
+type action struct {
+	kind  string // "" answers normally
+	code  uint8
+	delay time.Duration
+}
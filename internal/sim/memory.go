@@ -0,0 +1,178 @@
+// internal/sim/memory.go
+package sim
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Area is one of the four Modbus data tables. Values equal the read FC.
+type Area uint8
+
+const (
+	Coils            Area = 1
+	DiscreteInputs   Area = 2
+	HoldingRegisters Area = 3
+	InputRegisters   Area = 4
+)
+
+// ParseArea accepts the names used in seeds and scripts.
+func ParseArea(s string) (Area, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "coils", "coil", "1":
+		return Coils, nil
+	case "discrete", "discrete_inputs", "2":
+		return DiscreteInputs, nil
+	case "holding", "holding_registers", "3":
+		return HoldingRegisters, nil
+	case "input", "input_registers", "4":
+		return InputRegisters, nil
+	}
+	return 0, fmt.Errorf("unknown area %q (coils, discrete, holding, input)", s)
+}
+
+func (a Area) String() string {
+	switch a {
+	case Coils:
+		return "coils"
+	case DiscreteInputs:
+		return "discrete"
+	case HoldingRegisters:
+		return "holding"
+	case InputRegisters:
+		return "input"
+	}
+	return fmt.Sprintf("area(%d)", uint8(a))
+}
+
+func (a Area) isBits() bool { return a == Coils || a == DiscreteInputs }
+
+// Memory holds the four areas, each addressed from 0. Bits are stored
+// as 0/1 words so every area shares one representation.
+type Memory struct {
+	mu    sync.RWMutex
+	areas [5][]uint16 // indexed by Area
+}
+
+// NewMemory returns zeroed memory spanning the full 64k of every area.
+func NewMemory() *Memory {
+	return NewMemorySize(65536, 65536, 65536, 65536)
+}
+
+// NewMemorySize returns zeroed memory with the given number of
+// addresses per area (0: the area does not exist). Sizes above 65536
+// are capped.
+func NewMemorySize(coils, discrete, holding, input int) *Memory {
+	area := func(n int) []uint16 { return make([]uint16, max(0, min(n, 65536))) }
+	m := &Memory{}
+	m.areas[Coils] = area(coils)
+	m.areas[DiscreteInputs] = area(discrete)
+	m.areas[HoldingRegisters] = area(holding)
+	m.areas[InputRegisters] = area(input)
+	return m
+}
+
+// Size is the number of addresses in area a.
+func (m *Memory) Size(a Area) int {
+	if a < Coils || a > InputRegisters {
+		return 0
+	}
+	return len(m.areas[a])
+}
+
+// Contains reports whether [addr, addr+qty) lies inside area a.
+func (m *Memory) Contains(a Area, addr, qty uint16) bool {
+	return int(addr)+int(qty) <= m.Size(a)
+}
+
+// Set writes values from addr on; bit areas store v != 0.
+func (m *Memory) Set(a Area, addr uint16, values ...uint16) error {
+	if a < Coils || a > InputRegisters {
+		return fmt.Errorf("sim: bad area %d", a)
+	}
+	if int(addr)+len(values) > len(m.areas[a]) {
+		return fmt.Errorf("sim: %s %d+%d outside 0-%d", a, addr, len(values), len(m.areas[a])-1)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, v := range values {
+		if a.isBits() && v != 0 {
+			v = 1
+		}
+		m.areas[a][int(addr)+i] = v
+	}
+	return nil
+}
+
+// Get returns a copy of up to qty values from addr, stopping at the
+// end of the area.
+func (m *Memory) Get(a Area, addr, qty uint16) []uint16 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	area := m.areas[a]
+	lo := min(int(addr), len(area))
+	hi := min(int(addr)+int(qty), len(area))
+	return append([]uint16(nil), area[lo:hi]...)
+}
+
+// update applies f to one value under the lock (scripts). Addresses
+// outside the area are ignored.
+func (m *Memory) update(a Area, addr uint16, f func(uint16) uint16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if int(addr) < len(m.areas[a]) {
+		m.areas[a][addr] = f(m.areas[a][addr])
+	}
+}
+
+// LoadCSV seeds memory from lines of "area,address,value[,value...]".
+// Blank lines and lines starting with # are skipped; a header line
+// starting with "area" is allowed.
+func (m *Memory) LoadCSV(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		if strings.EqualFold(rec[0], "area") {
+			continue
+		}
+		if len(rec) < 3 {
+			return fmt.Errorf("seed line %d: want area,address,value[,...]", line)
+		}
+
+		a, err := ParseArea(rec[0])
+		if err != nil {
+			return fmt.Errorf("seed line %d: %w", line, err)
+		}
+		addr, err := strconv.ParseUint(rec[1], 10, 16)
+		if err != nil {
+			return fmt.Errorf("seed line %d: address %q: %w", line, rec[1], err)
+		}
+		var values []uint16
+		for _, f := range rec[2:] {
+			v, err := strconv.ParseUint(f, 0, 16)
+			if err != nil {
+				return fmt.Errorf("seed line %d: value %q: %w", line, f, err)
+			}
+			values = append(values, uint16(v))
+		}
+		if err := m.Set(a, uint16(addr), values...); err != nil {
+			return fmt.Errorf("seed line %d: %w", line, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// internal/sim/script.go
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Script changes one value on a timer.
+//
+//	ramp:        Min, Min+Step, ... Max, then back to Min
+//	random_walk: +/- up to Step each tick, clamped to [Min, Max]
+//
+// On coils and discrete inputs a ramp with Max 1 toggles the bit.
+type Script struct {
+	Area    string `yaml:"area"`
+	Address uint16 `yaml:"address"`
+	Kind    string `yaml:"kind"`
+	Min     uint16 `yaml:"min,omitempty"`
+	Max     uint16 `yaml:"max,omitempty"`  // default 65535 (1 for bit areas)
+	Step    uint16 `yaml:"step,omitempty"` // default 1
+	EveryMs int    `yaml:"every_ms"`
+}
+
+const (
+	ScriptRamp       = "ramp"
+	ScriptRandomWalk = "random_walk"
+)
+
+func (s *Script) validate() error {
+	a, err := ParseArea(s.Area)
+	if err != nil {
+		return fmt.Errorf("area: %w", err)
+	}
+	if s.Kind != ScriptRamp && s.Kind != ScriptRandomWalk {
+		return fmt.Errorf("kind: %q (ramp, random_walk)", s.Kind)
+	}
+	if s.EveryMs <= 0 {
+		return errors.New("every_ms: must be > 0")
+	}
+	if s.Max != 0 && s.Max < s.Min {
+		return fmt.Errorf("max: %d below min %d", s.Max, s.Min)
+	}
+	if a.isBits() && s.Max > 1 {
+		return fmt.Errorf("max: %d on a bit area", s.Max)
+	}
+	return nil
+}
+
+// bounds returns Min, Max and Step with defaults applied.
+func (s *Script) bounds() (area Area, lo, hi, step int) {
+	area, _ = ParseArea(s.Area)
+	lo, hi, step = int(s.Min), int(s.Max), int(s.Step)
+	if s.Max == 0 {
+		hi = 65535
+		if area.isBits() {
+			hi = 1
+		}
+	}
+	if step == 0 {
+		step = 1
+	}
+	return area, lo, hi, step
+}
+
+// tick applies one step of s to m.
+func (s *Script) tick(m *Memory, rnd *rand.Rand) {
+	area, lo, hi, step := s.bounds()
+	m.update(area, s.Address, func(v uint16) uint16 {
+		n := int(v)
+		switch s.Kind {
+		case ScriptRamp:
+			n += step
+			if n > hi || n < lo {
+				n = lo
+			}
+		case ScriptRandomWalk:
+			n += rnd.IntN(2*step+1) - step
+		}
+		return uint16(max(lo, min(hi, n)))
+	})
+}
@@ -0,0 +1,392 @@
+// internal/sim/server.go
+package sim
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/logging"
+)
+
+// DefaultListen is used when Config.Listen is empty.
+const DefaultListen = ":5020"
+
+// Stats counts what the server has seen. Snapshot via Server.Stats.
+type Stats struct {
+	Connections int // accepted
+	Requests    int // complete request frames read
+	Exceptions  int // exception responses, injected or not
+	Faults      int // faults triggered (delays included)
+}
+
+// Server is a Modbus TCP slave serving FC 1-4 from a Memory.
+// Every other function code gets exception 0x01.
+type Server struct {
+	cfg Config
+	mem *Memory
+	log *slog.Logger
+
+	mu       sync.Mutex
+	units    map[uint8]*Memory // SetUnitMemory
+	unitOnly bool              // NewGateway: no shared memory
+	faults   []*faultState
+	stats    Stats
+	rnd      *rand.Rand
+}
+
+// New validates cfg and seeds memory from cfg.Seed and cfg.Memory.
+// log nil discards.
+func New(cfg Config, log *slog.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{cfg: cfg, mem: NewMemory(), log: logging.OrDiscard(log)}
+
+	if cfg.Seed != "" {
+		f, err := os.Open(cfg.Seed)
+		if err != nil {
+			return nil, err
+		}
+		err = s.mem.LoadCSV(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range cfg.Memory {
+		a, _ := ParseArea(b.Area)
+		if err := s.mem.Set(a, b.Address, b.Values...); err != nil {
+			return nil, err
+		}
+	}
+
+	seed := cfg.RandomSeed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	s.rnd = rand.New(rand.NewPCG(seed, 0))
+
+	for _, f := range cfg.Faults {
+		s.faults = append(s.faults, &faultState{Fault: f})
+	}
+	return s, nil
+}
+
+// NewGateway returns a server without shared memory: only unit ids
+// given one with SetUnitMemory answer, others get exception 0x0B.
+func NewGateway(log *slog.Logger) *Server {
+	s, _ := New(Config{Gateway: true}, log)
+	s.unitOnly = true
+	return s
+}
+
+// Memory is the live memory; changes are visible to the next request.
+func (s *Server) Memory() *Memory { return s.mem }
+
+// SetUnitMemory serves m for unitID instead of the shared memory, as a
+// gateway or multi-unit slave would. Once any unit memory is set, an
+// empty Config.UnitIDs no longer answers every id: only listed ids
+// (shared memory) and ids with their own memory answer. m nil removes.
+func (s *Server) SetUnitMemory(unitID uint8, m *Memory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m == nil {
+		delete(s.units, unitID)
+		return
+	}
+	if s.units == nil {
+		s.units = map[uint8]*Memory{}
+	}
+	s.units[unitID] = m
+}
+
+// AddFault appends a fault at runtime.
+func (s *Server) AddFault(f Fault) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &faultState{Fault: f})
+	return nil
+}
+
+// ClearFaults removes every fault, configured ones included.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Stats returns a snapshot of the counters.
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Serve listens on addr (Config.Listen, then DefaultListen, when empty)
+// until ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = s.cfg.Listen
+	}
+	if addr == "" {
+		addr = DefaultListen
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeListener(ctx, ln)
+}
+
+// ServeListener serves on ln until ctx is cancelled. It closes ln and
+// every open connection, runs the scripts meanwhile, and returns nil on
+// cancellation.
+func (s *Server) ServeListener(ctx context.Context, ln net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+
+	for i := range s.cfg.Scripts {
+		wg.Add(1)
+		go func(sc *Script) {
+			defer wg.Done()
+			s.runScript(ctx, sc)
+		}(&s.cfg.Scripts[i])
+	}
+
+	s.log.Info("simulator listening", "addr", ln.Addr().String())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.stats.Connections++
+		s.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+			defer stop()
+			s.handle(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) runScript(ctx context.Context, sc *Script) {
+	t := time.NewTicker(time.Duration(sc.EveryMs) * time.Millisecond)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.mu.Lock()
+			sc.tick(s.mem, s.rnd)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// handle serves one connection, one request at a time.
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	log := s.log.With("remote", conn.RemoteAddr().String())
+
+	var hdr [7]byte
+	for {
+		if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+			return
+		}
+		tid := binary.BigEndian.Uint16(hdr[0:2])
+		proto := binary.BigEndian.Uint16(hdr[2:4])
+		length := int(binary.BigEndian.Uint16(hdr[4:6]))
+		unitID := hdr[6]
+		if proto != 0 || length < 2 || length > 254 {
+			log.Warn("bad mbap header, closing", "proto", proto, "length", length)
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.stats.Requests++
+		s.mu.Unlock()
+
+		mem := s.memoryFor(unitID)
+		if mem == nil {
+			if !s.cfg.Gateway {
+				continue
+			}
+			s.respond(conn, tid, unitID, exception(pdu[0], 0x0B))
+			continue
+		}
+
+		var addr, qty uint16
+		if len(pdu) >= 5 {
+			addr = binary.BigEndian.Uint16(pdu[1:3])
+			qty = binary.BigEndian.Uint16(pdu[3:5])
+		}
+		act := s.faultFor(unitID, pdu[0], addr, qty)
+		if act.kind != "" || act.delay > 0 {
+			log.Debug("fault", "kind", act.kind, "delay", act.delay, "fc", pdu[0], "address", addr)
+		}
+
+		if act.delay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(act.delay):
+			}
+		}
+
+		switch act.kind {
+		case FaultDrop:
+			return
+		case FaultHalfClose:
+			if tc, ok := conn.(*net.TCPConn); ok {
+				_ = tc.CloseWrite()
+			}
+			_, _ = io.Copy(io.Discard, conn)
+			return
+		case FaultException:
+			s.respond(conn, tid, unitID, exception(pdu[0], act.code))
+		case FaultTIDMismatch:
+			s.respond(conn, tid+1, unitID, execute(mem, pdu))
+		default:
+			s.respond(conn, tid, unitID, execute(mem, pdu))
+		}
+	}
+}
+
+// memoryFor returns the memory serving unitID, nil when it is not answered.
+func (s *Server) memoryFor(unitID uint8) *Memory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m := s.units[unitID]; m != nil {
+		return m
+	}
+	if s.unitOnly {
+		return nil
+	}
+	if slices.Contains(s.cfg.UnitIDs, unitID) || (len(s.cfg.UnitIDs) == 0 && len(s.units) == 0) {
+		return s.mem
+	}
+	return nil
+}
+
+// faultFor runs the fault table for one request. Delays add up; the
+// first other triggered fault decides the answer.
+func (s *Server) faultFor(unitID, fc uint8, addr, qty uint16) action {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var act action
+	for _, f := range s.faults {
+		if !f.matches(unitID, fc, addr, qty) || !f.fire() {
+			continue
+		}
+		s.stats.Faults++
+		switch {
+		case f.Kind == FaultDelay:
+			act.delay += time.Duration(f.DelayMs) * time.Millisecond
+		case act.kind == "":
+			act.kind = f.Kind
+			act.code = f.Code
+			if act.code == 0 {
+				act.code = 0x04
+			}
+		}
+	}
+	return act
+}
+
+// respond writes one ADU in a single Write: clients read each response
+// with one Read.
+func (s *Server) respond(conn net.Conn, tid uint16, unitID uint8, pdu []byte) {
+	if pdu[0]&0x80 != 0 {
+		s.mu.Lock()
+		s.stats.Exceptions++
+		s.mu.Unlock()
+	}
+	adu := make([]byte, 7+len(pdu))
+	binary.BigEndian.PutUint16(adu[0:2], tid)
+	binary.BigEndian.PutUint16(adu[4:6], uint16(1+len(pdu)))
+	adu[6] = unitID
+	copy(adu[7:], pdu)
+	_, _ = conn.Write(adu)
+}
+
+// execute answers a read request PDU from m.
+func execute(m *Memory, pdu []byte) []byte {
+	fc := pdu[0]
+	if fc < 1 || fc > 4 {
+		return exception(fc, 0x01)
+	}
+	if len(pdu) != 5 {
+		return exception(fc, 0x03)
+	}
+	addr := binary.BigEndian.Uint16(pdu[1:3])
+	qty := binary.BigEndian.Uint16(pdu[3:5])
+
+	area := Area(fc)
+	limit := uint16(125)
+	if area.isBits() {
+		limit = 2000
+	}
+	if qty == 0 || qty > limit {
+		return exception(fc, 0x03)
+	}
+	if !m.Contains(area, addr, qty) {
+		return exception(fc, 0x02)
+	}
+
+	values := m.Get(area, addr, qty)
+	if area.isBits() {
+		out := make([]byte, 2+(len(values)+7)/8)
+		out[0], out[1] = fc, byte(len(out)-2)
+		for i, v := range values {
+			if v != 0 {
+				out[2+i/8] |= 1 << (i % 8)
+			}
+		}
+		return out
+	}
+	out := make([]byte, 2+2*len(values))
+	out[0], out[1] = fc, byte(2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(out[2+2*i:], v)
+	}
+	return out
+}
+
+func exception(fc, code uint8) []byte {
+	return []byte{fc | 0x80, code}
+}
@@ -0,0 +1,309 @@
+// internal/sim/sim_test.go
+package sim
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pmodbus "github.com/tamzrod/modbus-replicator/internal/poller/modbus"
+)
+
+func TestLoadConfig_Example(t *testing.T) {
+	cfg, err := LoadConfig("testdata/device.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := s.Memory()
+	if got := m.Get(HoldingRegisters, 10, 4); !equal(got, 1, 2, 3, 4) {
+		t.Fatalf("csv seed: %v", got)
+	}
+	if got := m.Get(HoldingRegisters, 100, 3); !equal(got, 7, 8, 9) {
+		t.Fatalf("yaml seed: %v", got)
+	}
+	if got := m.Get(DiscreteInputs, 4, 2); !equal(got, 0, 1) {
+		t.Fatalf("bits: %v", got)
+	}
+}
+
+func TestLoadCSV_Errors(t *testing.T) {
+	for _, bad := range []string{
+		"holding,1",
+		"registers,1,2",
+		"holding,70000,1",
+		"holding,1,x",
+		"holding,65535,1,2",
+	} {
+		if err := NewMemory().LoadCSV(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cases := map[string]Config{
+		"scripts[0].kind":     {Scripts: []Script{{Area: "holding", Kind: "sine", EveryMs: 10}}},
+		"scripts[0].every_ms": {Scripts: []Script{{Area: "holding", Kind: ScriptRamp}}},
+		"scripts[0].max":      {Scripts: []Script{{Area: "coils", Kind: ScriptRamp, Max: 5, EveryMs: 10}}},
+		"faults[0].kind":      {Faults: []Fault{{Kind: "explode"}}},
+		"faults[0].delay_ms":  {Faults: []Fault{{Kind: FaultDelay}}},
+		"memory[0].area":      {Memory: []SeedBlock{{Area: "eeprom"}}},
+	}
+	for want, cfg := range cases {
+		if err := cfg.Validate(); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("%s: got %v", want, err)
+		}
+	}
+}
+
+func TestScript_Ramp(t *testing.T) {
+	m := NewMemory()
+	sc := Script{Area: "holding", Kind: ScriptRamp, Min: 10, Max: 12, EveryMs: 1}
+	var got []uint16
+	for range 5 {
+		sc.tick(m, nil)
+		got = append(got, m.Get(HoldingRegisters, 0, 1)[0])
+	}
+	if !equal(got, 10, 11, 12, 10, 11) {
+		t.Fatalf("ramp: %v", got)
+	}
+
+	toggle := Script{Area: "coils", Kind: ScriptRamp, EveryMs: 1}
+	toggle.tick(m, nil)
+	toggle.tick(m, nil)
+	toggle.tick(m, nil)
+	if got := m.Get(Coils, 0, 1); !equal(got, 1) {
+		t.Fatalf("toggle: %v", got)
+	}
+}
+
+func TestScript_RandomWalkStaysInBounds(t *testing.T) {
+	m := NewMemory()
+	rnd := rand.New(rand.NewPCG(1, 0))
+	sc := Script{Area: "input", Kind: ScriptRandomWalk, Min: 100, Max: 110, Step: 3, EveryMs: 1}
+	prev := -1
+	for range 200 {
+		sc.tick(m, rnd)
+		v := int(m.Get(InputRegisters, 0, 1)[0])
+		if v < 100 || v > 110 {
+			t.Fatalf("out of bounds: %d", v)
+		}
+		if prev >= 0 && (v-prev > 3 || prev-v > 3) {
+			t.Fatalf("step %d -> %d", prev, v)
+		}
+		prev = v
+	}
+}
+
+func TestFault_Schedule(t *testing.T) {
+	f := &faultState{Fault: Fault{Kind: FaultDrop, After: 2, Every: 3, Count: 2}}
+	var fired []int
+	for i := 1; i <= 12; i++ {
+		if f.fire() {
+			fired = append(fired, i)
+		}
+	}
+	if len(fired) != 2 || fired[0] != 3 || fired[1] != 6 {
+		t.Fatalf("fired on %v", fired)
+	}
+
+	w := &faultState{Fault: Fault{FC: 3, Address: 100, Quantity: 10}}
+	for _, c := range []struct {
+		fc        uint8
+		addr, qty uint16
+		want      bool
+	}{
+		{3, 90, 10, false},
+		{3, 90, 11, true},
+		{3, 109, 5, true},
+		{3, 110, 5, false},
+		{4, 100, 1, false},
+	} {
+		if got := w.matches(1, c.fc, c.addr, c.qty); got != c.want {
+			t.Errorf("fc %d %d+%d: %v", c.fc, c.addr, c.qty, got)
+		}
+	}
+}
+
+// ---- loopback ----
+
+func start(t *testing.T, cfg Config) (*Server, string) {
+	t.Helper()
+	s, err := New(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.ServeListener(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	return s, ln.Addr().String()
+}
+
+func dial(t *testing.T, addr string, unitID uint8) *pmodbus.Client {
+	t.Helper()
+	c, err := pmodbus.New(pmodbus.Config{Endpoint: addr, UnitID: unitID, Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestServer_Reads(t *testing.T) {
+	s, addr := start(t, Config{UnitIDs: []uint8{1}, Gateway: true})
+	m := s.Memory()
+	_ = m.Set(HoldingRegisters, 0, 10, 20, 30)
+	_ = m.Set(InputRegisters, 65534, 1, 2)
+	_ = m.Set(Coils, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1)
+
+	c := dial(t, addr, 1)
+	if regs, err := c.ReadHoldingRegisters(0, 3); err != nil || !equal(regs, 10, 20, 30) {
+		t.Fatalf("fc3: %v %v", regs, err)
+	}
+	if regs, err := c.ReadInputRegisters(65534, 2); err != nil || !equal(regs, 1, 2) {
+		t.Fatalf("fc4: %v %v", regs, err)
+	}
+	if bits, err := c.ReadCoils(0, 9); err != nil || !bits[0] || bits[1] || !bits[8] {
+		t.Fatalf("fc1: %v %v", bits, err)
+	}
+
+	// Protocol exceptions come from the memory rules, not only from faults.
+	if _, err := c.ReadHoldingRegisters(0, 126); exceptionCode(err) != 0x03 {
+		t.Fatalf("qty 126: %v", err)
+	}
+	if _, err := c.ReadHoldingRegisters(65535, 2); exceptionCode(err) != 0x02 {
+		t.Fatalf("past 65535: %v", err)
+	}
+	if _, err := dial(t, addr, 2).ReadHoldingRegisters(0, 1); exceptionCode(err) != 0x0B {
+		t.Fatalf("gateway, unknown unit: %v", err)
+	}
+
+	if st := s.Stats(); st.Connections != 2 || st.Requests != 6 || st.Exceptions != 3 {
+		t.Fatalf("stats: %+v", st)
+	}
+}
+
+func TestServer_UnitMemories(t *testing.T) {
+	s, addr := start(t, Config{Gateway: true})
+	small := NewMemorySize(0, 0, 10, 0)
+	_ = small.Set(HoldingRegisters, 9, 99)
+	s.SetUnitMemory(5, small)
+
+	c := dial(t, addr, 5)
+	if regs, err := c.ReadHoldingRegisters(9, 1); err != nil || !equal(regs, 99) {
+		t.Fatalf("unit 5: %v %v", regs, err)
+	}
+	if _, err := c.ReadHoldingRegisters(9, 2); exceptionCode(err) != 0x02 {
+		t.Fatalf("past size: %v", err)
+	}
+	if _, err := c.ReadCoils(0, 1); exceptionCode(err) != 0x02 {
+		t.Fatalf("absent area: %v", err)
+	}
+	// With unit memories set, an empty unit_ids no longer answers all.
+	if _, err := dial(t, addr, 1).ReadHoldingRegisters(0, 1); exceptionCode(err) != 0x0B {
+		t.Fatalf("unit 1: %v", err)
+	}
+}
+
+func TestServer_ClosedConnectionsLeaveNothingRunning(t *testing.T) {
+	_, addr := start(t, Config{UnitIDs: []uint8{1}})
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = c.Close()
+	}
+
+	// One goroutine per connection would linger until the server stops;
+	// allow a little slack for the server's own startup.
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before+5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after reconnects, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_UnknownUnitIsSilent(t *testing.T) {
+	_, addr := start(t, Config{UnitIDs: []uint8{1}})
+	var ne net.Error
+	if _, err := dial(t, addr, 2).ReadHoldingRegisters(0, 1); !errors.As(err, &ne) || !ne.Timeout() {
+		t.Fatalf("want timeout, got %v", err)
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	s, addr := start(t, Config{})
+
+	cases := []struct {
+		fault Fault
+		check func(err error) bool
+	}{
+		{Fault{Kind: FaultException, Code: 6}, func(err error) bool { return exceptionCode(err) == 6 }},
+		{Fault{Kind: FaultTIDMismatch}, func(err error) bool {
+			return err != nil && strings.Contains(err.Error(), "transaction id mismatch")
+		}},
+		{Fault{Kind: FaultDrop}, closedByPeer},
+		{Fault{Kind: FaultHalfClose}, closedByPeer},
+		{Fault{Kind: FaultDelay, DelayMs: 400}, func(err error) bool {
+			var ne net.Error
+			return errors.As(err, &ne) && ne.Timeout()
+		}},
+	}
+	for _, c := range cases {
+		s.ClearFaults()
+		if err := s.AddFault(Fault{Kind: c.fault.Kind, Code: c.fault.Code, DelayMs: c.fault.DelayMs, Count: 1}); err != nil {
+			t.Fatal(err)
+		}
+		cl := dial(t, addr, 1)
+		if _, err := cl.ReadHoldingRegisters(0, 1); !c.check(err) {
+			t.Errorf("%s: got %v", c.fault.Kind, err)
+		}
+	}
+
+	// Count: 1 is spent; a fresh connection reads normally.
+	if _, err := dial(t, addr, 1).ReadHoldingRegisters(0, 1); err != nil {
+		t.Fatalf("after faults: %v", err)
+	}
+}
+
+func exceptionCode(err error) uint8 {
+	var ex pmodbus.ModbusException
+	if errors.As(err, &ex) {
+		return ex.Exception
+	}
+	return 0
+}
+
+// closedByPeer reports a read that found the connection closed.
+func closedByPeer(err error) bool {
+	return errors.Is(err, io.EOF) || (err != nil && strings.Contains(err.Error(), "EOF"))
+}
+
+func equal(got []uint16, want ...uint16) bool { return slices.Equal(got, want) }
@@ -38,10 +38,32 @@ const SlotDeviceNameSlots = 8
 const SlotDeviceNameEnd = SlotDeviceNameStart + SlotDeviceNameSlots - 1
 
 // ------------------------------------------------------------
-// SLOTS 11–19 : RESERVED
+// SLOTS 11–19 : DIAGNOSTICS (layout version 2)
 // ------------------------------------------------------------
 
-const SlotReservedStart = 11
+// LayoutVersion is written to SlotLayoutVersion. Version 1 blocks left
+// slots 11–19 reserved (zero), so a reader seeing 0 there has no
+// diagnostics.
+const LayoutVersion uint16 = 2
+
+// SlotLayoutVersion holds LayoutVersion.
+const SlotLayoutVersion = 11
+
+// SlotLastPollDurationMs holds the wall time of the last poll cycle in
+// ms (uint16 direct, saturating).
+const SlotLastPollDurationMs = 12
+
+// last_success_epoch: Unix seconds of the last good poll (uint32, 0 => never)
+const SlotLastSuccessEpochLow  = 13
+const SlotLastSuccessEpochHigh = 14
+
+// SlotSecondsSinceGood counts seconds since the last good poll
+// (uint16 direct, saturating; 65535 until the first one).
+const SlotSecondsSinceGood = 15
+
+// Slots 16–19 remain reserved (zero). Slot 16 is kept for the index of
+// the source endpoint in use once a unit can have more than one.
+const SlotReservedStart = 16
 const SlotReservedEnd   = 19
 
 // ------------------------------------------------------------
@@ -94,4 +116,40 @@ const HealthError uint16 = 2
 const HealthStale uint16 = 3
 
 // HealthDisabled represents a disabled device state.
-const HealthDisabled uint16 = 4
\ No newline at end of file
+const HealthDisabled uint16 = 4
+
+// HealthByName maps a lower-case health name to its code.
+// It is used to resolve configured states such as the shutdown status.
+func HealthByName(name string) (uint16, bool) {
+	switch name {
+	case "unknown":
+		return HealthUnknown, true
+	case "ok":
+		return HealthOK, true
+	case "error":
+		return HealthError, true
+	case "stale":
+		return HealthStale, true
+	case "disabled":
+		return HealthDisabled, true
+	}
+	return 0, false
+}
+
+// HealthName is the inverse of HealthByName.
+// Unknown codes map to "invalid".
+func HealthName(code uint16) string {
+	switch code {
+	case HealthUnknown:
+		return "unknown"
+	case HealthOK:
+		return "ok"
+	case HealthError:
+		return "error"
+	case HealthStale:
+		return "stale"
+	case HealthDisabled:
+		return "disabled"
+	}
+	return "invalid"
+}
\ No newline at end of file
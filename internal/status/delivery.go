@@ -0,0 +1,89 @@
+// internal/status/delivery.go
+package status
+
+// ------------------------------------------------------------
+// TARGET DELIVERY BLOCK
+// ------------------------------------------------------------
+//
+// The delivery block is a second, opt-in block of SlotsPerDevice slots
+// in each target's status memory. It reports how writes of poll data
+// to each target of the unit went, so the reader of one replica can
+// tell that another replica has gone stale.
+//
+// It is delivery truth only. The device status block (Snapshot) is
+// source truth only. Neither feeds the other.
+
+// DeliveryMaxTargets is the number of target entries a block holds.
+const DeliveryMaxTargets = 5
+
+// DeliverySlotsPerTarget is the width of one entry. Entry i (the unit's
+// i-th target, in configuration order) starts at slot i*6; slots of
+// unused entries stay zero.
+const DeliverySlotsPerTarget = 6
+
+// Entry slots, relative to the entry start.
+
+// DeliverySlotTargetID holds the target id (uint16 direct).
+const DeliverySlotTargetID = 0
+
+// DeliverySlotLastOutcome holds the outcome of the last data write.
+const DeliverySlotLastOutcome = 1
+
+// DeliverySlotConsecutiveFailures counts failed data writes since the
+// last good one (uint16 direct, saturating).
+const DeliverySlotConsecutiveFailures = 2
+
+// last_delivery_epoch: Unix seconds of the last good data write (uint32, 0 => never)
+const DeliverySlotLastSuccessEpochLow = 3
+const DeliverySlotLastSuccessEpochHigh = 4
+
+// DeliverySlotQueuedPackets holds the packets waiting to be sent to the
+// target (uint16 direct, saturating; 0 while writes are synchronous).
+const DeliverySlotQueuedPackets = 5
+
+// ------------------------------------------------------------
+// DELIVERY OUTCOMES
+// ------------------------------------------------------------
+
+// DeliveryNone means nothing has been written to the target yet.
+const DeliveryNone uint16 = 0
+
+// DeliveryOK means the last data write reached the target.
+const DeliveryOK uint16 = 1
+
+// DeliveryFailed means the last data write failed.
+const DeliveryFailed uint16 = 2
+
+// TargetDelivery is the delivery truth of one data target.
+type TargetDelivery struct {
+	TargetID            uint16
+	LastOutcome         uint16
+	ConsecutiveFailures uint16
+	LastSuccessEpoch    uint32
+	QueuedPackets       uint16
+}
+
+// DeliveryEntry is one target entry of the delivery block, in slot order.
+var DeliveryEntry = []Field[TargetDelivery]{
+	{"target_id", DeliverySlotTargetID, 1, func(d TargetDelivery) uint32 { return uint32(d.TargetID) }},
+	{"last_outcome", DeliverySlotLastOutcome, 1, func(d TargetDelivery) uint32 { return uint32(d.LastOutcome) }},
+	{"consecutive_failures", DeliverySlotConsecutiveFailures, 1, func(d TargetDelivery) uint32 { return uint32(d.ConsecutiveFailures) }},
+	{"last_delivery_epoch", DeliverySlotLastSuccessEpochLow, 2, func(d TargetDelivery) uint32 { return d.LastSuccessEpoch }},
+	{"queued_packets", DeliverySlotQueuedPackets, 1, func(d TargetDelivery) uint32 { return uint32(d.QueuedPackets) }},
+}
+
+// EncodeDelivery converts the deliveries of a unit's targets into a full
+// delivery block. Targets past DeliveryMaxTargets are left out.
+// No IO. No side effects.
+func EncodeDelivery(targets []TargetDelivery) []uint16 {
+	regs := make([]uint16, SlotsPerDevice)
+
+	for i, d := range targets {
+		if i == DeliveryMaxTargets {
+			break
+		}
+		put(regs, i*DeliverySlotsPerTarget, DeliveryEntry, d)
+	}
+
+	return regs
+}
@@ -0,0 +1,174 @@
+// internal/status/encode_test.go
+package status
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// diagnostic is a snapshot with every field set to a distinct value.
+var diagnostic = Snapshot{
+	Health:             HealthError,
+	LastErrorCode:      0x0B,
+	SecondsInError:     42,
+	LastPollDurationMs: 137,
+	LastSuccessEpoch:   1772366400, // 2026-03-01T12:00:00Z
+	SecondsSinceGood:   43,
+
+	RequestsTotal:        0x00012345,
+	ResponsesValidTotal:  0x00012000,
+	TimeoutsTotal:        0x10,
+	TransportErrorsTotal: 0x20,
+	ConsecutiveFailCurr:  5,
+	ConsecutiveFailMax:   9,
+}
+
+// golden compares regs, one "slot value" line each, with
+// testdata/<name>.golden; go test -update rewrites it.
+func golden(t *testing.T, name string, regs []uint16) {
+	t.Helper()
+	var b strings.Builder
+	for slot, v := range regs {
+		fmt.Fprintf(&b, "%2d 0x%04X\n", slot, v)
+	}
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != string(want) {
+		t.Fatalf("%s differs from %s:\n%s", name, path, got)
+	}
+}
+
+func TestEncode_GoldenLayoutV2(t *testing.T) {
+	regs := Encode(diagnostic, "")
+	if len(regs) != SlotsPerDevice {
+		t.Fatalf("%d slots, want %d", len(regs), SlotsPerDevice)
+	}
+	golden(t, "layout_v2", regs)
+}
+
+// A fresh snapshot still carries the layout version, so readers can
+// tell a v2 block that has seen no poll from a v1 block.
+func TestEncode_ZeroSnapshotCarriesVersion(t *testing.T) {
+	regs := Encode(Snapshot{}, "")
+	for slot, v := range regs {
+		want := uint16(0)
+		if slot == SlotLayoutVersion {
+			want = LayoutVersion
+		}
+		if v != want {
+			t.Fatalf("slot %d = %d, want %d", slot, v, want)
+		}
+	}
+}
+
+func TestEncode_DeviceName(t *testing.T) {
+	regs := Encode(Snapshot{}, "AB\x01CDEFGHIJKLMNOPQRSTUVWXYZ")
+	name := regs[SlotDeviceNameStart : SlotDeviceNameEnd+1]
+	want := []uint16{'A'<<8 | 'B', '?'<<8 | 'C', 'D'<<8 | 'E', 'F'<<8 | 'G', 'H'<<8 | 'I', 'J'<<8 | 'K', 'L'<<8 | 'M', 'N'<<8 | 'O'}
+	if !slices.Equal(name, want) {
+		t.Fatalf("name slots %x, want %x", name, want)
+	}
+}
+
+// Layout covers each slot at most once and stays inside the block.
+func TestLayout_NoOverlap(t *testing.T) {
+	owner := make([]string, SlotsPerDevice)
+	for _, f := range Layout {
+		for slot := f.Slot; slot < f.Slot+f.Width; slot++ {
+			if slot >= SlotsPerDevice {
+				t.Fatalf("%s runs past slot %d", f.Name, SlotsPerDevice-1)
+			}
+			if owner[slot] != "" {
+				t.Fatalf("slot %d in both %s and %s", slot, owner[slot], f.Name)
+			}
+			owner[slot] = f.Name
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	prev := Encode(diagnostic, "METER-7")
+
+	if runs := Diff(nil, prev); len(runs) != 1 || runs[0].Slot != 0 || len(runs[0].Regs) != SlotsPerDevice {
+		t.Fatalf("nil prev: %+v, want the full block", runs)
+	}
+	if runs := Diff(prev, prev); len(runs) != 0 {
+		t.Fatalf("no change: %+v", runs)
+	}
+
+	s := diagnostic
+	s.Health = HealthOK       // 0
+	s.SecondsInError = 0      // 2; slot 1 is unchanged between them
+	s.LastSuccessEpoch++      // 13–14: only the low word moves, both go
+	s.SecondsSinceGood = 0    // 15, joins 13–14
+	s.ConsecutiveFailCurr = 0 // 28
+	s.ConsecutiveFailMax = 10 // 29, joins 28
+	runs := Diff(prev, Encode(s, "METER-7"))
+
+	var got []string
+	for _, r := range runs {
+		got = append(got, fmt.Sprintf("%d+%d", r.Slot, len(r.Regs)))
+	}
+	want := []string{"0+1", "2+1", "13+3", "28+2"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("runs %v, want %v", got, want)
+	}
+}
+
+func TestSlotName(t *testing.T) {
+	for slot, want := range map[int]string{
+		0:  "health_code",
+		4:  "device_name[1]",
+		13: "last_success_epoch.lo",
+		14: "last_success_epoch.hi",
+		18: "reserved",
+		29: "consecutive_fail_max",
+	} {
+		if got := SlotName(slot); got != want {
+			t.Errorf("SlotName(%d) = %q, want %q", slot, got, want)
+		}
+	}
+}
+
+func TestEncodeDelivery_Golden(t *testing.T) {
+	regs := EncodeDelivery([]TargetDelivery{
+		{TargetID: 1, LastOutcome: DeliveryOK, LastSuccessEpoch: 1772366400},
+		{TargetID: 2, LastOutcome: DeliveryFailed, ConsecutiveFailures: 7, LastSuccessEpoch: 1772366393, QueuedPackets: 3},
+		{TargetID: 3},
+	})
+	if len(regs) != SlotsPerDevice {
+		t.Fatalf("%d slots, want %d", len(regs), SlotsPerDevice)
+	}
+	golden(t, "delivery", regs)
+}
+
+// Only the first DeliveryMaxTargets targets fit; the rest are dropped,
+// not wrapped over the first ones.
+func TestEncodeDelivery_MaxTargets(t *testing.T) {
+	var targets []TargetDelivery
+	for id := uint16(1); id <= DeliveryMaxTargets+1; id++ {
+		targets = append(targets, TargetDelivery{TargetID: id})
+	}
+	regs := EncodeDelivery(targets)
+	for i := 0; i < DeliveryMaxTargets; i++ {
+		if got := regs[i*DeliverySlotsPerTarget+DeliverySlotTargetID]; got != uint16(i+1) {
+			t.Fatalf("entry %d target_id = %d, want %d", i, got, i+1)
+		}
+	}
+}
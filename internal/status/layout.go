@@ -0,0 +1,150 @@
+// internal/status/layout.go
+package status
+
+import "fmt"
+
+// Field is one value of a block, read from a T.
+type Field[T any] struct {
+	Name  string // as in docs/Status_Block_Layout.md
+	Slot  int    // first slot
+	Width int    // slots; uint32 values take 2, low word first
+
+	// value reads the field. nil for the device name, which comes
+	// from configuration, not from the runner.
+	value func(T) uint32
+}
+
+// put writes every field of fields that has a value, read from v, at
+// regs[base+Slot].
+func put[T any](regs []uint16, base int, fields []Field[T], v T) {
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		x := f.value(v)
+		if f.Width == 2 {
+			// uint32 → two uint16 (low first, then high)
+			regs[base+f.Slot] = uint16(x & 0xFFFF)
+			regs[base+f.Slot+1] = uint16((x >> 16) & 0xFFFF)
+			continue
+		}
+		regs[base+f.Slot] = uint16(x)
+	}
+}
+
+// Layout is the block, in slot order. It is the single description
+// of the wire format: Encode, Diff and the status writers follow it.
+// Slots not covered (16–19) are reserved and stay zero.
+var Layout = []Field[Snapshot]{
+	// --- Slots 0–2 : Operational Truth ---
+	{"health_code", SlotHealthCode, 1, func(s Snapshot) uint32 { return uint32(s.Health) }},
+	{"last_error_code", SlotLastErrorCode, 1, func(s Snapshot) uint32 { return uint32(s.LastErrorCode) }},
+	{"seconds_in_error", SlotSecondsInError, 1, func(s Snapshot) uint32 { return uint32(s.SecondsInError) }},
+
+	// --- Slots 3–10 : Device Name ---
+	{"device_name", SlotDeviceNameStart, SlotDeviceNameSlots, nil},
+
+	// --- Slots 11–15 : Diagnostics ---
+	{"layout_version", SlotLayoutVersion, 1, func(Snapshot) uint32 { return uint32(LayoutVersion) }},
+	{"last_poll_duration_ms", SlotLastPollDurationMs, 1, func(s Snapshot) uint32 { return uint32(s.LastPollDurationMs) }},
+	{"last_success_epoch", SlotLastSuccessEpochLow, 2, func(s Snapshot) uint32 { return s.LastSuccessEpoch }},
+	{"seconds_since_good", SlotSecondsSinceGood, 1, func(s Snapshot) uint32 { return uint32(s.SecondsSinceGood) }},
+
+	// --- Slots 20–29 : Transport Lifetime Counters ---
+	{"requests_total", SlotRequestsTotalLow, 2, func(s Snapshot) uint32 { return s.RequestsTotal }},
+	{"responses_valid_total", SlotResponsesValidTotalLow, 2, func(s Snapshot) uint32 { return s.ResponsesValidTotal }},
+	{"timeouts_total", SlotTimeoutsTotalLow, 2, func(s Snapshot) uint32 { return s.TimeoutsTotal }},
+	{"transport_errors_total", SlotTransportErrorsTotalLow, 2, func(s Snapshot) uint32 { return s.TransportErrorsTotal }},
+	{"consecutive_fail_current", SlotConsecutiveFailCurr, 1, func(s Snapshot) uint32 { return uint32(s.ConsecutiveFailCurr) }},
+	{"consecutive_fail_max", SlotConsecutiveFailMax, 1, func(s Snapshot) uint32 { return uint32(s.ConsecutiveFailMax) }},
+}
+
+// SlotName names a slot for humans: "health_code",
+// "requests_total.lo", "device_name[2]", "reserved".
+func SlotName(slot int) string {
+	for _, f := range Layout {
+		if slot < f.Slot || slot >= f.Slot+f.Width {
+			continue
+		}
+		switch {
+		case f.value == nil:
+			return fmt.Sprintf("%s[%d]", f.Name, slot-f.Slot)
+		case f.Width == 2 && slot == f.Slot:
+			return f.Name + ".lo"
+		case f.Width == 2:
+			return f.Name + ".hi"
+		}
+		return f.Name
+	}
+	return "reserved"
+}
+
+// Run is a contiguous range of slots to write.
+type Run struct {
+	Slot int
+	Regs []uint16
+}
+
+// Diff returns the runs of cur that differ from prev, field by field:
+// a changed uint32 is rewritten whole, and adjacent changed fields are
+// one run. prev nil means nothing is known: the whole block is one run.
+func Diff(prev, cur []uint16) []Run {
+	if prev == nil {
+		return []Run{{Slot: 0, Regs: cur}}
+	}
+
+	changed := make([]bool, len(cur))
+	for _, f := range Layout {
+		for i := f.Slot; i < f.Slot+f.Width; i++ {
+			if prev[i] != cur[i] {
+				for j := f.Slot; j < f.Slot+f.Width; j++ {
+					changed[j] = true
+				}
+				break
+			}
+		}
+	}
+
+	var runs []Run
+	for i := 0; i < len(cur); i++ {
+		if !changed[i] {
+			continue
+		}
+		start := i
+		for i < len(cur) && changed[i] {
+			i++
+		}
+		runs = append(runs, Run{Slot: start, Regs: cur[start:i]})
+	}
+	return runs
+}
+
+// EncodeDeviceName packs up to DeviceNameMaxChars ASCII characters, two
+// per slot, high byte first. Non-printable bytes become '?'.
+func EncodeDeviceName(name string) []uint16 {
+	out := make([]uint16, SlotDeviceNameSlots)
+
+	b := []byte(name)
+	if len(b) > DeviceNameMaxChars {
+		b = b[:DeviceNameMaxChars]
+	}
+
+	for i := 0; i < len(b); i++ {
+		if b[i] < 0x20 || b[i] > 0x7E {
+			b[i] = '?'
+		}
+	}
+
+	for i := 0; i < DeviceNameMaxChars; i += 2 {
+		var hi, lo byte
+		if i < len(b) {
+			hi = b[i]
+		}
+		if i+1 < len(b) {
+			lo = b[i+1]
+		}
+		out[i/2] = uint16(hi)<<8 | uint16(lo)
+	}
+
+	return out
+}
@@ -14,6 +14,11 @@ type Snapshot struct {
 	LastErrorCode  uint16
 	SecondsInError uint16
 
+	// --- Diagnostics (Slots 11–15) ---
+	LastPollDurationMs uint16
+	LastSuccessEpoch   uint32
+	SecondsSinceGood   uint16
+
 	// --- Transport Lifetime Counters (Slots 20–29) ---
 
 	RequestsTotal        uint32
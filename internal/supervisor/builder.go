@@ -0,0 +1,217 @@
+// internal/supervisor/builder.go
+package supervisor
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/events"
+	"github.com/tamzrod/modbus-replicator/internal/historian"
+	"github.com/tamzrod/modbus-replicator/internal/mqttpub"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/recording"
+	"github.com/tamzrod/modbus-replicator/internal/runner"
+	"github.com/tamzrod/modbus-replicator/internal/writer"
+)
+
+// BuildUnit wires poller → runner → writers for one unit.
+// No dialing happens here; device and target availability is runtime state.
+func BuildUnit(u config.UnitConfig, shutdownHealth uint16, log *slog.Logger) (Built, error) {
+	return BuildOptions{}.build(u, shutdownHealth, log)
+}
+
+// BuildOptions are the run-wide extras a unit can be built with.
+// The zero value is BuildUnit.
+type BuildOptions struct {
+	// RecordDir, when set, records every poll result of a unit to
+	// recording.Path(RecordDir, unit id).
+	RecordDir string
+
+	// ReplayDir, when set, polls each unit from its recording in this
+	// directory instead of the device, at ReplaySpeed (<= 0 means 1).
+	ReplayDir   string
+	ReplaySpeed float64
+
+	// Historian, when Dir is set, keeps every unit's values in local
+	// trend files.
+	Historian historian.Options
+
+	// MQTT, when set, publishes every unit's values and health. The
+	// publisher outlives reloads; its owner closes it after Shutdown.
+	MQTT *mqttpub.Publisher
+
+	// Events, when set, receives every unit's change-of-value events.
+	Events *events.Hub
+}
+
+// Builder returns a Builder applying o.
+func (o BuildOptions) Builder() Builder { return o.build }
+
+func (o BuildOptions) build(u config.UnitConfig, shutdownHealth uint16, log *slog.Logger) (Built, error) {
+	// ---- poller ----
+	var factory func() (poller.Client, error)
+	closeReplay := func() error { return nil }
+	if o.ReplayDir != "" {
+		rp, err := recording.OpenReplay(recording.Path(o.ReplayDir, u.ID), o.ReplaySpeed)
+		if err != nil {
+			return Built{}, err
+		}
+		if rp.Unit() != u.ID {
+			_ = rp.Release()
+			return Built{}, fmt.Errorf("replay: recording is of unit %q", rp.Unit())
+		}
+		// The same replay answers every (re)connect.
+		factory = func() (poller.Client, error) { return rp, nil }
+		closeReplay = rp.Release
+	}
+
+	p, closePoller, err := poller.BuildWith(u, log, factory)
+	if err != nil {
+		_ = closeReplay()
+		return Built{}, err
+	}
+	if o.ReplayDir != "" {
+		closeP := closePoller
+		closePoller = func() error {
+			err := closeP()
+			if rerr := closeReplay(); rerr != nil {
+				err = rerr
+			}
+			return err
+		}
+	}
+
+	// ---- writer plan ----
+	plan, err := writer.BuildPlan(u)
+	if err != nil {
+		_ = closePoller()
+		return Built{}, err
+	}
+
+	// ---- writer clients ----
+	clients, closeWriters, err := writer.BuildEndpointClients(u)
+	if err != nil {
+		_ = closePoller()
+		return Built{}, err
+	}
+
+	var rec *lazyRecorder
+	if o.RecordDir != "" {
+		rec = &lazyRecorder{path: recording.Path(o.RecordDir, u.ID), unit: u.ID}
+	}
+
+	var outputs []writer.Writer
+	var hist *historian.Writer
+	if o.Historian.Dir != "" {
+		hist = historian.NewWriter(o.Historian, u.ID)
+		outputs = append(outputs, hist)
+	}
+	if o.Events != nil {
+		outputs = append(outputs, events.NewDetector(u.ID, u.Reads, o.Events))
+	}
+
+	statusWriters := writer.NewDeviceStatusWriters(plan, clients, writer.WithLogger(log))
+	var pub mqttpub.Unit
+	if o.MQTT != nil {
+		pub = o.MQTT.Unit(u.ID)
+		outputs = append(outputs, pub)
+		statusWriters = append(statusWriters, pub)
+	}
+
+	closeAll := func() error {
+		err := closeWriters()
+		if perr := closePoller(); perr != nil {
+			err = perr
+		}
+		if hist != nil {
+			if herr := hist.Close(); herr != nil {
+				err = herr
+			}
+		}
+		if pub != nil {
+			if merr := pub.Close(); merr != nil {
+				err = merr
+			}
+		}
+		if rec != nil {
+			if rerr := rec.Close(); rerr != nil {
+				err = rerr
+			}
+		}
+		return err
+	}
+
+	// ---- orchestrator ----
+	r, err := runner.New(runner.Config{
+		UnitID:          u.ID,
+		Source:          p,
+		Writer:          writer.New(plan, clients, writer.WithLogger(log)),
+		StatusWriters:   statusWriters,
+		DeliveryWriters: writer.NewDeliveryWriters(plan, clients, writer.WithLogger(log)),
+		Outputs:         outputs,
+		Recorder:        runnerRecorder(rec),
+		ShutdownHealth:  &shutdownHealth,
+		Logger:          log,
+	})
+	if err != nil {
+		_ = closeAll()
+		return Built{}, err
+	}
+
+	return Built{Run: r, Close: closeAll}, nil
+}
+
+// runnerRecorder keeps a nil *lazyRecorder from becoming a non-nil
+// runner.Recorder.
+func runnerRecorder(rec *lazyRecorder) runner.Recorder {
+	if rec == nil {
+		return nil
+	}
+	return rec
+}
+
+// lazyRecorder opens its recording on the first result. A reload builds
+// a changed unit before the old one stops, so the file is only taken
+// once the new runner is polling and the old one has closed it.
+//
+// If the file cannot be opened the error is returned once and recording
+// stays off for this unit.
+type lazyRecorder struct {
+	path, unit string
+
+	mu     sync.Mutex
+	rec    *recording.Recorder
+	failed bool
+}
+
+func (l *lazyRecorder) Record(res poller.PollResult) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.failed {
+		return nil
+	}
+	if l.rec == nil {
+		rec, err := recording.Open(l.path, l.unit)
+		if err != nil {
+			l.failed = true
+			return fmt.Errorf("recording disabled: %w", err)
+		}
+		l.rec = rec
+	}
+	return l.rec.Record(res)
+}
+
+func (l *lazyRecorder) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rec == nil {
+		return nil
+	}
+	err := l.rec.Close()
+	l.rec, l.failed = nil, true
+	return err
+}
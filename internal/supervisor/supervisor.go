@@ -0,0 +1,288 @@
+// internal/supervisor/supervisor.go
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/logging"
+	"github.com/tamzrod/modbus-replicator/internal/runner"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+const defaultShutdownTimeout = 5 * time.Second
+
+// Runnable is one started-able unit pipeline.
+// *runner.Runner satisfies it.
+type Runnable interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	SetShutdownHealth(h uint16)
+	Info() runner.Info
+}
+
+// Built is the result of building one unit: the pipeline plus the
+// closer for the resources it owns (poller, writer clients).
+type Built struct {
+	Run   Runnable
+	Close func() error
+}
+
+// Builder constructs one unit pipeline without touching the network.
+// log is already bound to the unit (see logging.Unit).
+type Builder func(u config.UnitConfig, shutdownHealth uint16, log *slog.Logger) (Built, error)
+
+// ReloadReport describes the outcome of one Apply.
+type ReloadReport struct {
+	At        time.Time `json:"at"`
+	Trigger   string    `json:"trigger"`
+	Added     []string  `json:"added"`
+	Removed   []string  `json:"removed"`
+	Restarted []string  `json:"restarted"`
+	Unchanged []string  `json:"unchanged"`
+	Err       string    `json:"error,omitempty"`
+}
+
+// Supervisor owns the set of running units and reconciles it against
+// successive configurations. Units whose configuration did not change
+// keep running untouched: same connections, same counters.
+type Supervisor struct {
+	build Builder
+	log   *slog.Logger
+
+	mu    sync.Mutex
+	cfg   *config.Config
+	units map[string]*unitHandle
+	last  ReloadReport
+}
+
+type unitHandle struct {
+	cfg   config.UnitConfig
+	run   Runnable
+	close func() error
+}
+
+// New returns an empty supervisor. build nil means BuildUnit;
+// log nil discards.
+func New(build Builder, log *slog.Logger) *Supervisor {
+	if build == nil {
+		build = BuildUnit
+	}
+	return &Supervisor{
+		build: build,
+		log:   logging.OrDiscard(log),
+		units: make(map[string]*unitHandle),
+	}
+}
+
+// Apply reconciles the running units against cfg, which must already be
+// loaded and validated.
+//
+// Every new or changed unit is built before anything is stopped; if any
+// build fails the reload is aborted and the running set is left intact.
+// Removed and changed units are then stopped (draining, with their final
+// shutdown status), and added and changed units are started.
+func (s *Supervisor) Apply(cfg *config.Config, trigger string) ReloadReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rep := ReloadReport{At: time.Now(), Trigger: trigger}
+	health := ShutdownHealth(cfg.Replicator.Shutdown)
+
+	next := make(map[string]config.UnitConfig, len(cfg.Replicator.Units))
+	for _, u := range cfg.Replicator.Units {
+		next[u.ID] = u
+	}
+
+	// ---- classify ----
+	var toStart []string
+	for id, u := range next {
+		h, ok := s.units[id]
+		switch {
+		case !ok:
+			rep.Added = append(rep.Added, id)
+			toStart = append(toStart, id)
+		case !reflect.DeepEqual(h.cfg, u):
+			rep.Restarted = append(rep.Restarted, id)
+			toStart = append(toStart, id)
+		default:
+			rep.Unchanged = append(rep.Unchanged, id)
+		}
+	}
+	for id := range s.units {
+		if _, ok := next[id]; !ok {
+			rep.Removed = append(rep.Removed, id)
+		}
+	}
+	sortReport(&rep)
+	sort.Strings(toStart)
+
+	// ---- build (all or nothing) ----
+	built := make(map[string]Built, len(toStart))
+	for _, id := range toStart {
+		b, err := s.build(next[id], health, logging.Unit(s.log, next[id]))
+		if err != nil {
+			for _, prev := range built {
+				_ = prev.Close()
+			}
+			return s.fail(rep, fmt.Errorf("unit %s: %w", id, err))
+		}
+		built[id] = b
+	}
+
+	// ---- stop removed + changed ----
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout(cfg.Replicator.Shutdown))
+	defer cancel()
+
+	for _, id := range append(append([]string(nil), rep.Removed...), rep.Restarted...) {
+		s.stopUnit(stopCtx, id)
+	}
+
+	// ---- start added + changed ----
+	for _, id := range toStart {
+		b := built[id]
+		if err := b.Run.Start(context.Background()); err != nil {
+			_ = b.Close()
+			s.log.Error("unit start failed", "unit", id, "trigger", trigger, "err", err)
+			rep.Err = fmt.Sprintf("unit %s: start: %v", id, err)
+			continue
+		}
+		s.units[id] = &unitHandle{cfg: next[id], run: b.Run, close: b.Close}
+	}
+
+	// ---- global settings reach unchanged units in place ----
+	for _, id := range rep.Unchanged {
+		s.units[id].run.SetShutdownHealth(health)
+	}
+
+	s.cfg = cfg
+	s.last = rep
+	s.logReport(rep)
+	return rep
+}
+
+// Fail records a reload that never reached Apply (load or validation
+// error). The running set is untouched.
+func (s *Supervisor) Fail(err error, trigger string) ReloadReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fail(ReloadReport{At: time.Now(), Trigger: trigger}, err)
+}
+
+func (s *Supervisor) fail(rep ReloadReport, err error) ReloadReport {
+	rep.Added, rep.Removed, rep.Restarted, rep.Unchanged = nil, nil, nil, nil
+	rep.Err = err.Error()
+	s.last = rep
+	s.log.Error("reload rejected; running units unchanged", "trigger", rep.Trigger, "err", err)
+	return rep
+}
+
+// Shutdown stops every unit concurrently within the configured drain
+// deadline and releases their resources.
+//
+// The units are detached under the lock and drained outside it, so
+// Units and LastReload keep answering while the drain runs.
+func (s *Supervisor) Shutdown() {
+	s.mu.Lock()
+	units := s.units
+	s.units = make(map[string]*unitHandle)
+	timeout := defaultShutdownTimeout
+	if s.cfg != nil {
+		timeout = shutdownTimeout(s.cfg.Replicator.Shutdown)
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for id, h := range units {
+		wg.Add(1)
+		go func(id string, h *unitHandle) {
+			defer wg.Done()
+			if err := h.run.Stop(ctx); err != nil {
+				s.log.Warn("unit did not drain before shutdown deadline", "unit", id, "err", err)
+			}
+			_ = h.close()
+		}(id, h)
+	}
+	wg.Wait()
+}
+
+// LastReload returns the outcome of the most recent Apply or Fail.
+func (s *Supervisor) LastReload() ReloadReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Units returns the observer view of every running unit, sorted by ID.
+func (s *Supervisor) Units() []runner.Info {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]runner.Info, 0, len(s.units))
+	for _, h := range s.units {
+		out = append(out, h.run.Info())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UnitID < out[j].UnitID })
+	return out
+}
+
+// Config returns the configuration currently applied.
+func (s *Supervisor) Config() *config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+func (s *Supervisor) stopUnit(ctx context.Context, id string) {
+	h := s.units[id]
+	if err := h.run.Stop(ctx); err != nil {
+		s.log.Warn("unit did not drain before reload deadline", "unit", id, "err", err)
+	}
+	_ = h.close()
+	delete(s.units, id)
+}
+
+// ShutdownHealth maps the configured shutdown status to a health code.
+// Config has already been validated; empty means DISABLED.
+func ShutdownHealth(sc config.ShutdownConfig) uint16 {
+	if h, ok := status.HealthByName(sc.Status); ok {
+		return h
+	}
+	return status.HealthDisabled
+}
+
+func shutdownTimeout(sc config.ShutdownConfig) time.Duration {
+	if sc.TimeoutMs > 0 {
+		return time.Duration(sc.TimeoutMs) * time.Millisecond
+	}
+	return defaultShutdownTimeout
+}
+
+func sortReport(rep *ReloadReport) {
+	sort.Strings(rep.Added)
+	sort.Strings(rep.Removed)
+	sort.Strings(rep.Restarted)
+	sort.Strings(rep.Unchanged)
+}
+
+func (s *Supervisor) logReport(rep ReloadReport) {
+	s.log.Info("config applied",
+		"trigger", rep.Trigger,
+		"added", rep.Added,
+		"removed", rep.Removed,
+		"restarted", rep.Restarted,
+		"unchanged", len(rep.Unchanged),
+	)
+	if rep.Err != "" {
+		s.log.Error("config partially applied", "trigger", rep.Trigger, "err", rep.Err)
+	}
+}
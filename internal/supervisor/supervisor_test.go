@@ -0,0 +1,216 @@
+// internal/supervisor/supervisor_test.go
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/runner"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+// ------------------------------------------------------------------
+// Fakes
+// ------------------------------------------------------------------
+
+type fakeRun struct {
+	id     string
+	starts int
+	stops  int
+	health uint16
+	closed bool
+	hold   chan struct{} // when set, Stop signals on it and waits to be released
+}
+
+func (f *fakeRun) Start(context.Context) error { f.starts++; return nil }
+func (f *fakeRun) Stop(context.Context) error {
+	f.stops++
+	if f.hold != nil {
+		f.hold <- struct{}{}
+		<-f.hold
+	}
+	return nil
+}
+func (f *fakeRun) SetShutdownHealth(h uint16) { f.health = h }
+func (f *fakeRun) Info() runner.Info          { return runner.Info{UnitID: f.id} }
+
+type fakeBuilder struct {
+	built  map[string][]*fakeRun
+	failOn string
+	hold   chan struct{}
+}
+
+func newFakeBuilder() *fakeBuilder {
+	return &fakeBuilder{built: make(map[string][]*fakeRun)}
+}
+
+func (b *fakeBuilder) build(u config.UnitConfig, h uint16, _ *slog.Logger) (Built, error) {
+	if u.ID == b.failOn {
+		return Built{}, errors.New("boom")
+	}
+	r := &fakeRun{id: u.ID, health: h, hold: b.hold}
+	b.built[u.ID] = append(b.built[u.ID], r)
+	return Built{Run: r, Close: func() error { r.closed = true; return nil }}, nil
+}
+
+func (b *fakeBuilder) latest(id string) *fakeRun {
+	rs := b.built[id]
+	return rs[len(rs)-1]
+}
+
+func unitCfg(id string, interval int) config.UnitConfig {
+	return config.UnitConfig{
+		ID:     id,
+		Source: config.SourceConfig{Endpoint: "127.0.0.1:502", UnitID: 1},
+		Reads:  []config.ReadConfig{{FC: 3, Address: 0, Quantity: 1}},
+		Poll:   config.PollConfig{IntervalMs: interval},
+	}
+}
+
+func cfgOf(units ...config.UnitConfig) *config.Config {
+	return &config.Config{Replicator: config.ReplicatorConfig{Units: units}}
+}
+
+// ------------------------------------------------------------------
+// Tests
+// ------------------------------------------------------------------
+
+func TestApply_InitialStartsAll(t *testing.T) {
+	b := newFakeBuilder()
+	s := New(b.build, nil)
+
+	rep := s.Apply(cfgOf(unitCfg("a", 1000), unitCfg("b", 1000)), "startup")
+
+	if !reflect.DeepEqual(rep.Added, []string{"a", "b"}) {
+		t.Fatalf("added=%v", rep.Added)
+	}
+	if b.latest("a").starts != 1 || b.latest("b").starts != 1 {
+		t.Fatalf("expected both units started once")
+	}
+}
+
+func TestApply_DiffOnlyTouchesChangedUnits(t *testing.T) {
+	b := newFakeBuilder()
+	s := New(b.build, nil)
+
+	s.Apply(cfgOf(unitCfg("keep", 1000), unitCfg("change", 1000), unitCfg("drop", 1000)), "startup")
+	keep := b.latest("keep")
+	oldChange := b.latest("change")
+	drop := b.latest("drop")
+
+	rep := s.Apply(cfgOf(unitCfg("keep", 1000), unitCfg("change", 500), unitCfg("new", 1000)), "sighup")
+
+	if !reflect.DeepEqual(rep.Unchanged, []string{"keep"}) ||
+		!reflect.DeepEqual(rep.Restarted, []string{"change"}) ||
+		!reflect.DeepEqual(rep.Removed, []string{"drop"}) ||
+		!reflect.DeepEqual(rep.Added, []string{"new"}) {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+
+	if keep.starts != 1 || keep.stops != 0 || len(b.built["keep"]) != 1 {
+		t.Fatalf("unchanged unit was touched: %+v", keep)
+	}
+	if oldChange.stops != 1 || !oldChange.closed {
+		t.Fatalf("changed unit not stopped and closed")
+	}
+	if b.latest("change").starts != 1 || b.latest("change") == oldChange {
+		t.Fatalf("changed unit not rebuilt and started")
+	}
+	if drop.stops != 1 || !drop.closed {
+		t.Fatalf("removed unit not stopped and closed")
+	}
+	if b.latest("new").starts != 1 {
+		t.Fatalf("added unit not started")
+	}
+}
+
+func TestApply_BuildFailureLeavesRunningSetIntact(t *testing.T) {
+	b := newFakeBuilder()
+	s := New(b.build, nil)
+
+	s.Apply(cfgOf(unitCfg("a", 1000)), "startup")
+	a := b.latest("a")
+
+	b.failOn = "bad"
+	rep := s.Apply(cfgOf(unitCfg("a", 500), unitCfg("bad", 1000)), "file")
+
+	if rep.Err == "" {
+		t.Fatalf("expected reload error")
+	}
+	if a.stops != 0 {
+		t.Fatalf("running unit stopped despite aborted reload")
+	}
+	if len(b.built["a"]) != 2 || !b.latest("a").closed {
+		t.Fatalf("speculatively built unit not closed")
+	}
+	if s.LastReload().Err == "" {
+		t.Fatalf("last reload does not record the failure")
+	}
+}
+
+func TestApply_ShutdownStatusReachesUnchangedUnits(t *testing.T) {
+	b := newFakeBuilder()
+	s := New(b.build, nil)
+
+	s.Apply(cfgOf(unitCfg("a", 1000)), "startup")
+	if b.latest("a").health != status.HealthDisabled {
+		t.Fatalf("default shutdown health=%d", b.latest("a").health)
+	}
+
+	next := cfgOf(unitCfg("a", 1000))
+	next.Replicator.Shutdown.Status = "unknown"
+	s.Apply(next, "sighup")
+
+	if b.latest("a").health != status.HealthUnknown {
+		t.Fatalf("shutdown health not updated in place: %d", b.latest("a").health)
+	}
+}
+
+func TestShutdown_StopsAndClosesAll(t *testing.T) {
+	b := newFakeBuilder()
+	s := New(b.build, nil)
+	s.Apply(cfgOf(unitCfg("a", 1000), unitCfg("b", 1000)), "startup")
+
+	s.Shutdown()
+
+	for _, id := range []string{"a", "b"} {
+		r := b.latest(id)
+		if r.stops != 1 || !r.closed {
+			t.Fatalf("unit %s not stopped/closed: %+v", id, r)
+		}
+	}
+}
+
+func TestShutdown_DrainsOutsideTheLock(t *testing.T) {
+	b := newFakeBuilder()
+	b.hold = make(chan struct{})
+	s := New(b.build, nil)
+	s.Apply(cfgOf(unitCfg("a", 1000)), "startup")
+
+	done := make(chan struct{})
+	go func() { s.Shutdown(); close(done) }()
+	<-b.hold
+
+	// While unit a is still draining, the observer view must answer.
+	answered := make(chan []runner.Info)
+	go func() { answered <- s.Units() }()
+	select {
+	case got := <-answered:
+		if len(got) != 0 {
+			t.Fatalf("units during drain = %+v, want none", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Units blocked behind Shutdown")
+	}
+
+	b.hold <- struct{}{}
+	<-done
+	if r := b.latest("a"); r.stops != 1 || !r.closed {
+		t.Fatalf("unit a not stopped/closed: %+v", r)
+	}
+}
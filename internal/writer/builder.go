@@ -52,6 +52,19 @@ func BuildPlan(u cfg.UnitConfig) (Plan, error) {
 		}
 	}
 
+	// ------------------------------------------------------------
+	// DELIVERY PLANS (PER TARGET, OPT-IN; IN THE STATUS MEMORY)
+	// ------------------------------------------------------------
+	if u.Source.StatusSlot != nil && u.Source.DeliverySlot != nil {
+		for _, t := range u.Targets {
+			plan.Delivery = append(plan.Delivery, DeliveryPlan{
+				Endpoint: t.Endpoint,
+				UnitID:   *t.StatusUnitID,
+				BaseSlot: *u.Source.DeliverySlot,
+			})
+		}
+	}
+
 	return plan, nil
 }
 
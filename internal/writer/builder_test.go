@@ -0,0 +1,39 @@
+// internal/writer/builder_test.go
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+)
+
+// The YAML, JSON and TOML examples must drive identical write plans.
+func TestBuildPlan_SameForEveryConfigFormat(t *testing.T) {
+	plans := func(path string) []Plan {
+		t.Helper()
+		cfg, err := config.Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		var out []Plan
+		for _, u := range cfg.Replicator.Units {
+			p, err := BuildPlan(u)
+			if err != nil {
+				t.Fatalf("%s: unit %s: %v", path, u.ID, err)
+			}
+			out = append(out, p)
+		}
+		return out
+	}
+
+	want := plans("../config/example.yaml")
+	if len(want) == 0 {
+		t.Fatal("example.yaml has no units")
+	}
+	for _, f := range []string{"../config/example.json", "../config/example.toml"} {
+		if got := plans(f); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: plan differs from example.yaml\n got %+v\nwant %+v", f, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// internal/writer/delivery_writer.go
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+// DeliveryWriter is the delivery-only contract for the target delivery
+// block. It never sees source status.
+type DeliveryWriter interface {
+	WriteDelivery(targets []status.TargetDelivery) error
+}
+
+// deliveryStatusWriter writes the delivery block into ONE target's
+// status memory.
+type deliveryStatusWriter struct {
+	plan *DeliveryPlan
+	cli  endpointClient
+	log  *slog.Logger
+
+	// last is the block as the target holds it; nil until written,
+	// or after a failed write.
+	last []uint16
+}
+
+// NewDeliveryWriters builds per-target delivery block writers.
+// Returns empty slice if the delivery block is disabled.
+func NewDeliveryWriters(plan Plan, clients map[string]endpointClient, opts ...Option) []DeliveryWriter {
+	o := buildOptions(opts)
+
+	var out []DeliveryWriter
+
+	for _, dp := range plan.Delivery {
+		cli := clients[dp.Endpoint]
+		if cli == nil {
+			continue
+		}
+
+		out = append(out, &deliveryStatusWriter{
+			plan: &dp,
+			cli:  cli,
+			log:  o.log.With("target", dp.Endpoint, "target_id", dp.UnitID),
+		})
+	}
+
+	return out
+}
+
+// WriteDelivery writes the whole block, in one write, when it differs
+// from the last block written. Most good polls move a last_delivery_epoch,
+// so a smaller write would rarely save a packet.
+func (dw *deliveryStatusWriter) WriteDelivery(targets []status.TargetDelivery) error {
+	if dw == nil || dw.plan == nil {
+		return errors.New("delivery writer: disabled")
+	}
+	if dw.cli == nil {
+		return fmt.Errorf("delivery writer: missing client for endpoint %s", dw.plan.Endpoint)
+	}
+
+	cur := status.EncodeDelivery(targets)
+	if dw.last != nil && slices.Equal(dw.last, cur) {
+		return nil
+	}
+
+	if err := dw.cli.WriteRegisters(
+		statusAreaHoldingRegisters,
+		dw.plan.UnitID,
+		dw.plan.BaseSlot*status.SlotsPerDevice,
+		cur,
+	); err != nil {
+		dw.last = nil
+		err = fmt.Errorf("delivery writer: block write failed: %w", err)
+		dw.log.Warn("delivery write failed", "err", err)
+		return err
+	}
+
+	dw.last = cur
+	return nil
+}
+
+// DeliveryOf turns data target outcomes into delivery block entries,
+// saturating counters at 65535.
+func DeliveryOf(outcomes []TargetOutcome) []status.TargetDelivery {
+	out := make([]status.TargetDelivery, len(outcomes))
+	for i, o := range outcomes {
+		d := status.TargetDelivery{
+			TargetID:            uint16(o.TargetID), // 0..255, see config
+			ConsecutiveFailures: uint16(min(o.ConsecutiveFailures, math.MaxUint16)),
+			QueuedPackets:       uint16(min(max(o.Queued, 0), math.MaxUint16)),
+		}
+		switch {
+		case o.LastWriteAt.IsZero():
+			d.LastOutcome = status.DeliveryNone
+		case o.LastErr == "":
+			d.LastOutcome = status.DeliveryOK
+		default:
+			d.LastOutcome = status.DeliveryFailed
+		}
+		if !o.LastSuccessAt.IsZero() {
+			d.LastSuccessEpoch = uint32(o.LastSuccessAt.Unix())
+		}
+		out[i] = d
+	}
+	return out
+}
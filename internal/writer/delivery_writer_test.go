@@ -0,0 +1,115 @@
+// internal/writer/delivery_writer_test.go
+package writer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+// Target B is down: its delivery entry in target A's memory says so,
+// while the data keeps flowing to A.
+func TestDeliveryWriter_ReportsOtherTargetStale(t *testing.T) {
+	a := &memoryClient{}
+	b := &fakeEndpointClient{writeErr: errors.New("connection refused")}
+	clients := map[string]endpointClient{"a": a, "b": b}
+
+	plan := Plan{
+		UnitID: "unit-1",
+		Targets: []TargetEndpoint{
+			{TargetID: 1, Endpoint: "a", Memories: []MemoryDest{{}}},
+			{TargetID: 2, Endpoint: "b", Memories: []MemoryDest{{}}},
+		},
+		Delivery: []DeliveryPlan{
+			{Endpoint: "a", UnitID: 50, BaseSlot: 1},
+			{Endpoint: "b", UnitID: 50, BaseSlot: 1},
+		},
+	}
+	w := New(plan, clients)
+	dws := NewDeliveryWriters(plan, clients)
+	if len(dws) != 2 {
+		t.Fatalf("expected 2 delivery writers, got %d", len(dws))
+	}
+
+	at := time.Unix(1772366400, 0)
+	res := poller.PollResult{
+		UnitID: "unit-1",
+		At:     at,
+		Blocks: []poller.BlockResult{{FC: 3, Address: 0, Quantity: 1, Registers: []uint16{7}}},
+	}
+
+	for i := 0; i < 3; i++ {
+		_ = w.Write(res)
+		d := DeliveryOf(w.(OutcomeReporter).Outcomes())
+		if err := dws[0].WriteDelivery(d); err != nil {
+			t.Fatalf("write to a: %v", err)
+		}
+		if err := dws[1].WriteDelivery(d); err == nil {
+			t.Fatal("write to b: expected error")
+		}
+	}
+
+	block := a.block()
+	entryB := block[status.DeliverySlotsPerTarget:]
+	if entryB[status.DeliverySlotTargetID] != 2 ||
+		entryB[status.DeliverySlotLastOutcome] != status.DeliveryFailed ||
+		entryB[status.DeliverySlotConsecutiveFailures] != 3 ||
+		entryB[status.DeliverySlotLastSuccessEpochLow] != 0 {
+		t.Fatalf("entry of b: %v", entryB[:status.DeliverySlotsPerTarget])
+	}
+	if block[status.DeliverySlotLastOutcome] != status.DeliveryOK {
+		t.Fatalf("entry of a: %v", block[:status.DeliverySlotsPerTarget])
+	}
+	if a.regs[0] != 7 {
+		t.Fatalf("data not delivered to a")
+	}
+}
+
+// The block is written once per change, in one packet, and re-asserted
+// after a failure.
+func TestDeliveryWriter_WritesOnChangeOnly(t *testing.T) {
+	cli := &fakeEndpointClient{}
+	dw := NewDeliveryWriters(
+		Plan{Delivery: []DeliveryPlan{{Endpoint: "ep", UnitID: 1, BaseSlot: 2}}},
+		map[string]endpointClient{"ep": cli},
+	)[0]
+
+	d := []status.TargetDelivery{{TargetID: 1, LastOutcome: status.DeliveryOK, LastSuccessEpoch: 10}}
+	_ = dw.WriteDelivery(d)
+	_ = dw.WriteDelivery(d)
+	if cli.writeRegsCnt != 1 || cli.lastRegsAddr != 2*status.SlotsPerDevice || len(cli.lastRegs) != status.SlotsPerDevice {
+		t.Fatalf("writes=%d addr=%d len=%d", cli.writeRegsCnt, cli.lastRegsAddr, len(cli.lastRegs))
+	}
+
+	cli.writeErr = errors.New("down")
+	d[0].LastSuccessEpoch = 11
+	if err := dw.WriteDelivery(d); err == nil {
+		t.Fatal("expected error")
+	}
+	cli.writeErr = nil
+	if err := dw.WriteDelivery(d); err != nil || cli.writeRegsCnt != 3 {
+		t.Fatalf("re-assert: err=%v writes=%d", err, cli.writeRegsCnt)
+	}
+}
+
+func TestDeliveryOf(t *testing.T) {
+	now := time.Unix(1772366400, 0)
+	got := DeliveryOf([]TargetOutcome{
+		{TargetID: 1},
+		{TargetID: 2, LastWriteAt: now, LastSuccessAt: now},
+		{TargetID: 3, LastWriteAt: now, LastErr: "x", ConsecutiveFailures: 70000, Queued: 4},
+	})
+	want := []status.TargetDelivery{
+		{TargetID: 1, LastOutcome: status.DeliveryNone},
+		{TargetID: 2, LastOutcome: status.DeliveryOK, LastSuccessEpoch: 1772366400},
+		{TargetID: 3, LastOutcome: status.DeliveryFailed, ConsecutiveFailures: 65535, QueuedPackets: 4},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("target %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -51,7 +51,7 @@ func TestDeviceNameWrittenOnFullAssertOnly(t *testing.T) {
 		)
 	}
 
-	expectedNameRegs := encodeDeviceNameRegs("DEV-01")
+	expectedNameRegs := status.EncodeDeviceName("DEV-01")
 
 	for i := 0; i < status.SlotDeviceNameSlots; i++ {
 		slot := status.SlotDeviceNameStart + i
@@ -128,17 +128,22 @@ func TestSecondsInErrorResetOnRecovery(t *testing.T) {
 		t.Fatalf("recovery snapshot write failed: %v", err)
 	}
 
-	expectedAddr := uint16(0)*status.SlotsPerDevice + status.SlotSecondsInError
+	// Slots 0–2 all changed: one write, ending in the reset.
+	if cli.writeRegsCnt != 2 {
+		t.Fatalf("expected 2 register writes (full + 1 run), got %d", cli.writeRegsCnt)
+	}
+
+	expectedAddr := uint16(0)*status.SlotsPerDevice + status.SlotHealthCode
 
 	if cli.lastRegsAddr != expectedAddr {
 		t.Fatalf("unexpected write addr: got=%d want=%d", cli.lastRegsAddr, expectedAddr)
 	}
 
-	if len(cli.lastRegs) != 1 {
-		t.Fatalf("expected 1 register write, got %d", len(cli.lastRegs))
+	if len(cli.lastRegs) != 3 {
+		t.Fatalf("expected 3 register write, got %d", len(cli.lastRegs))
 	}
 
-	if cli.lastRegs[0] != 0 {
-		t.Fatalf("seconds_in_error not reset: got=%d want=0", cli.lastRegs[0])
+	if cli.lastRegs[status.SlotSecondsInError] != 0 {
+		t.Fatalf("seconds_in_error not reset: got=%d want=0", cli.lastRegs[status.SlotSecondsInError])
 	}
 }
\ No newline at end of file
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync/atomic"
 	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
 )
 
 const (
@@ -23,6 +26,10 @@ const (
 type EndpointClient struct {
 	endpoint string
 	timeout  time.Duration
+
+	// Passive delivery instrumentation: packets and bytes fully sent.
+	packets atomic.Uint64
+	bytes   atomic.Uint64
 }
 
 type Config struct {
@@ -34,8 +41,10 @@ func NewEndpointClient(cfg Config) (*EndpointClient, error) {
 	if cfg.Endpoint == "" {
 		return nil, errors.New("writer ingest: endpoint required")
 	}
+	// Loaded configs always carry a timeout; this only covers callers
+	// that build a Config by hand, with the same default.
 	if cfg.Timeout <= 0 {
-		cfg.Timeout = 2 * time.Second
+		cfg.Timeout = config.DefaultSourceTimeoutMs * time.Millisecond
 	}
 	return &EndpointClient{
 		endpoint: cfg.Endpoint,
@@ -45,6 +54,12 @@ func NewEndpointClient(cfg Config) (*EndpointClient, error) {
 
 func (c *EndpointClient) Close() error { return nil }
 
+// Stats returns the number of packets and bytes fully sent to the endpoint,
+// regardless of the MMA response.
+func (c *EndpointClient) Stats() (packets, bytes uint64) {
+	return c.packets.Load(), c.bytes.Load()
+}
+
 //
 // Implements writer.endpointClient
 //
@@ -96,6 +111,8 @@ func (c *EndpointClient) send(
 	if err := writeAll(conn, pkt); err != nil {
 		return fmt.Errorf("writer ingest: write: %w", err)
 	}
+	c.packets.Add(1)
+	c.bytes.Add(uint64(len(pkt)))
 
 	_ = conn.SetReadDeadline(time.Now().Add(c.timeout))
 	var resp [1]byte
@@ -0,0 +1,89 @@
+// internal/writer/sink_test.go
+package writer
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tamzrod/modbus-replicator/internal/config"
+	"github.com/tamzrod/modbus-replicator/internal/ingestserver"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+	"github.com/tamzrod/modbus-replicator/internal/sim"
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+// Real Raw Ingest packets into the reference receiver: data lands at
+// offset-shifted addresses of the target id's memory, status at
+// slot*30 of status_unit_id.
+func TestWriter_DeliversToSink(t *testing.T) {
+	sink, err := ingestserver.New(ingestserver.Config{Units: []ingestserver.UnitConfig{
+		{ID: 7, Coils: 16, Holding: 200},
+		{ID: 9, Holding: 60},
+		{ID: 8, Holding: 10}, // too small for the data
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sink.ServeListener(ctx, ln)
+	ep := ln.Addr().String()
+
+	slot, statusUnit := uint16(1), uint8(9)
+	u := config.UnitConfig{
+		ID:     "dev",
+		Source: config.SourceConfig{TimeoutMs: 1000, StatusSlot: &slot, DeviceName: "PUMP"},
+		Targets: []config.TargetConfig{{
+			ID: 7, Endpoint: ep, StatusUnitID: &statusUnit,
+			Memories: []config.MemoryConfig{{Offsets: map[int]uint16{3: 100}}},
+		}},
+	}
+	plan, err := BuildPlan(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients, _, err := BuildEndpointClients(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := poller.PollResult{UnitID: "dev", Blocks: []poller.BlockResult{
+		{FC: 1, Address: 2, Quantity: 3, Bits: []bool{true, false, true}},
+		{FC: 3, Address: 10, Quantity: 2, Registers: []uint16{500, 0xBEEF}},
+	}}
+	if err := New(plan, clients).Write(res); err != nil {
+		t.Fatal(err)
+	}
+	m := sink.Memory(7)
+	if got := m.Get(sim.HoldingRegisters, 110, 2); !slices.Equal(got, []uint16{500, 0xBEEF}) {
+		t.Fatalf("holding 110: %v", got)
+	}
+	if got := m.Get(sim.Coils, 2, 3); !slices.Equal(got, []uint16{1, 0, 1}) {
+		t.Fatalf("coils 2: %v", got)
+	}
+
+	sw := NewDeviceStatusWriters(plan, clients)[0]
+	if err := sw.WriteStatus(status.Snapshot{Health: status.HealthOK, RequestsTotal: 70000}); err != nil {
+		t.Fatal(err)
+	}
+	block := sink.Memory(9).Get(sim.HoldingRegisters, 30, 30)
+	if block[status.SlotHealthCode] != status.HealthOK ||
+		block[status.SlotDeviceNameStart] != 'P'<<8|'U' ||
+		block[status.SlotRequestsTotalLow] != 70000&0xFFFF || block[status.SlotRequestsTotalHigh] != 1 {
+		t.Fatalf("status block: %v", block)
+	}
+
+	// The receiver bounds-checks: writing past a memory is rejected.
+	u.Targets[0].ID = 8
+	plan, _ = BuildPlan(u)
+	if err := New(plan, clients).Write(res); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Fatalf("want rejection, got %v", err)
+	}
+}
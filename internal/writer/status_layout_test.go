@@ -0,0 +1,152 @@
+// internal/writer/status_layout_test.go
+package writer
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tamzrod/modbus-replicator/internal/status"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// memoryClient applies register writes to one status memory.
+type memoryClient struct {
+	regs    [status.SlotsPerDevice * 2]uint16
+	packets int
+}
+
+func (m *memoryClient) WriteBits(byte, uint8, uint16, []bool) error { return nil }
+
+func (m *memoryClient) WriteRegisters(_ byte, _ uint8, addr uint16, regs []uint16) error {
+	m.packets++
+	copy(m.regs[addr:], regs)
+	return nil
+}
+
+// block is the status block at base slot 1 (registers 30–59).
+func (m *memoryClient) block() []uint16 {
+	return m.regs[status.SlotsPerDevice : 2*status.SlotsPerDevice]
+}
+
+func newLayoutWriter(cli endpointClient) StatusWriter {
+	plan := Plan{Status: []StatusPlan{{Endpoint: "mma", UnitID: 100, BaseSlot: 1, DeviceName: "METER-7"}}}
+	return NewDeviceStatusWriters(plan, map[string]endpointClient{"mma": cli})[0]
+}
+
+var diagnostic = status.Snapshot{
+	Health:             status.HealthError,
+	LastErrorCode:      0x0B,
+	SecondsInError:     42,
+	LastPollDurationMs: 137,
+	LastSuccessEpoch:   1772366400,
+	SecondsSinceGood:   43,
+
+	RequestsTotal:        0x00012345,
+	ResponsesValidTotal:  0x00012000,
+	TimeoutsTotal:        0x10,
+	TransportErrorsTotal: 0x20,
+	ConsecutiveFailCurr:  5,
+	ConsecutiveFailMax:   9,
+}
+
+func TestStatusLayout_GoldenFullBlock(t *testing.T) {
+	cli := &memoryClient{}
+	if err := newLayoutWriter(cli).WriteStatus(diagnostic); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	for slot, v := range cli.block() {
+		fmt.Fprintf(&b, "%2d 0x%04X\n", slot, v)
+	}
+	path := filepath.Join("testdata", "status_block_v2.golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != string(want) {
+		t.Fatalf("full block differs from %s:\n%s", path, b.String())
+	}
+
+	// The writer sends exactly what status.Encode produces.
+	if !slices.Equal(cli.block(), status.Encode(diagnostic, "METER-7")) {
+		t.Fatal("full block differs from status.Encode")
+	}
+	if cli.packets != 1 {
+		t.Fatalf("full block took %d writes, want 1", cli.packets)
+	}
+}
+
+// Incremental updates leave the memory exactly as a full write would.
+func TestStatusLayout_IncrementalMatchesFull(t *testing.T) {
+	cli := &memoryClient{}
+	sw := newLayoutWriter(cli)
+
+	steps := []func(s *status.Snapshot){
+		func(s *status.Snapshot) {},
+		func(s *status.Snapshot) { s.LastPollDurationMs = 12 },
+		func(s *status.Snapshot) { s.LastSuccessEpoch = 1772366401; s.SecondsSinceGood = 0 },
+		func(s *status.Snapshot) { s.LastPollDurationMs = 0; s.SecondsSinceGood = 65535 },
+		func(s *status.Snapshot) { s.Health = status.HealthOK; s.RequestsTotal = 0x00020000 },
+	}
+	s := diagnostic
+	for i, step := range steps {
+		step(&s)
+		if err := sw.WriteStatus(s); err != nil {
+			t.Fatal(err)
+		}
+		want := status.Encode(s, "METER-7")
+		if got := cli.block(); !slices.Equal(got, want) {
+			t.Fatalf("step %d: memory\n%v\nwant\n%v", i, got, want)
+		}
+	}
+}
+
+// A poll that moves several adjacent fields costs one write, not one
+// per field.
+func TestStatusLayout_CoalescedRuns(t *testing.T) {
+	cli := &memoryClient{}
+	sw := newLayoutWriter(cli)
+	if err := sw.WriteStatus(diagnostic); err != nil {
+		t.Fatal(err)
+	}
+
+	s := diagnostic
+	s.LastPollDurationMs = 20
+	s.LastSuccessEpoch++
+	s.SecondsSinceGood = 0
+	s.RequestsTotal++
+	s.ResponsesValidTotal++
+
+	cli.packets = 0
+	if err := sw.WriteStatus(s); err != nil {
+		t.Fatal(err)
+	}
+	// 12–15 and 20–23.
+	if cli.packets != 2 {
+		t.Fatalf("%d writes, want 2", cli.packets)
+	}
+	if !slices.Equal(cli.block(), status.Encode(s, "METER-7")) {
+		t.Fatal("memory differs from status.Encode")
+	}
+
+	cli.packets = 0
+	if err := sw.WriteStatus(s); err != nil {
+		t.Fatal(err)
+	}
+	if cli.packets != 0 {
+		t.Fatalf("unchanged snapshot took %d writes", cli.packets)
+	}
+}
@@ -1,7 +1,12 @@
 // internal/writer/types.go
 package writer
 
-import "github.com/tamzrod/modbus-replicator/internal/poller"
+import (
+	"time"
+
+	"github.com/tamzrod/modbus-replicator/internal/metrics"
+	"github.com/tamzrod/modbus-replicator/internal/poller"
+)
 
 // MemoryDest is one write destination inside an endpoint.
 // Offsets are per-FC address deltas; missing FC => 0.
@@ -24,14 +29,59 @@ type StatusPlan struct {
 	DeviceName string
 }
 
+// DeliveryPlan describes where the delivery block of a unit is written
+// for ONE target.
+type DeliveryPlan struct {
+	Endpoint string
+	UnitID   uint8
+	BaseSlot uint16
+}
+
 // Plan is the fully-built write plan for one unit.
 type Plan struct {
-	UnitID  string
-	Targets []TargetEndpoint
-	Status  []StatusPlan // per-target status (hot-standby replication)
+	UnitID   string
+	Targets  []TargetEndpoint
+	Status   []StatusPlan   // per-target status (hot-standby replication)
+	Delivery []DeliveryPlan // per-target delivery block (opt-in)
 }
 
 // Writer writes poll snapshots into targets.
 type Writer interface {
 	Write(res poller.PollResult) error
 }
+
+// TargetOutcome is the delivery record of one data target.
+// It is observability only; it never influences status or control flow.
+type TargetOutcome struct {
+	TargetID uint32
+	Endpoint string
+
+	LastWriteAt time.Time
+	LastErr     string // empty when the last write succeeded
+
+	Successes uint64
+	Failures  uint64
+
+	// ConsecutiveFailures counts failed writes since the last good one.
+	ConsecutiveFailures uint64
+	// LastSuccessAt is zero until a write succeeds.
+	LastSuccessAt time.Time
+
+	// Latency covers all writes to the target for one poll result.
+	Latency metrics.Histogram
+
+	// IngestPackets / IngestBytes are read from the endpoint client when it
+	// reports them. The client is shared by data and status writes of the unit.
+	IngestPackets uint64
+	IngestBytes   uint64
+
+	// Queued is read from the endpoint client when it reports a send
+	// queue; Raw Ingest writes are synchronous and leave it 0.
+	Queued int
+}
+
+// OutcomeReporter is implemented by writers that track per-target delivery.
+// Outcomes returns a copy and must be called from the goroutine that calls Write.
+type OutcomeReporter interface {
+	Outcomes() []TargetOutcome
+}
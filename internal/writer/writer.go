@@ -4,7 +4,10 @@ package writer
 import (
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/tamzrod/modbus-replicator/internal/poller"
 )
@@ -19,15 +22,83 @@ type endpointClient interface {
 type writerImpl struct {
 	plan    Plan
 	clients map[string]endpointClient
+
+	// outcomes is index-aligned with plan.Targets.
+	outcomes []TargetOutcome
+
+	// logs is index-aligned with plan.Targets; each carries target attrs.
+	logs []*slog.Logger
+}
+
+// Option configures a writer or status writer.
+type Option func(*options)
+
+type options struct {
+	log *slog.Logger
+}
+
+// WithLogger sets the logger for delivery failures.
+// Entries carry target and target_id attributes. Default discards.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		if l != nil {
+			o.log = l
+		}
+	}
+}
+
+func buildOptions(opts []Option) options {
+	o := options{log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
 }
 
-func New(plan Plan, clients map[string]endpointClient) Writer {
+func New(plan Plan, clients map[string]endpointClient, opts ...Option) Writer {
+	o := buildOptions(opts)
+
+	logs := make([]*slog.Logger, len(plan.Targets))
+	outcomes := make([]TargetOutcome, len(plan.Targets))
+	for i, t := range plan.Targets {
+		outcomes[i] = TargetOutcome{TargetID: t.TargetID, Endpoint: t.Endpoint}
+		logs[i] = o.log.With("target", t.Endpoint, "target_id", t.TargetID)
+	}
+
 	return &writerImpl{
-		plan:    plan,
-		clients: clients,
+		plan:     plan,
+		clients:  clients,
+		outcomes: outcomes,
+		logs:     logs,
 	}
 }
 
+// statsReporter is implemented by endpoint clients that count what they send.
+type statsReporter interface {
+	Stats() (packets, bytes uint64)
+}
+
+// queueReporter is implemented by endpoint clients that queue packets.
+type queueReporter interface {
+	Queued() int
+}
+
+// Outcomes implements OutcomeReporter.
+func (w *writerImpl) Outcomes() []TargetOutcome {
+	out := make([]TargetOutcome, len(w.outcomes))
+	copy(out, w.outcomes)
+
+	for i := range out {
+		if sr, ok := w.clients[out[i].Endpoint].(statsReporter); ok {
+			out[i].IngestPackets, out[i].IngestBytes = sr.Stats()
+		}
+		if qr, ok := w.clients[out[i].Endpoint].(queueReporter); ok {
+			out[i].Queued = qr.Queued()
+		}
+	}
+	return out
+}
+
 func (w *writerImpl) Write(res poller.PollResult) error {
 	var errs []string
 
@@ -36,13 +107,17 @@ func (w *writerImpl) Write(res poller.PollResult) error {
 	// ------------------------------------------------------------
 
 	if res.Err == nil {
-		for _, tgt := range w.plan.Targets {
+		for ti, tgt := range w.plan.Targets {
+			before := len(errs)
+			started := time.Now()
+
 			cli := w.clients[tgt.Endpoint]
 			if cli == nil {
 				errs = append(errs, fmt.Sprintf(
 					"writer: missing client for endpoint %s",
 					tgt.Endpoint,
 				))
+				w.record(ti, started, errs[before:])
 				continue
 			}
 
@@ -51,6 +126,7 @@ func (w *writerImpl) Write(res poller.PollResult) error {
 					"writer: target unit id %d out of range",
 					tgt.TargetID,
 				))
+				w.record(ti, started, errs[before:])
 				continue
 			}
 			unitID := uint8(tgt.TargetID)
@@ -79,6 +155,8 @@ func (w *writerImpl) Write(res poller.PollResult) error {
 					}
 				}
 			}
+
+			w.record(ti, started, errs[before:])
 		}
 	}
 
@@ -89,6 +167,26 @@ func (w *writerImpl) Write(res poller.PollResult) error {
 	return nil
 }
 
+// record updates the delivery outcome of target ti from the errors
+// produced while writing it.
+func (w *writerImpl) record(ti int, started time.Time, errs []string) {
+	o := &w.outcomes[ti]
+	o.LastWriteAt = time.Now()
+	o.Latency.Observe(o.LastWriteAt.Sub(started))
+
+	if len(errs) == 0 {
+		o.LastErr = ""
+		o.Successes++
+		o.ConsecutiveFailures = 0
+		o.LastSuccessAt = o.LastWriteAt
+		return
+	}
+	o.LastErr = strings.Join(errs, " | ")
+	o.Failures++
+	o.ConsecutiveFailures++
+	w.logs[ti].Warn("target write failed", "err", o.LastErr)
+}
+
 func offsetForFC(offsets map[int]uint16, fc uint8) uint16 {
 	if offsets == nil {
 		return 0
@@ -265,3 +265,47 @@ func TestWriter_TargetID_OutOfRange_ReturnsError(t *testing.T) {
 		t.Fatalf("expected error for TargetID out of range, got nil")
 	}
 }
+
+func TestWriter_OutcomesTrackedPerTarget(t *testing.T) {
+	plan := Plan{
+		UnitID: "unit-1",
+		Targets: []TargetEndpoint{
+			{TargetID: 1, Endpoint: "ok-ep", Memories: []MemoryDest{{Offsets: nil}}},
+			{TargetID: 2, Endpoint: "bad-ep", Memories: []MemoryDest{{Offsets: nil}}},
+		},
+	}
+
+	w := New(plan, map[string]endpointClient{
+		"ok-ep":  &fakeEndpointClient{},
+		"bad-ep": &fakeEndpointClient{writeErr: errors.New("fail")},
+	})
+
+	res := poller.PollResult{
+		UnitID: "unit-1",
+		At:     time.Now(),
+		Blocks: []poller.BlockResult{
+			{FC: 3, Address: 0, Quantity: 1, Registers: []uint16{1}},
+		},
+	}
+
+	_ = w.Write(res)
+	_ = w.Write(res)
+
+	out := w.(OutcomeReporter).Outcomes()
+	if len(out) != 2 {
+		t.Fatalf("expected 2 outcomes, got %d", len(out))
+	}
+
+	if out[0].Successes != 2 || out[0].Failures != 0 || out[0].LastErr != "" {
+		t.Fatalf("unexpected outcome for ok target: %+v", out[0])
+	}
+	if out[1].Successes != 0 || out[1].Failures != 2 || out[1].LastErr == "" {
+		t.Fatalf("unexpected outcome for failing target: %+v", out[1])
+	}
+	if out[0].ConsecutiveFailures != 0 || out[0].LastSuccessAt.IsZero() {
+		t.Fatalf("ok target: consecutive=%d last_success=%v", out[0].ConsecutiveFailures, out[0].LastSuccessAt)
+	}
+	if out[1].ConsecutiveFailures != 2 || !out[1].LastSuccessAt.IsZero() {
+		t.Fatalf("failing target: consecutive=%d last_success=%v", out[1].ConsecutiveFailures, out[1].LastSuccessAt)
+	}
+}